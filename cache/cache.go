@@ -8,60 +8,118 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-redis/redis/v8"
-	"github.com/justseemore/surl/models"
 	"github.com/patrickmn/go-cache"
 )
 
+// EntryType 缓存条目类型，不同类型可以使用不同的过期时间
+type EntryType string
+
+const (
+	EntryURL      EntryType = "url"      // 短链接
+	EntryNotFound EntryType = "notfound" // 不存在的短代码（负缓存）
+
+	EntryUnavailable EntryType = "unavailable" // 已确认不可用（禁用、过期、尚未生效）的短链接
+)
+
+// defaultNotFoundTTL 负缓存的默认过期时间，应远短于URL条目的过期时间
+const defaultNotFoundTTL = 30 * time.Second
+
 type Manager struct {
-	memCache       *cache.Cache
-	redisClient    *redis.Client
+	memCache       *cache.Cache // 计数、令牌等通用条目
+	urlCache       *urlLRU      // 短链接条目，按 CacheMaxItems 限制数量
+	redisClient    redis.UniversalClient
 	ctx            context.Context
-	expiry         time.Duration
+	expiry         time.Duration               // 默认过期时间（URL条目）
+	ttls           map[EntryType]time.Duration // 按条目类型覆盖的过期时间
+	ttlMutex       sync.RWMutex
 	useRedis       bool
-	maxItems       int          // 新增：最大项目数限制
-	currentItems   int64        // 新增：当前项目计数
-	itemsMutex     sync.RWMutex // 新增：项目计数互斥锁
+	keyPrefix      string // 所有键的命名空间前缀，用于多个实例共享同一个Redis
 	memClickCounts map[string]int64
 	memClickMutex  sync.RWMutex
+	maxClickKeys   int           // 内存点击计数的软上限（不同短代码数），0表示不限制
+	flushSignal    chan struct{} // 超过上限时通知同步任务提前同步
+
+	// GetURL 命中统计，按提供结果的缓存层分别计数
+	memHits   atomic.Int64
+	redisHits atomic.Int64
+	misses    atomic.Int64
+}
+
+func NewCacheManager(redisAddr string, redisPassword string, redisDB int, cacheExpiry int, maxItems int, keyPrefix string) *Manager {
+	opts := RedisOptions{Password: redisPassword, DB: redisDB}
+	if redisAddr != "" {
+		opts.Addrs = []string{redisAddr}
+	}
+	return NewCacheManagerWithRedis(opts, cacheExpiry, maxItems, keyPrefix)
+}
+
+// RedisOptions Redis连接配置
+type RedisOptions struct {
+	Mode       string   // single（默认）、sentinel 或 cluster
+	Addrs      []string // single 模式取第一个地址；sentinel 为哨兵地址；cluster 为集群节点
+	MasterName string   // sentinel 模式下的主节点名称
+	Password   string
+	DB         int // cluster 模式不支持选择数据库，忽略该值
 }
 
-func NewCacheManager(redisAddr string, redisPassword string, redisDB int, cacheExpiry int, maxItems int) *Manager {
+// newRedisClient 按部署模式创建Redis客户端，未配置地址时返回nil
+func newRedisClient(opts RedisOptions) redis.UniversalClient {
+	if len(opts.Addrs) == 0 {
+		return nil
+	}
+
+	switch opts.Mode {
+	case "sentinel":
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:    opts.MasterName,
+			SentinelAddrs: opts.Addrs,
+			Password:      opts.Password,
+			DB:            opts.DB,
+		})
+	case "cluster":
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:    opts.Addrs,
+			Password: opts.Password,
+		})
+	default:
+		return redis.NewClient(&redis.Options{
+			Addr:     opts.Addrs[0],
+			Password: opts.Password,
+			DB:       opts.DB,
+		})
+	}
+}
+
+// NewCacheManagerWithRedis 使用指定的Redis部署模式创建缓存管理器
+func NewCacheManagerWithRedis(redisOpts RedisOptions, cacheExpiry int, maxItems int, keyPrefix string) *Manager {
 	memCache := cache.New(time.Duration(cacheExpiry)*time.Minute, 10*time.Minute)
 
 	manager := &Manager{
 		memCache:       memCache,
+		urlCache:       newURLLRU(maxItems),
 		ctx:            context.Background(),
 		expiry:         time.Duration(cacheExpiry) * time.Minute,
+		ttls:           map[EntryType]time.Duration{EntryNotFound: defaultNotFoundTTL, EntryUnavailable: defaultNotFoundTTL},
 		useRedis:       false,
-		maxItems:       maxItems, // 新增
-		currentItems:   0,        // 新增
+		keyPrefix:      normalizeKeyPrefix(keyPrefix),
 		memClickCounts: make(map[string]int64),
+		flushSignal:    make(chan struct{}, 1),
 	}
 
 	// 如果提供了Redis地址，尝试连接Redis
-	if redisAddr != "" {
-		// 配置Redis连接选项
-		options := &redis.Options{
-			Addr: redisAddr,
-			DB:   redisDB,
-		}
-
-		// 如果提供了密码，设置密码
-		if redisPassword != "" {
-			options.Password = redisPassword
-		}
-
-		rdb := redis.NewClient(options)
-
+	if rdb := newRedisClient(redisOpts); rdb != nil {
 		// 测试Redis连接
 		if err := rdb.Ping(manager.ctx).Err(); err != nil {
 			log.Printf("Redis连接失败，仅使用内存缓存: %v", err)
+			rdb.Close()
 		} else {
 			manager.redisClient = rdb
 			manager.useRedis = true
+			manager.migrateLegacyClickKeys()
 			log.Println("Redis缓存已启用")
 		}
 	}
@@ -72,10 +130,53 @@ func NewCacheManager(redisAddr string, redisPassword string, redisDB int, cacheE
 // NewManager 新的构造函数，用于兼容已有代码
 // NewManager 兼容旧接口
 func NewManager(redisAddr string, redisPassword string, redisDB int, cacheExpiry int) *Manager {
-	return NewCacheManager(redisAddr, redisPassword, redisDB, cacheExpiry, 10000) // 默认10000项
+	return NewCacheManager(redisAddr, redisPassword, redisDB, cacheExpiry, 10000, "") // 默认10000项
 }
 
-// Close 关闭缓存管理器
+// normalizeKeyPrefix 规范化键前缀，非空时以冒号结尾
+func normalizeKeyPrefix(prefix string) string {
+	if prefix != "" && !strings.HasSuffix(prefix, ":") {
+		prefix += ":"
+	}
+	return prefix
+}
+
+// key 为键加上命名空间前缀
+func (c *Manager) key(key string) string {
+	return c.keyPrefix + key
+}
+
+// scanPattern 生成带命名空间前缀的SCAN匹配模式（转义前缀中的通配符）
+func (c *Manager) scanPattern(pattern string) string {
+	var b strings.Builder
+	for _, r := range c.keyPrefix {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String() + pattern
+}
+
+// SetTTL 设置某类缓存条目的过期时间
+func (c *Manager) SetTTL(entryType EntryType, ttl time.Duration) {
+	c.ttlMutex.Lock()
+	defer c.ttlMutex.Unlock()
+	c.ttls[entryType] = ttl
+}
+
+// TTL 获取某类缓存条目的过期时间，未设置时使用默认过期时间
+func (c *Manager) TTL(entryType EntryType) time.Duration {
+	c.ttlMutex.RLock()
+	defer c.ttlMutex.RUnlock()
+	if ttl, ok := c.ttls[entryType]; ok && ttl > 0 {
+		return ttl
+	}
+	return c.expiry
+}
+
+// Close 关闭缓存管理器（不会持久化缓冲的点击计数，关闭前应先调用 Flush）
 func (c *Manager) Close() error {
 	if c.useRedis && c.redisClient != nil {
 		return c.redisClient.Close()
@@ -83,67 +184,241 @@ func (c *Manager) Close() error {
 	return nil
 }
 
-// GetURL 获取URL
-func (c *Manager) GetURL(shortCode string) (*models.URL, bool) {
-	key := fmt.Sprintf("url:%s", shortCode)
+// GetURL 获取URL的精简记录
+func (c *Manager) GetURL(shortCode string) (*CachedURL, bool) {
+	key := c.key(fmt.Sprintf("url:%s", shortCode))
 
 	// 1. 先查内存缓存
-	if data, found := c.memCache.Get(key); found {
-		if url, ok := data.(*models.URL); ok {
-			return url, true
-		}
+	if url, found := c.urlCache.Get(key); found {
+		c.memHits.Add(1)
+		return url, true
 	}
 
 	// 2. 查Redis（如果可用）
 	if c.useRedis {
 		val, err := c.redisClient.Get(c.ctx, key).Result()
 		if err == nil {
-			var url models.URL
+			var url CachedURL
 			if err := json.Unmarshal([]byte(val), &url); err == nil {
 				// 存入内存缓存
-				c.memCache.Set(key, &url, c.expiry)
+				c.urlCache.Set(key, &url, c.TTL(EntryURL))
+				c.redisHits.Add(1)
 				return &url, true
 			}
 		}
 	}
 
+	c.misses.Add(1)
 	return nil, false
 }
 
-// SetURL 设置URL缓存（带数量限制）
-func (c *Manager) SetURL(shortCode string, url *models.URL) {
-	key := fmt.Sprintf("url:%s", shortCode)
-
-	// 检查是否已存在
-	if _, found := c.memCache.Get(key); !found {
-		// 检查是否超出限制
-		c.itemsMutex.Lock()
-		if c.currentItems >= int64(c.maxItems) {
-			// 清理一些最旧的项目（LRU策略由go-cache自动处理）
-			log.Printf("内存缓存已达上限 (%d)，依赖过期清理机制", c.maxItems)
-			c.itemsMutex.Unlock()
-			return
-		}
-		c.currentItems++
-		c.itemsMutex.Unlock()
-	}
+// SetURL 设置URL缓存（内存中超过 CacheMaxItems 时淘汰最久未使用的条目）
+func (c *Manager) SetURL(shortCode string, url *CachedURL) {
+	key := c.key(fmt.Sprintf("url:%s", shortCode))
 
 	// 存入内存缓存
-	c.memCache.Set(key, url, c.expiry)
+	ttl := c.TTL(EntryURL)
+	c.urlCache.Set(key, url, ttl)
+	c.memCache.Delete(c.notFoundKey(shortCode))
+	c.memCache.Delete(c.unavailableKey(shortCode))
 
-	// 存入Redis（如果可用）
+	// 存入Redis（如果可用），同时清除所有实例共享的负缓存
 	if c.useRedis {
 		if data, err := json.Marshal(url); err == nil {
-			if err := c.redisClient.Set(c.ctx, key, data, c.expiry).Err(); err != nil {
+			if err := c.redisClient.Set(c.ctx, key, data, ttl).Err(); err != nil {
 				log.Printf("Redis设置缓存失败: %v", err)
 			}
 		}
+		if err := c.redisClient.Del(c.ctx, c.notFoundKey(shortCode)).Err(); err != nil {
+			log.Printf("Redis删除负缓存失败: %v", err)
+		}
 	}
 }
 
+// Stats URL缓存统计
+type Stats struct {
+	Items     int   `json:"items"`      // 当前内存中的URL条目数
+	MaxItems  int   `json:"max_items"`  // 容量上限，0表示不限制
+	Evictions int64 `json:"evictions"`  // 因容量不足被淘汰的条目数，持续增长说明 CACHE_MAX_ITEMS 偏小
+	HighWater int   `json:"high_water"` // 历史最大条目数
+
+	MemHits   int64 `json:"mem_hits"`   // GetURL 由内存缓存命中的次数
+	RedisHits int64 `json:"redis_hits"` // GetURL 由Redis命中的次数
+	Misses    int64 `json:"misses"`     // GetURL 两层均未命中的次数
+}
+
+// Stats 返回URL缓存统计（仅本进程，Prefork模式下每个子进程各自统计）
+func (c *Manager) Stats() Stats {
+	stats := c.urlCache.stats()
+	stats.MemHits = c.memHits.Load()
+	stats.RedisHits = c.redisHits.Load()
+	stats.Misses = c.misses.Load()
+	return stats
+}
+
+// RedisEnabled 是否已连接Redis；为 false 时缓存和点击计数只保存在本进程内存中
+func (c *Manager) RedisEnabled() bool {
+	return c.useRedis
+}
+
+// SetLogEvictions 设置URL缓存淘汰条目时是否输出日志
+func (c *Manager) SetLogEvictions(enabled bool) {
+	c.urlCache.setLogEvictions(enabled)
+}
+
 // DeleteURL 删除缓存
 func (c *Manager) DeleteURL(shortCode string) {
-	key := fmt.Sprintf("url:%s", shortCode)
+	key := c.key(fmt.Sprintf("url:%s", shortCode))
+	c.urlCache.Delete(key)
+	c.memCache.Delete(c.unavailableKey(shortCode))
+
+	if c.useRedis {
+		if err := c.redisClient.Del(c.ctx, key).Err(); err != nil {
+			log.Printf("Redis删除缓存失败: %v", err)
+		}
+	}
+}
+
+// SetNotFound 记录短代码在数据库中不存在，过期前重复查询不再访问数据库
+// Redis可用时只保存在Redis中，任一实例（或Prefork子进程）创建同名短代码时由 SetURL 清除；否则保存在内存中
+func (c *Manager) SetNotFound(shortCode string) {
+	key := c.notFoundKey(shortCode)
+	if c.useRedis {
+		if err := c.redisClient.Set(c.ctx, key, "1", c.TTL(EntryNotFound)).Err(); err != nil {
+			log.Printf("Redis设置负缓存失败: %v", err)
+		}
+		return
+	}
+	c.memCache.Set(key, true, c.TTL(EntryNotFound))
+}
+
+// IsNotFound 检查短代码是否已被标记为不存在，Redis出错时视为未标记
+func (c *Manager) IsNotFound(shortCode string) bool {
+	key := c.notFoundKey(shortCode)
+	if c.useRedis {
+		exists, err := c.redisClient.Exists(c.ctx, key).Result()
+		if err != nil {
+			log.Printf("Redis查询负缓存失败: %v", err)
+			return false
+		}
+		return exists > 0
+	}
+	_, found := c.memCache.Get(key)
+	return found
+}
+
+func (c *Manager) notFoundKey(shortCode string) string {
+	return c.key("notfound:" + shortCode)
+}
+
+// SetUnavailable 记录缓存中的短链接已从数据库确认不可用，过期前重复访问不再查询数据库
+// 只保存在本进程内存中，由 SetURL/DeleteURL 清除；其他实例上的修改最晚在过期后生效
+func (c *Manager) SetUnavailable(shortCode string) {
+	c.memCache.Set(c.unavailableKey(shortCode), true, c.TTL(EntryUnavailable))
+}
+
+// IsUnavailable 检查短链接是否刚确认过不可用
+func (c *Manager) IsUnavailable(shortCode string) bool {
+	_, found := c.memCache.Get(c.unavailableKey(shortCode))
+	return found
+}
+
+func (c *Manager) unavailableKey(shortCode string) string {
+	return c.key("unavailable:" + shortCode)
+}
+
+// IncrWindow 在固定时间窗口内递增计数，返回当前计数和窗口剩余时间
+// 窗口从第一次计数开始，过期后自动重置
+func (c *Manager) IncrWindow(key string, window time.Duration) (int64, time.Duration) {
+	key = c.key(key)
+	if c.useRedis {
+		count, remaining, err := c.incrWindowRedis(key, window)
+		if err == nil {
+			return count, remaining
+		}
+		log.Printf("Redis计数失败，使用内存计数: %v", err)
+	}
+
+	// 内存计数
+	c.memCache.Add(key, int64(0), window)
+	count, err := c.memCache.IncrementInt64(key, 1)
+	if err != nil {
+		// 键在Add和Increment之间过期，重新开始一个窗口
+		c.memCache.Set(key, int64(1), window)
+		return 1, window
+	}
+	remaining := window
+	if _, expiration, found := c.memCache.GetWithExpiration(key); found && !expiration.IsZero() {
+		remaining = time.Until(expiration)
+	}
+	return count, remaining
+}
+
+// incrWindowRedis 使用Redis实现窗口计数
+func (c *Manager) incrWindowRedis(key string, window time.Duration) (int64, time.Duration, error) {
+	pipe := c.redisClient.Pipeline()
+	incr := pipe.Incr(c.ctx, key)
+	ttl := pipe.PTTL(c.ctx, key)
+	if _, err := pipe.Exec(c.ctx); err != nil {
+		return 0, 0, err
+	}
+
+	remaining := ttl.Val()
+	// 新建的键（或没有过期时间的键）需要设置窗口过期时间
+	if incr.Val() == 1 || remaining < 0 {
+		if err := c.redisClient.Expire(c.ctx, key, window).Err(); err != nil {
+			return 0, 0, err
+		}
+		remaining = window
+	}
+	return incr.Val(), remaining, nil
+}
+
+// Get 获取通用缓存值（JSON反序列化到dest），先查内存再查Redis
+func (c *Manager) Get(key string, dest interface{}) bool {
+	key = c.key(key)
+	if data, found := c.memCache.Get(key); found {
+		if raw, ok := data.([]byte); ok {
+			return json.Unmarshal(raw, dest) == nil
+		}
+	}
+
+	if c.useRedis {
+		val, err := c.redisClient.Get(c.ctx, key).Bytes()
+		if err == nil && json.Unmarshal(val, dest) == nil {
+			// 回填内存缓存，保持与Redis相同的剩余过期时间
+			ttl, err := c.redisClient.PTTL(c.ctx, key).Result()
+			if err == nil && ttl > 0 {
+				c.memCache.Set(key, val, ttl)
+			}
+			return true
+		}
+	}
+
+	return false
+}
+
+// Set 设置通用缓存值（JSON序列化），同时写入内存和Redis
+func (c *Manager) Set(key string, value interface{}, ttl time.Duration) error {
+	key = c.key(key)
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	c.memCache.Set(key, data, ttl)
+
+	if c.useRedis {
+		if err := c.redisClient.Set(c.ctx, key, data, ttl).Err(); err != nil {
+			log.Printf("Redis设置缓存失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// Delete 删除通用缓存值
+func (c *Manager) Delete(key string) {
+	key = c.key(key)
 	c.memCache.Delete(key)
 
 	if c.useRedis {
@@ -153,13 +428,37 @@ func (c *Manager) DeleteURL(shortCode string) {
 	}
 }
 
+// Incr 递增计数并返回新值，ttl从第一次计数开始计算
+func (c *Manager) Incr(key string, ttl time.Duration) int64 {
+	count, _ := c.IncrWindow(key, ttl)
+	return count
+}
+
+// RevokeToken 吊销令牌（按jti），ttl应不短于令牌剩余有效期
+func (c *Manager) RevokeToken(jti string, ttl time.Duration) {
+	if err := c.Set(fmt.Sprintf("revoked:%s", jti), true, ttl); err != nil {
+		log.Printf("吊销令牌失败: %v", err)
+	}
+}
+
+// IsTokenRevoked 检查令牌是否已被吊销
+func (c *Manager) IsTokenRevoked(jti string) bool {
+	var revoked bool
+	return c.Get(fmt.Sprintf("revoked:%s", jti), &revoked) && revoked
+}
+
+// clickHashKey 缓冲点击计数的Redis哈希，字段为短代码，值为尚未同步的点击数
+func (c *Manager) clickHashKey() string {
+	return c.key("clicks")
+}
+
 // IncrementClick 增加点击计数（异步）
 func (c *Manager) IncrementClick(shortCode string) {
 	go func() {
 		// 优先使用Redis，如果Redis不可用则使用内存计数
 		if c.useRedis {
-			key := fmt.Sprintf("clicks:%s", shortCode)
-			if err := c.redisClient.Incr(c.ctx, key).Err(); err != nil {
+			key := c.clickHashKey()
+			if err := c.redisClient.HIncrBy(c.ctx, key, shortCode, 1).Err(); err != nil {
 				log.Printf("Redis增加点击计数失败: %v", err)
 				// Redis失败时使用内存计数
 				c.incrementMemoryClick(shortCode)
@@ -175,24 +474,50 @@ func (c *Manager) IncrementClick(shortCode string) {
 	}()
 }
 
+// SetMaxClickKeys 设置内存点击计数的软上限
+// 每个键约占几十字节，上限决定了两次同步之间点击计数表的大致内存占用；
+// 达到上限后通过 FlushSignal 通知同步任务提前同步，同步完成前的新点击仍会写入，因此可能短暂超出
+func (c *Manager) SetMaxClickKeys(n int) {
+	c.memClickMutex.Lock()
+	defer c.memClickMutex.Unlock()
+	c.maxClickKeys = n
+}
+
+// FlushSignal 返回提前同步的通知通道，内存点击计数超过上限时触发
+func (c *Manager) FlushSignal() <-chan struct{} {
+	return c.flushSignal
+}
+
 // incrementMemoryClick 内存点击计数增加
 func (c *Manager) incrementMemoryClick(shortCode string) {
 	c.memClickMutex.Lock()
-	defer c.memClickMutex.Unlock()
 	c.memClickCounts[shortCode]++
+	overLimit := c.maxClickKeys > 0 && len(c.memClickCounts) >= c.maxClickKeys
+	c.memClickMutex.Unlock()
+
+	if overLimit {
+		// 非阻塞发送：已有未处理的通知时无需重复通知
+		select {
+		case c.flushSignal <- struct{}{}:
+		default:
+		}
+	}
 }
 
 // GetAndResetClicks 获取并重置点击计数
 func (c *Manager) GetAndResetClicks(shortCode string) int64 {
 	var count int64
 
-	// 先尝试从Redis获取
+	// 先尝试从Redis获取，HGET 和 HDEL 放在同一个事务中，避免两者之间的点击丢失
 	if c.useRedis {
-		key := fmt.Sprintf("clicks:%s", shortCode)
-		val, err := c.redisClient.GetDel(c.ctx, key).Result()
+		var get *redis.StringCmd
+		_, err := c.redisClient.TxPipelined(c.ctx, func(pipe redis.Pipeliner) error {
+			get = pipe.HGet(c.ctx, c.clickHashKey(), shortCode)
+			pipe.HDel(c.ctx, c.clickHashKey(), shortCode)
+			return nil
+		})
 		if err == nil {
-			redisCount, err := strconv.ParseInt(val, 10, 64)
-			if err == nil {
+			if redisCount, err := get.Int64(); err == nil {
 				count += redisCount
 			}
 		}
@@ -208,70 +533,168 @@ func (c *Manager) GetAndResetClicks(shortCode string) int64 {
 	return count
 }
 
+// GetPendingClicks 获取尚未同步到数据库的点击计数（不重置）
+func (c *Manager) GetPendingClicks(shortCode string) int64 {
+	var count int64
+
+	if c.useRedis {
+		if redisCount, err := c.redisClient.HGet(c.ctx, c.clickHashKey(), shortCode).Int64(); err == nil {
+			count += redisCount
+		}
+	}
+
+	c.memClickMutex.RLock()
+	count += c.memClickCounts[shortCode]
+	c.memClickMutex.RUnlock()
+
+	return count
+}
+
 // IncrementClickCount 增加点击计数（兼容旧接口）
 func (c *Manager) IncrementClickCount(shortCode string) {
 	c.IncrementClick(shortCode)
 }
 
-// GetAllClickCounts 获取所有点击计数
-func (c *Manager) GetAllClickCounts() map[string]int64 {
-	results := make(map[string]int64)
+// scanBatchSize SCAN 每次迭代的 COUNT 提示
+const scanBatchSize = 1000
 
-	// 获取Redis中的计数
-	if c.useRedis {
-		// 使用 Redis 的 SCAN 命令获取所有 clicks:* 键
-		iter := c.redisClient.Scan(c.ctx, 0, "clicks:*", 0).Iterator()
-		for iter.Next(c.ctx) {
+// scanLegacyClickKeys 扫描旧版本按短代码分别存储的 clicks:<短代码> 键
+// SCAN 只保证完整遍历期间一直存在的键，且同一个键可能被返回多次（至少一次语义），因此这里去重
+// 集群模式下 SCAN 只作用于单个节点，需要逐个主节点扫描
+func (c *Manager) scanLegacyClickKeys() ([]string, error) {
+	seen := make(map[string]struct{})
+	var keys []string
+	var mutex sync.Mutex
+
+	scan := func(ctx context.Context, client redis.Cmdable) error {
+		iter := client.Scan(ctx, 0, c.scanPattern("clicks:*"), scanBatchSize).Iterator()
+		for iter.Next(ctx) {
 			key := iter.Val()
-			val, err := c.redisClient.Get(c.ctx, key).Result()
-			if err == nil {
-				count, err := strconv.ParseInt(val, 10, 64)
-				if err == nil {
-					shortCode := strings.TrimPrefix(key, "clicks:")
-					results[shortCode] = count
-				}
+			mutex.Lock()
+			if _, ok := seen[key]; !ok {
+				seen[key] = struct{}{}
+				keys = append(keys, key)
 			}
+			mutex.Unlock()
 		}
-
-		if err := iter.Err(); err != nil {
-			log.Printf("Redis扫描失败: %v", err)
-		}
+		return iter.Err()
 	}
 
-	// 合并内存中的计数
-	c.memClickMutex.RLock()
-	for shortCode, count := range c.memClickCounts {
-		results[shortCode] += count
+	var err error
+	if cluster, ok := c.redisClient.(*redis.ClusterClient); ok {
+		err = cluster.ForEachMaster(c.ctx, func(ctx context.Context, client *redis.Client) error {
+			return scan(ctx, client)
+		})
+	} else {
+		err = scan(c.ctx, c.redisClient)
 	}
-	c.memClickMutex.RUnlock()
 
-	return results
+	return keys, err
 }
 
-// ClearClickCounts 清空所有点击计数
-func (c *Manager) ClearClickCounts() {
-	// 清空Redis中的计数
-	if c.useRedis {
-		// 获取所有 clicks:* 键并删除
-		iter := c.redisClient.Scan(c.ctx, 0, "clicks:*", 0).Iterator()
-		keys := []string{}
-		for iter.Next(c.ctx) {
-			keys = append(keys, iter.Val())
+// takeLegacyClickScript 原子地读取并删除旧点击计数键，等价于 GETDEL（Redis 6.2 才支持）
+// 只操作一个键，集群模式下不会跨槽
+var takeLegacyClickScript = redis.NewScript(`
+local count = redis.call('GET', KEYS[1])
+if count then
+	redis.call('DEL', KEYS[1])
+end
+return count
+`)
+
+// migrateLegacyClickKeys 将旧版本遗留的 clicks:<短代码> 计数并入点击计数哈希
+// 只在连接Redis时执行一次，升级前尚未同步的点击不会丢失
+func (c *Manager) migrateLegacyClickKeys() {
+	keys, err := c.scanLegacyClickKeys()
+	if err != nil {
+		log.Printf("Redis扫描旧点击计数失败: %v", err)
+	}
+	migrated := 0
+	for _, key := range keys {
+		shortCode := strings.TrimPrefix(key, c.key("clicks:"))
+		count, err := takeLegacyClickScript.Run(c.ctx, c.redisClient, []string{key}).Int64()
+		if err == redis.Nil {
+			continue // 扫描之后已被删除
+		}
+		if err != nil {
+			log.Printf("读取旧点击计数失败 [%s]: %v", shortCode, err)
+			continue
+		}
+		if err := c.redisClient.HIncrBy(c.ctx, c.clickHashKey(), shortCode, count).Err(); err != nil {
+			log.Printf("迁移旧点击计数失败，丢失 %d 次点击 [%s]: %v", count, shortCode, err)
+			continue
+		}
+		migrated++
+	}
+	if migrated > 0 {
+		log.Printf("已迁移 %d 个旧点击计数键", migrated)
+	}
+}
+
+// parseClickCounts 解析点击计数哈希的字段值
+func parseClickCounts(values map[string]string, results map[string]int64) {
+	for shortCode, val := range values {
+		if count, err := strconv.ParseInt(val, 10, 64); err == nil {
+			results[shortCode] += count
 		}
+	}
+}
+
+// RestoreClicks 将写入数据库失败的点击计数放回缓冲区，与期间的新点击累加，下次同步时重试
+// 放回的计数不触发提前同步，避免数据库不可用时反复同步
+func (c *Manager) RestoreClicks(counts map[string]int64) {
+	if len(counts) == 0 {
+		return
+	}
 
-		if len(keys) > 0 {
-			if err := c.redisClient.Del(c.ctx, keys...).Err(); err != nil {
-				log.Printf("Redis批量删除失败: %v", err)
+	if c.useRedis {
+		key := c.clickHashKey()
+		_, err := c.redisClient.Pipelined(c.ctx, func(pipe redis.Pipeliner) error {
+			for shortCode, count := range counts {
+				pipe.HIncrBy(c.ctx, key, shortCode, count)
 			}
+			pipe.Expire(c.ctx, key, 24*time.Hour)
+			return nil
+		})
+		if err == nil {
+			return
 		}
+		log.Printf("Redis放回点击计数失败，使用内存: %v", err)
+	}
 
-		if err := iter.Err(); err != nil {
-			log.Printf("Redis扫描失败: %v", err)
+	c.memClickMutex.Lock()
+	for shortCode, count := range counts {
+		c.memClickCounts[shortCode] += count
+	}
+	c.memClickMutex.Unlock()
+}
+
+// Flush 取出并清空所有缓冲的点击计数，调用方负责持久化返回的计数
+// Redis中的计数在同一个事务中 HGETALL 并 DEL，内存计数整体替换，避免读取和清空之间的点击丢失
+func (c *Manager) Flush() map[string]int64 {
+	results := make(map[string]int64)
+
+	if c.useRedis {
+		var getAll *redis.StringStringMapCmd
+		_, err := c.redisClient.TxPipelined(c.ctx, func(pipe redis.Pipeliner) error {
+			getAll = pipe.HGetAll(c.ctx, c.clickHashKey())
+			pipe.Del(c.ctx, c.clickHashKey())
+			return nil
+		})
+		if err != nil {
+			log.Printf("Redis取出点击计数失败: %v", err)
+		} else {
+			parseClickCounts(getAll.Val(), results)
 		}
 	}
 
-	// 清空内存中的计数
 	c.memClickMutex.Lock()
+	memCounts := c.memClickCounts
 	c.memClickCounts = make(map[string]int64)
 	c.memClickMutex.Unlock()
+
+	for shortCode, count := range memCounts {
+		results[shortCode] += count
+	}
+	return results
 }
@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+var (
+	testRedisOnce sync.Once
+	testRedis     *miniredis.Miniredis
+	testRedisErr  error
+)
+
+// sharedTestRedis 返回包内测试共用的进程内Redis（miniredis），各测试通过不同的键前缀隔离
+func sharedTestRedis(t testing.TB) *miniredis.Miniredis {
+	t.Helper()
+	testRedisOnce.Do(func() {
+		testRedis, testRedisErr = miniredis.Run()
+	})
+	if testRedisErr != nil {
+		t.Fatalf("start miniredis: %v", testRedisErr)
+	}
+	return testRedis
+}
+
+// newRedisTestManager 连接共用的进程内Redis，同一前缀的多个实例模拟共享Redis的多个进程
+// 每个测试使用独立的键前缀，结束时不清理
+func newRedisTestManager(t testing.TB, prefix string) *Manager {
+	t.Helper()
+	m := NewCacheManager(sharedTestRedis(t).Addr(), "", 0, 60, 1000, prefix)
+	if !m.RedisEnabled() {
+		t.Fatal("miniredis not reachable")
+	}
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+func testPrefix(t testing.TB) string {
+	return "surltest:" + t.Name() + ":" + strconv.FormatInt(time.Now().UnixNano(), 36)
+}
+
+func TestNotFoundMemory(t *testing.T) {
+	m := NewCacheManager("", "", 0, 60, 1000, "")
+	if m.IsNotFound("nope") {
+		t.Fatal("unmarked code reported as not found")
+	}
+	m.SetNotFound("nope")
+	if !m.IsNotFound("nope") {
+		t.Fatal("tombstone not recorded")
+	}
+	// 创建同名短代码时清除负缓存
+	m.SetURL("nope", &CachedURL{ShortCode: "nope", IsActive: true})
+	if m.IsNotFound("nope") {
+		t.Fatal("SetURL did not clear the tombstone")
+	}
+}
+
+func TestNotFoundExpires(t *testing.T) {
+	m := NewCacheManager("", "", 0, 60, 1000, "")
+	m.SetTTL(EntryNotFound, 20*time.Millisecond)
+	m.SetNotFound("gone")
+	time.Sleep(40 * time.Millisecond)
+	if m.IsNotFound("gone") {
+		t.Fatal("tombstone outlived its TTL")
+	}
+}
+
+func TestNotFoundSharedThroughRedis(t *testing.T) {
+	prefix := testPrefix(t)
+	a := newRedisTestManager(t, prefix)
+	b := newRedisTestManager(t, prefix)
+
+	a.SetNotFound("fresh")
+	if !b.IsNotFound("fresh") {
+		t.Fatal("tombstone set on one instance not visible on another")
+	}
+	// 另一个实例创建该短代码后，两个实例都不再视为不存在
+	b.SetURL("fresh", &CachedURL{ShortCode: "fresh", IsActive: true})
+	if a.IsNotFound("fresh") {
+		t.Fatal("tombstone still visible after SetURL on another instance")
+	}
+}
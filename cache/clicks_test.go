@@ -0,0 +1,312 @@
+package cache
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// waitPending 等待异步的 IncrementClick 全部计入
+func waitPending(t testing.TB, m *Manager, shortCode string, want int64) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for m.GetPendingClicks(shortCode) < want {
+		if time.Now().After(deadline) {
+			t.Fatalf("pending clicks for %s = %d, want %d", shortCode, m.GetPendingClicks(shortCode), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFlushMemoryClicks(t *testing.T) {
+	m := NewCacheManager("", "", 0, 60, 1000, "")
+	for i := 0; i < 3; i++ {
+		m.IncrementClick("a")
+	}
+	m.IncrementClick("b")
+	waitPending(t, m, "a", 3)
+	waitPending(t, m, "b", 1)
+
+	if got, want := m.Flush(), map[string]int64{"a": 3, "b": 1}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Flush() = %v, want %v", got, want)
+	}
+	if got := m.Flush(); len(got) != 0 {
+		t.Fatalf("second Flush() = %v, want empty", got)
+	}
+}
+
+func TestRestoreClicksAddsToNewClicks(t *testing.T) {
+	m := NewCacheManager("", "", 0, 60, 1000, "")
+	m.IncrementClick("a")
+	waitPending(t, m, "a", 1)
+
+	m.RestoreClicks(map[string]int64{"a": 4, "b": 2})
+	if got, want := m.Flush(), map[string]int64{"a": 5, "b": 2}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Flush() = %v, want %v", got, want)
+	}
+}
+
+func TestMaxClickKeysSignalsFlush(t *testing.T) {
+	m := NewCacheManager("", "", 0, 60, 1000, "")
+	m.SetMaxClickKeys(2)
+	m.incrementMemoryClick("a")
+	select {
+	case <-m.FlushSignal():
+		t.Fatal("flush signalled below the key limit")
+	default:
+	}
+	m.incrementMemoryClick("b")
+	select {
+	case <-m.FlushSignal():
+	default:
+		t.Fatal("flush not signalled at the key limit")
+	}
+}
+
+func TestFlushRedisClicksAcrossInstances(t *testing.T) {
+	prefix := testPrefix(t)
+	a := newRedisTestManager(t, prefix)
+	b := newRedisTestManager(t, prefix)
+
+	a.IncrementClick("x")
+	b.IncrementClick("x")
+	waitPending(t, a, "x", 2)
+
+	if got, want := b.Flush(), map[string]int64{"x": 2}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Flush() = %v, want %v", got, want)
+	}
+	if got := a.GetPendingClicks("x"); got != 0 {
+		t.Fatalf("pending after Flush = %d, want 0", got)
+	}
+}
+
+func TestMigrateLegacyClickKeys(t *testing.T) {
+	prefix := testPrefix(t)
+	m := newRedisTestManager(t, prefix)
+	if err := m.redisClient.Set(m.ctx, m.key("clicks:old"), 5, time.Hour).Err(); err != nil {
+		t.Fatal(err)
+	}
+	m.IncrementClick("old")
+	waitPending(t, m, "old", 1)
+
+	m.migrateLegacyClickKeys()
+	if got := m.GetPendingClicks("old"); got != 6 {
+		t.Fatalf("pending after migration = %d, want 6", got)
+	}
+	if n, _ := m.redisClient.Exists(m.ctx, m.key("clicks:old")).Result(); n != 0 {
+		t.Fatal("legacy key not deleted")
+	}
+	// 再次迁移不会重复计入
+	m.migrateLegacyClickKeys()
+	if got := m.GetPendingClicks("old"); got != 6 {
+		t.Fatalf("pending after second migration = %d, want 6", got)
+	}
+}
+
+func BenchmarkIncrementMemoryClick(b *testing.B) {
+	m := NewCacheManager("", "", 0, 60, 1000, "")
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			m.incrementMemoryClick("code" + strconv.Itoa(i%1000))
+			i++
+		}
+	})
+}
+
+func BenchmarkFlushMemoryClicks(b *testing.B) {
+	m := NewCacheManager("", "", 0, 60, 1000, "")
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		for k := 0; k < 1000; k++ {
+			m.incrementMemoryClick("code" + strconv.Itoa(k))
+		}
+		b.StartTimer()
+		m.Flush()
+	}
+}
+
+// BenchmarkAggregateRedisClicks 对比点击计数哈希（一次 HGETALL+DEL）与旧版本按键存储（SCAN 后逐个 GETDEL）
+func BenchmarkAggregateRedisClicks(b *testing.B) {
+	const keys = 1000
+	m := newRedisTestManager(b, testPrefix(b))
+
+	b.Run("hash", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			counts := make(map[string]int64, keys)
+			for k := 0; k < keys; k++ {
+				counts["code"+strconv.Itoa(k)] = 1
+			}
+			m.RestoreClicks(counts)
+			b.StartTimer()
+			if got := m.Flush(); len(got) != keys {
+				b.Fatalf("flushed %d keys, want %d", len(got), keys)
+			}
+		}
+	})
+
+	b.Run("scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			pipe := m.redisClient.Pipeline()
+			for k := 0; k < keys; k++ {
+				pipe.Set(m.ctx, m.key("clicks:code"+strconv.Itoa(k)), 1, time.Hour)
+			}
+			if _, err := pipe.Exec(m.ctx); err != nil {
+				b.Fatal(err)
+			}
+			b.StartTimer()
+			found, err := m.scanLegacyClickKeys()
+			if err != nil {
+				b.Fatal(err)
+			}
+			for _, key := range found {
+				takeLegacyClickScript.Run(m.ctx, m.redisClient, []string{key})
+			}
+			if len(found) != keys {
+				b.Fatalf("scanned %d keys, want %d", len(found), keys)
+			}
+		}
+	})
+}
+
+func TestScanLegacyClickKeysManyKeys(t *testing.T) {
+	m := newRedisTestManager(t, testPrefix(t))
+	const keys = 5 * scanBatchSize
+	pipe := m.redisClient.Pipeline()
+	for k := 0; k < keys; k++ {
+		pipe.Set(m.ctx, m.key("clicks:code"+strconv.Itoa(k)), 1, time.Hour)
+	}
+	// 不匹配的键不应被扫描到
+	pipe.Set(m.ctx, m.key("url:code0"), "{}", time.Hour)
+	pipe.Set(m.ctx, m.key("clicksx"), 1, time.Hour)
+	if _, err := pipe.Exec(m.ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := m.scanLegacyClickKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool, len(found))
+	for _, key := range found {
+		if seen[key] {
+			t.Fatalf("key %s returned twice", key)
+		}
+		seen[key] = true
+	}
+	if len(seen) != keys {
+		t.Fatalf("scanned %d keys, want %d", len(seen), keys)
+	}
+
+	// 迁移后每个短代码恰好计入一次
+	m.migrateLegacyClickKeys()
+	counts := m.Flush()
+	if len(counts) != keys {
+		t.Fatalf("migrated %d codes, want %d", len(counts), keys)
+	}
+	for code, n := range counts {
+		if n != 1 {
+			t.Fatalf("code %s migrated with %d clicks, want 1", code, n)
+		}
+	}
+}
+
+type scanCursorKey struct{}
+
+// pagedScanHook 把 miniredis 一次返回全部键的 SCAN 拆成多页，相邻两页有 overlap 个重复的键，
+// 模拟真实Redis在遍历期间 rehash 时重复返回键的情况
+type pagedScanHook struct {
+	overlap int
+	counts  []interface{} // 每次 SCAN 收到的 COUNT 参数
+}
+
+func (h *pagedScanHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	if cmd.Name() != "scan" {
+		return ctx, nil
+	}
+	args := cmd.Args()
+	h.counts = append(h.counts, args[len(args)-1])
+	ctx = context.WithValue(ctx, scanCursorKey{}, args[1])
+	args[1] = 0 // miniredis 只接受从0开始的游标
+	return ctx, nil
+}
+
+func (h *pagedScanHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	scan, ok := cmd.(*redis.ScanCmd)
+	if !ok || scan.Err() != nil {
+		return nil
+	}
+	cursor, _ := ctx.Value(scanCursorKey{}).(uint64)
+	all, _ := scan.Val()
+	start := int(cursor) * scanBatchSize
+	end := start + scanBatchSize + h.overlap
+	if end >= len(all) {
+		scan.SetVal(all[start:], 0)
+		return nil
+	}
+	scan.SetVal(all[start:end], cursor+1)
+	return nil
+}
+
+func (h *pagedScanHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (h *pagedScanHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	return nil
+}
+
+func TestScanLegacyClickKeysDedupesAcrossPages(t *testing.T) {
+	m := newRedisTestManager(t, testPrefix(t))
+	const keys = 3*scanBatchSize + 7
+	pipe := m.redisClient.Pipeline()
+	for k := 0; k < keys; k++ {
+		pipe.Set(m.ctx, m.key("clicks:code"+strconv.Itoa(k)), 1, time.Hour)
+	}
+	if _, err := pipe.Exec(m.ctx); err != nil {
+		t.Fatal(err)
+	}
+	hook := &pagedScanHook{overlap: 25}
+	m.redisClient.AddHook(hook)
+
+	found, err := m.scanLegacyClickKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hook.counts) < 2 {
+		t.Fatalf("SCAN ran %d times, want several pages", len(hook.counts))
+	}
+	for _, count := range hook.counts {
+		if count != int64(scanBatchSize) {
+			t.Fatalf("SCAN COUNT = %v, want %d", count, scanBatchSize)
+		}
+	}
+	seen := make(map[string]bool, len(found))
+	for _, key := range found {
+		if seen[key] {
+			t.Fatalf("duplicate key %s returned by SCAN not removed", key)
+		}
+		seen[key] = true
+	}
+	if len(seen) != keys {
+		t.Fatalf("scanned %d keys, want %d", len(seen), keys)
+	}
+
+	// 重复返回的键只迁移一次
+	m.migrateLegacyClickKeys()
+	counts := m.Flush()
+	if len(counts) != keys {
+		t.Fatalf("migrated %d codes, want %d", len(counts), keys)
+	}
+	for code, n := range counts {
+		if n != 1 {
+			t.Fatalf("code %s migrated with %d clicks, want 1", code, n)
+		}
+	}
+}
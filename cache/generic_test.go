@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type genericValue struct {
+	Name  string            `json:"name"`
+	Count int               `json:"count"`
+	Tags  map[string]string `json:"tags"`
+}
+
+// roundTrip 在同一个实例上写入、读取、递增和删除通用值
+func roundTrip(t *testing.T, m *Manager) {
+	t.Helper()
+	want := genericValue{Name: "v", Count: 3, Tags: map[string]string{"a": "b"}}
+	if err := m.Set("generic:obj", want, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	var got genericValue
+	if !m.Get("generic:obj", &got) || !reflect.DeepEqual(got, want) {
+		t.Fatalf("Get = %+v, want %+v", got, want)
+	}
+
+	for i := int64(1); i <= 3; i++ {
+		if n := m.Incr("generic:counter", time.Minute); n != i {
+			t.Fatalf("Incr #%d = %d", i, n)
+		}
+	}
+
+	m.Delete("generic:obj")
+	var gone genericValue
+	if m.Get("generic:obj", &gone) {
+		t.Fatal("value still present after Delete")
+	}
+	if err := m.Set("generic:func", func() {}, time.Minute); err == nil {
+		t.Fatal("Set accepted a value that cannot be encoded")
+	}
+}
+
+func TestGenericMemoryRoundTrip(t *testing.T) {
+	m := NewCacheManager("", "", 0, 60, 1000, "")
+	roundTrip(t, m)
+
+	var missing string
+	if m.Get("generic:missing", &missing) {
+		t.Fatal("Get found a key that was never set")
+	}
+	// 类型不匹配时视为未命中
+	if err := m.Set("generic:str", "text", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if m.Get("generic:str", &n) {
+		t.Fatal("Get decoded a string into an int")
+	}
+}
+
+func TestGenericValueExpires(t *testing.T) {
+	m := NewCacheManager("", "", 0, 60, 1000, "")
+	if err := m.Set("generic:short", 1, 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if n := m.Incr("generic:window", 20*time.Millisecond); n != 1 {
+		t.Fatalf("Incr = %d, want 1", n)
+	}
+	time.Sleep(40 * time.Millisecond)
+	var v int
+	if m.Get("generic:short", &v) {
+		t.Fatal("value outlived its TTL")
+	}
+	if n := m.Incr("generic:window", 20*time.Millisecond); n != 1 {
+		t.Fatalf("Incr after window = %d, want a new window", n)
+	}
+}
+
+func TestGenericRedisRoundTrip(t *testing.T) {
+	prefix := testPrefix(t)
+	a := newRedisTestManager(t, prefix)
+	b := newRedisTestManager(t, prefix)
+	roundTrip(t, a)
+
+	// 写入一个实例，从另一个实例读取（内存未命中时回源Redis）
+	if err := a.Set("generic:shared", "hello", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	var got string
+	if !b.Get("generic:shared", &got) || got != "hello" {
+		t.Fatalf("b.Get = %q", got)
+	}
+	if n := b.Incr("generic:counter", time.Minute); n != 4 {
+		t.Fatalf("Incr on second instance = %d, want 4", n)
+	}
+	a.Delete("generic:shared")
+	b.memCache.Flush() // 丢弃b的内存副本，确认Redis中已删除
+	if b.Get("generic:shared", &got) {
+		t.Fatal("value still in Redis after Delete")
+	}
+}
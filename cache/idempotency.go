@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// IdempotentResponse 带幂等键的请求首次处理的结果
+type IdempotentResponse struct {
+	RequestHash string `json:"request_hash"` // 请求体摘要，用于发现同一个键被用于不同的请求
+	Status      int    `json:"status"`       // 为0表示首次请求仍在处理中
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
+
+// Pending 首次请求是否仍在处理中
+func (r *IdempotentResponse) Pending() bool {
+	return r.Status == 0
+}
+
+// idempotencyKey 幂等键按 scope（用户）隔离，不同用户使用相同的键互不影响
+func (c *Manager) idempotencyKey(scope, key string) string {
+	return c.key("idempotency:" + scope + ":" + key)
+}
+
+// ReserveIdempotent 原子地占用幂等键并标记为处理中，键已存在时返回 false
+// Redis可用时只使用Redis，保证多个实例/进程之间互斥
+func (c *Manager) ReserveIdempotent(scope, key, requestHash string, ttl time.Duration) bool {
+	data, err := json.Marshal(IdempotentResponse{RequestHash: requestHash})
+	if err != nil {
+		return false
+	}
+	k := c.idempotencyKey(scope, key)
+
+	if c.useRedis {
+		ok, err := c.redisClient.SetNX(c.ctx, k, data, ttl).Result()
+		if err == nil {
+			return ok
+		}
+		log.Printf("Redis占用幂等键失败，使用内存: %v", err)
+	}
+	return c.memCache.Add(k, data, ttl) == nil
+}
+
+// GetIdempotent 获取幂等键对应的结果
+// 与 Get 不同，Redis可用时不回填内存，避免本进程长时间看到过时的处理中标记
+func (c *Manager) GetIdempotent(scope, key string) (*IdempotentResponse, bool) {
+	k := c.idempotencyKey(scope, key)
+
+	var data []byte
+	if c.useRedis {
+		if val, err := c.redisClient.Get(c.ctx, k).Bytes(); err == nil {
+			data = val
+		}
+	}
+	if data == nil {
+		raw, found := c.memCache.Get(k)
+		if !found {
+			return nil, false
+		}
+		data, _ = raw.([]byte)
+	}
+
+	var resp IdempotentResponse
+	if json.Unmarshal(data, &resp) != nil {
+		return nil, false
+	}
+	return &resp, true
+}
+
+// SetIdempotent 保存首次请求的结果，之后使用相同键的请求直接返回该结果
+func (c *Manager) SetIdempotent(scope, key string, resp IdempotentResponse, ttl time.Duration) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("保存幂等结果失败: %v", err)
+		return
+	}
+	k := c.idempotencyKey(scope, key)
+
+	if c.useRedis {
+		err := c.redisClient.Set(c.ctx, k, data, ttl).Err()
+		if err == nil {
+			return
+		}
+		log.Printf("Redis保存幂等结果失败，使用内存: %v", err)
+	}
+	c.memCache.Set(k, data, ttl)
+}
+
+// ReleaseIdempotent 释放幂等键，用于首次请求失败后允许客户端重试
+func (c *Manager) ReleaseIdempotent(scope, key string) {
+	k := c.idempotencyKey(scope, key)
+	c.memCache.Delete(k)
+
+	if c.useRedis {
+		if err := c.redisClient.Del(c.ctx, k).Err(); err != nil {
+			log.Printf("Redis释放幂等键失败: %v", err)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"container/list"
+	"log"
+	"sync"
+	"time"
+)
+
+// urlLRU 带过期时间的URL内存缓存，超过容量时淘汰最久未使用的条目
+type urlLRU struct {
+	maxItems int // 容量，<=0 表示不限制
+	items    map[string]*list.Element
+	order    *list.List // 从前到后为最近到最久使用
+	mutex    sync.Mutex
+
+	evictions    int64 // 因容量不足被淘汰的条目数（不含过期和主动删除）
+	highWater    int   // 历史最大条目数
+	logEvictions bool  // 淘汰时输出日志，用于调整 CacheMaxItems
+}
+
+type lruEntry struct {
+	key       string
+	url       *CachedURL
+	expiresAt time.Time
+}
+
+func newURLLRU(maxItems int) *urlLRU {
+	return &urlLRU{
+		maxItems: maxItems,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get 获取条目并标记为最近使用，过期条目视为不存在并移除
+func (l *urlLRU) Get(key string) (*CachedURL, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	elem, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*lruEntry)
+	if time.Now().After(entry.expiresAt) {
+		l.removeElement(elem)
+		return nil, false
+	}
+	l.order.MoveToFront(elem)
+	return entry.url, true
+}
+
+// Set 写入条目，容量已满时淘汰最久未使用的条目
+func (l *urlLRU) Set(key string, url *CachedURL, ttl time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	expiresAt := time.Now().Add(ttl)
+	if elem, ok := l.items[key]; ok {
+		entry := elem.Value.(*lruEntry)
+		entry.url = url
+		entry.expiresAt = expiresAt
+		l.order.MoveToFront(elem)
+		return
+	}
+
+	if l.maxItems > 0 && l.order.Len() >= l.maxItems {
+		l.evictOldest()
+	}
+	l.items[key] = l.order.PushFront(&lruEntry{key: key, url: url, expiresAt: expiresAt})
+	if n := l.order.Len(); n > l.highWater {
+		l.highWater = n
+	}
+}
+
+// Delete 删除条目
+func (l *urlLRU) Delete(key string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if elem, ok := l.items[key]; ok {
+		l.removeElement(elem)
+	}
+}
+
+// Len 返回当前条目数
+func (l *urlLRU) Len() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.order.Len()
+}
+
+// stats 返回容量和淘汰统计
+func (l *urlLRU) stats() Stats {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return Stats{
+		Items:     l.order.Len(),
+		MaxItems:  l.maxItems,
+		Evictions: l.evictions,
+		HighWater: l.highWater,
+	}
+}
+
+// setLogEvictions 设置淘汰时是否输出日志
+func (l *urlLRU) setLogEvictions(enabled bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.logEvictions = enabled
+}
+
+// evictOldest 淘汰最久未使用的条目，调用方需持有锁
+func (l *urlLRU) evictOldest() {
+	if elem := l.order.Back(); elem != nil {
+		l.removeElement(elem)
+		l.evictions++
+		if l.logEvictions {
+			log.Printf("URL缓存已满（%d项），淘汰最久未使用的条目: %s", l.maxItems, elem.Value.(*lruEntry).key)
+		}
+	}
+}
+
+func (l *urlLRU) removeElement(elem *list.Element) {
+	l.order.Remove(elem)
+	delete(l.items, elem.Value.(*lruEntry).key)
+}
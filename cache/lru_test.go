@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLRUEvictionCounter(t *testing.T) {
+	m := NewCacheManager("", "", 0, 60, 3, "")
+	for i := 0; i < 3; i++ {
+		m.SetURL("code"+strconv.Itoa(i), &CachedURL{IsActive: true})
+	}
+	if stats := m.Stats(); stats.Evictions != 0 || stats.Items != 3 || stats.HighWater != 3 || stats.MaxItems != 3 {
+		t.Fatalf("stats before eviction = %+v", stats)
+	}
+
+	// 访问 code0 使其成为最近使用，随后写入的条目淘汰 code1 和 code2
+	if _, ok := m.GetURL("code0"); !ok {
+		t.Fatal("code0 missing")
+	}
+	m.SetURL("code3", &CachedURL{IsActive: true})
+	m.SetURL("code4", &CachedURL{IsActive: true})
+	stats := m.Stats()
+	if stats.Evictions != 2 || stats.Items != 3 || stats.HighWater != 3 {
+		t.Fatalf("stats after eviction = %+v", stats)
+	}
+	for code, want := range map[string]bool{"code0": true, "code1": false, "code2": false, "code3": true, "code4": true} {
+		if _, ok := m.GetURL(code); ok != want {
+			t.Errorf("%s cached = %v, want %v", code, ok, want)
+		}
+	}
+
+	// 更新已有条目、主动删除和过期都不计入淘汰
+	m.SetURL("code0", &CachedURL{IsActive: false})
+	m.DeleteURL("code3")
+	m.SetTTL(EntryURL, time.Millisecond)
+	m.SetURL("code5", &CachedURL{IsActive: true})
+	time.Sleep(5 * time.Millisecond)
+	m.GetURL("code5")
+	if got := m.Stats().Evictions; got != 2 {
+		t.Fatalf("evictions = %d after update/delete/expiry, want 2", got)
+	}
+}
+
+func TestLRUUnboundedNeverEvicts(t *testing.T) {
+	l := newURLLRU(0)
+	for i := 0; i < 100; i++ {
+		l.Set("k"+strconv.Itoa(i), &CachedURL{}, time.Minute)
+	}
+	if stats := l.stats(); stats.Evictions != 0 || stats.Items != 100 || stats.HighWater != 100 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+func TestLRUEvictionLogging(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	m := NewCacheManager("", "", 0, 60, 1, "")
+	m.SetURL("first", &CachedURL{})
+	m.SetURL("second", &CachedURL{})
+	if buf.Len() != 0 {
+		t.Fatalf("eviction logged while logging disabled: %q", buf.String())
+	}
+	m.SetLogEvictions(true)
+	m.SetURL("third", &CachedURL{})
+	if !strings.Contains(buf.String(), "url:second") {
+		t.Fatalf("eviction log = %q, want the evicted key", buf.String())
+	}
+}
@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyPrefix(t *testing.T) {
+	cases := map[string]string{
+		"":          "url:abc",
+		"prod":      "prod:url:abc",
+		"prod:":     "prod:url:abc",
+		"tenant:a:": "tenant:a:url:abc",
+	}
+	for prefix, want := range cases {
+		m := NewCacheManager("", "", 0, 60, 1000, prefix)
+		if got := m.key("url:abc"); got != want {
+			t.Errorf("prefix %q: key = %q, want %q", prefix, got, want)
+		}
+	}
+
+	// 负缓存、不可用标记和点击计数哈希同样带前缀
+	m := NewCacheManager("", "", 0, 60, 1000, "prod")
+	for got, want := range map[string]string{
+		m.notFoundKey("abc"):    "prod:notfound:abc",
+		m.unavailableKey("abc"): "prod:unavailable:abc",
+		m.clickHashKey():        "prod:clicks",
+	} {
+		if got != want {
+			t.Errorf("key = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestScanPatternEscapesPrefix(t *testing.T) {
+	cases := map[string]string{
+		"":        "clicks:*",
+		"prod":    "prod:clicks:*",
+		"a*b":     `a\*b:clicks:*`,
+		"t?[x]":   `t\?\[x\]:clicks:*`,
+		`back\sl`: `back\\sl:clicks:*`,
+	}
+	for prefix, want := range cases {
+		m := NewCacheManager("", "", 0, 60, 1000, prefix)
+		if got := m.scanPattern("clicks:*"); got != want {
+			t.Errorf("prefix %q: scanPattern = %q, want %q", prefix, got, want)
+		}
+	}
+}
+
+func TestPrefixesIsolateRedisKeys(t *testing.T) {
+	base := testPrefix(t)
+	staging := newRedisTestManager(t, base+":staging")
+	prod := newRedisTestManager(t, base+":prod")
+
+	staging.SetURL("same", &CachedURL{ShortCode: "same", OriginalURL: "https://staging.example/", IsActive: true})
+	prod.SetURL("same", &CachedURL{ShortCode: "same", OriginalURL: "https://prod.example/", IsActive: true})
+	staging.urlCache.Delete(staging.key("url:same")) // 强制从Redis读取
+	if got, ok := staging.GetURL("same"); !ok || got.OriginalURL != "https://staging.example/" {
+		t.Fatalf("staging sees %+v", got)
+	}
+
+	if err := staging.Set("shared", "staging", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	var v string
+	if prod.Get("shared", &v) {
+		t.Fatalf("prod sees staging's generic value %q", v)
+	}
+	staging.SetNotFound("miss")
+	if prod.IsNotFound("miss") {
+		t.Fatal("prod sees staging's tombstone")
+	}
+	staging.SetUnavailable("off")
+	if prod.IsUnavailable("off") {
+		t.Fatal("prod sees staging's unavailable marker")
+	}
+
+	// 点击计数和旧版本按键迁移都限定在各自的命名空间
+	staging.IncrementClick("same")
+	waitPending(t, staging, "same", 1)
+
+	// 实际写入Redis的键都在各自的前缀下
+	mr := sharedTestRedis(t)
+	for _, key := range []string{"url:same", "shared", "notfound:miss", "clicks"} {
+		if !mr.Exists(base + ":staging:" + key) {
+			t.Errorf("key %q not stored under the staging prefix", key)
+		}
+		if mr.Exists(key) {
+			t.Errorf("key %q stored without a prefix", key)
+		}
+	}
+	if got := mr.HGet(base+":staging:clicks", "same"); got != "1" {
+		t.Fatalf("staging click hash field = %q, want 1", got)
+	}
+	if got := prod.Flush(); len(got) != 0 {
+		t.Fatalf("prod flushed staging's clicks: %v", got)
+	}
+	if err := prod.redisClient.Set(prod.ctx, prod.key("clicks:legacy"), 2, time.Hour).Err(); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := staging.scanLegacyClickKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 0 {
+		t.Fatalf("staging scanned prod's legacy keys: %v", keys)
+	}
+	if got := staging.Flush(); got["same"] != 1 || len(got) != 1 {
+		t.Fatalf("staging Flush = %v", got)
+	}
+}
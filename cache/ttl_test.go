@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEntryTypeTTLDefaults(t *testing.T) {
+	m := NewCacheManager("", "", 0, 60, 1000, "")
+	if got := m.TTL(EntryURL); got != time.Hour {
+		t.Errorf("TTL(EntryURL) = %v, want the configured expiry (minutes)", got)
+	}
+	if got := m.TTL(EntryNotFound); got != defaultNotFoundTTL {
+		t.Errorf("TTL(EntryNotFound) = %v, want %v", got, defaultNotFoundTTL)
+	}
+	if got := m.TTL(EntryUnavailable); got != defaultNotFoundTTL {
+		t.Errorf("TTL(EntryUnavailable) = %v, want %v", got, defaultNotFoundTTL)
+	}
+	// 未设置的类型和非正数的覆盖都回退到默认过期时间
+	m.SetTTL(EntryNotFound, 0)
+	if got := m.TTL(EntryNotFound); got != time.Hour {
+		t.Errorf("TTL after SetTTL(0) = %v, want %v", got, time.Hour)
+	}
+	if got := m.TTL(EntryType("other")); got != time.Hour {
+		t.Errorf("TTL(other) = %v, want %v", got, time.Hour)
+	}
+}
+
+func TestEntryTypesExpireIndependently(t *testing.T) {
+	m := NewCacheManager("", "", 0, 60, 1000, "")
+	m.SetTTL(EntryURL, 150*time.Millisecond)
+	m.SetTTL(EntryNotFound, 30*time.Millisecond)
+	m.SetTTL(EntryUnavailable, 80*time.Millisecond)
+
+	m.SetURL("live", &CachedURL{ShortCode: "live", IsActive: true})
+	m.SetNotFound("missing")
+	m.SetUnavailable("off")
+	if err := m.Set("generic", "v", 110*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(at string, url, notFound, unavailable, generic bool) {
+		t.Helper()
+		_, gotURL := m.GetURL("live")
+		var v string
+		got := [4]bool{gotURL, m.IsNotFound("missing"), m.IsUnavailable("off"), m.Get("generic", &v)}
+		if want := [4]bool{url, notFound, unavailable, generic}; got != want {
+			t.Fatalf("%s: url/notfound/unavailable/generic = %v, want %v", at, got, want)
+		}
+	}
+
+	check("start", true, true, true, true)
+	time.Sleep(50 * time.Millisecond)
+	check("50ms", true, false, true, true)
+	time.Sleep(45 * time.Millisecond)
+	check("95ms", true, false, false, true)
+	time.Sleep(30 * time.Millisecond)
+	check("125ms", true, false, false, false)
+	time.Sleep(45 * time.Millisecond)
+	check("170ms", false, false, false, false)
+}
@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// admitUniqueIPScript 原子地检查并记录访客IP：已记录的IP直接放行，未达到上限时记录并放行
+// 集合的过期时间从第一个访客开始计算，到期后重新计数
+var admitUniqueIPScript = redis.NewScript(`
+if redis.call('SISMEMBER', KEYS[1], ARGV[1]) == 1 then
+	return 1
+end
+if redis.call('SCARD', KEYS[1]) >= tonumber(ARGV[2]) then
+	return 0
+end
+redis.call('SADD', KEYS[1], ARGV[1])
+if redis.call('PTTL', KEYS[1]) < 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[3])
+end
+return 1
+`)
+
+// uniqueIPSet 内存中某个短链接已记录的访客IP
+type uniqueIPSet struct {
+	mutex sync.Mutex
+	ips   map[string]struct{}
+}
+
+func (c *Manager) uniqueIPKey(shortCode string) string {
+	return c.key("uniqueips:" + shortCode)
+}
+
+// AdmitUniqueIP 检查IP能否访问限制了独立访客数的短链接
+// 已记录过的IP始终放行；新IP在已记录数未达到 limit 时记录并放行，否则拒绝
+// Redis可用时只使用Redis，保证多个实例/进程共享同一份访客记录
+func (c *Manager) AdmitUniqueIP(shortCode, ip string, limit int64, window time.Duration) bool {
+	key := c.uniqueIPKey(shortCode)
+
+	if c.useRedis {
+		admitted, err := admitUniqueIPScript.Run(c.ctx, c.redisClient, []string{key}, ip, limit, window.Milliseconds()).Int()
+		if err == nil {
+			return admitted == 1
+		}
+		log.Printf("Redis记录访客IP失败，使用内存: %v", err)
+	}
+
+	// 内存记录，Add 保证并发时只创建一个集合
+	c.memCache.Add(key, &uniqueIPSet{ips: make(map[string]struct{})}, window)
+	raw, found := c.memCache.Get(key)
+	if !found {
+		// 集合在Add和Get之间过期，重新开始一个窗口
+		raw = &uniqueIPSet{ips: make(map[string]struct{})}
+		c.memCache.Set(key, raw, window)
+	}
+	set, ok := raw.(*uniqueIPSet)
+	if !ok {
+		return true
+	}
+
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+	if _, seen := set.ips[ip]; seen {
+		return true
+	}
+	if int64(len(set.ips)) >= limit {
+		return false
+	}
+	set.ips[ip] = struct{}{}
+	return true
+}
@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+// checkUniqueIPCap 上限为2：前两个新IP放行，第三个拒绝，已记录的IP继续放行
+func checkUniqueIPCap(t *testing.T, m *Manager) {
+	t.Helper()
+	admit := func(ip string) bool { return m.AdmitUniqueIP("private", ip, 2, time.Hour) }
+	steps := []struct {
+		ip   string
+		want bool
+	}{
+		{"10.0.0.1", true},
+		{"10.0.0.1", true},
+		{"10.0.0.2", true},
+		{"10.0.0.3", false},
+		{"10.0.0.1", true},
+		{"10.0.0.2", true},
+		{"10.0.0.4", false},
+	}
+	for i, step := range steps {
+		if got := admit(step.ip); got != step.want {
+			t.Fatalf("step %d: AdmitUniqueIP(%s) = %v, want %v", i, step.ip, got, step.want)
+		}
+	}
+	// 各短链接分别计数
+	if !m.AdmitUniqueIP("other", "10.0.0.3", 2, time.Hour) {
+		t.Fatal("visitor rejected by another link's cap")
+	}
+}
+
+func TestAdmitUniqueIPMemory(t *testing.T) {
+	checkUniqueIPCap(t, NewCacheManager("", "", 0, 60, 1000, ""))
+}
+
+func TestAdmitUniqueIPRedis(t *testing.T) {
+	checkUniqueIPCap(t, newRedisTestManager(t, testPrefix(t)))
+}
+
+func TestAdmitUniqueIPWindowExpires(t *testing.T) {
+	m := NewCacheManager("", "", 0, 60, 1000, "")
+	const window = 50 * time.Millisecond
+	if !m.AdmitUniqueIP("short", "10.0.0.1", 1, window) {
+		t.Fatal("first visitor rejected")
+	}
+	if m.AdmitUniqueIP("short", "10.0.0.2", 1, window) {
+		t.Fatal("second visitor admitted past the cap")
+	}
+	// 窗口到期后重新计数
+	time.Sleep(2 * window)
+	if !m.AdmitUniqueIP("short", "10.0.0.2", 1, window) {
+		t.Fatal("visitor rejected after the window expired")
+	}
+}
@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/justseemore/surl/models"
+)
+
+// CachedURL 跳转所需的精简短链接记录
+// 只保存解析跳转和可用性检查用到的字段，标题以外的元数据（描述、时间戳等）需要从数据库读取
+type CachedURL struct {
+	ID           uint       `json:"id"`
+	ShortCode    string     `json:"short_code"`
+	OriginalURL  string     `json:"original_url"`
+	Title        string     `json:"title"` // 拦截提示页展示用
+	IsActive     bool       `json:"is_active"`
+	StartsAt     *time.Time `json:"starts_at"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	MaxClicks    *int64     `json:"max_clicks"`
+	MaxUniqueIPs *int64     `json:"max_unique_ips"`
+	RedirectType int        `json:"redirect_type"`
+	CreatedBy    string     `json:"created_by"` // 判断是否统计创建者本人的点击
+
+	RefererRules  models.RefererRules  `json:"referer_rules,omitempty"`
+	ScheduleRules models.ScheduleRules `json:"schedule_rules,omitempty"`
+
+	GeoRules models.GeoRules `json:"geo_rules"`
+
+	// 写入缓存时已同步到数据库的点击数，只有设置了最大点击次数的链接会在同步点击计数后更新
+	ClickCount int64 `json:"click_count"`
+}
+
+// NewCachedURL 从完整记录生成精简记录
+func NewCachedURL(url *models.URL) *CachedURL {
+	return &CachedURL{
+		ID:           url.ID,
+		ShortCode:    url.ShortCode,
+		OriginalURL:  url.OriginalURL,
+		Title:        url.Title,
+		IsActive:     url.IsActive,
+		StartsAt:     url.StartsAt,
+		ExpiresAt:    url.ExpiresAt,
+		MaxClicks:    url.MaxClicks,
+		MaxUniqueIPs: url.MaxUniqueIPs,
+		RedirectType: url.RedirectType,
+		CreatedBy:    url.CreatedBy,
+		RefererRules: url.RefererRules,
+
+		ScheduleRules: url.ScheduleRules,
+
+		GeoRules: url.GeoRules,
+
+		ClickCount: url.ClickCount,
+	}
+}
+
+// URL 转换为只填充了跳转相关字段的 models.URL
+func (u *CachedURL) URL() *models.URL {
+	return &models.URL{
+		ID:           u.ID,
+		ShortCode:    u.ShortCode,
+		OriginalURL:  u.OriginalURL,
+		Title:        u.Title,
+		IsActive:     u.IsActive,
+		StartsAt:     u.StartsAt,
+		ExpiresAt:    u.ExpiresAt,
+		MaxClicks:    u.MaxClicks,
+		MaxUniqueIPs: u.MaxUniqueIPs,
+		RedirectType: u.RedirectType,
+		CreatedBy:    u.CreatedBy,
+		RefererRules: u.RefererRules,
+
+		ScheduleRules: u.ScheduleRules,
+
+		GeoRules: u.GeoRules,
+
+		ClickCount: u.ClickCount,
+	}
+}
@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/justseemore/surl/models"
+)
+
+// redirectURL 填充了所有跳转相关字段的完整记录
+func redirectURL() *models.URL {
+	startsAt := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	expiresAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	maxClicks, maxIPs := int64(100), int64(10)
+	return &models.URL{
+		ID:            42,
+		ShortCode:     "abc123",
+		OriginalURL:   "https://example.com/landing",
+		Title:         "Landing",
+		Description:   "not cached",
+		IsActive:      true,
+		StartsAt:      &startsAt,
+		ExpiresAt:     &expiresAt,
+		MaxClicks:     &maxClicks,
+		MaxUniqueIPs:  &maxIPs,
+		RedirectType:  307,
+		CreatedBy:     "alice",
+		ClickCount:    7,
+		RefererRules:  models.RefererRules{{Match: "t.co", Target: "https://example.com/social"}, {Match: "evil.example", Block: true}},
+		ScheduleRules: models.ScheduleRules{{Days: []time.Weekday{time.Monday}, Start: "09:00", End: "17:00", Target: "https://example.com/office"}},
+		GeoRules:      models.GeoRules{Allow: []string{"CN", "JP"}},
+		Tags:          models.Tags{"promo"},
+	}
+}
+
+// redirectFields 只保留跳转相关字段，用于与缓存还原的记录比较
+func redirectFields(u *models.URL) *models.URL {
+	return &models.URL{
+		ID: u.ID, ShortCode: u.ShortCode, OriginalURL: u.OriginalURL, Title: u.Title,
+		IsActive: u.IsActive, StartsAt: u.StartsAt, ExpiresAt: u.ExpiresAt,
+		MaxClicks: u.MaxClicks, MaxUniqueIPs: u.MaxUniqueIPs, RedirectType: u.RedirectType,
+		CreatedBy: u.CreatedBy, ClickCount: u.ClickCount,
+		RefererRules: u.RefererRules, ScheduleRules: u.ScheduleRules, GeoRules: u.GeoRules,
+	}
+}
+
+func TestCachedURLRoundTrip(t *testing.T) {
+	full := redirectURL()
+	want := redirectFields(full)
+
+	if got := NewCachedURL(full).URL(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("URL() = %+v\nwant %+v", got, want)
+	}
+
+	// Redis 中以JSON保存
+	data, err := json.Marshal(NewCachedURL(full))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded CachedURL
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if got := decoded.URL(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("JSON round trip = %+v\nwant %+v", got, want)
+	}
+}
+
+func TestSlimCacheRoundTrip(t *testing.T) {
+	m := NewCacheManager("", "", 0, 60, 1000, "")
+	full := redirectURL()
+	m.SetURL(full.ShortCode, NewCachedURL(full))
+	cached, ok := m.GetURL(full.ShortCode)
+	if !ok {
+		t.Fatal("entry not cached")
+	}
+	if got := cached.URL(); !reflect.DeepEqual(got, redirectFields(full)) || got.Description != "" || got.Tags != nil {
+		t.Fatalf("cached URL = %+v", got)
+	}
+}
+
+func TestSlimCacheRoundTripThroughRedis(t *testing.T) {
+	prefix := testPrefix(t)
+	a := newRedisTestManager(t, prefix)
+	b := newRedisTestManager(t, prefix)
+	full := redirectURL()
+	a.SetURL(full.ShortCode, NewCachedURL(full))
+
+	cached, ok := b.GetURL(full.ShortCode)
+	if !ok {
+		t.Fatal("entry not visible through Redis")
+	}
+	if got := cached.URL(); !reflect.DeepEqual(got, redirectFields(full)) {
+		t.Fatalf("cached URL = %+v", got)
+	}
+}
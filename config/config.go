@@ -1,12 +1,19 @@
 package config
 
 import (
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"log"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/joho/godotenv"
+	"github.com/justseemore/surl/models"
 )
 
 type Account struct {
@@ -25,9 +32,127 @@ type Config struct {
 	CacheExpiry   int // 分钟
 	CacheMaxItems int // 新增：内存缓存最大项目数
 	JWTSecret     string
-	Accounts      []Account
+	Accounts      []Account // 仅用于首次启动时导入数据库
 	MaxURLLength  int
 	DefaultExpiry int
+	RedisPrefix   string // Redis键命名空间前缀，多个实例共享同一Redis时使用
+
+	// Redis部署模式
+	RedisMode           string   // single（默认）、sentinel 或 cluster
+	RedisAddrs          []string // sentinel/cluster 模式的节点地址列表（哨兵地址或集群节点），为空时使用 RedisAddr
+	RedisSentinelMaster string   // sentinel 模式下的主节点名称
+
+	// 创建接口 Idempotency-Key 结果的保留时间（小时）
+	IdempotencyTTL int
+
+	// 设置了独立访客上限的链接记录访客IP的时长（小时），过期后重新计数
+	UniqueIPWindow int
+
+	// 点击计数写入数据库失败时放回缓冲区，下次同步时重试；关闭时丢弃
+	ClickSyncRetry bool
+
+	// 短链接请求自带的查询参数：strip（默认）丢弃，keep 追加到目标地址
+	QueryStringMode string
+
+	// 首页跳转地址，为空时渲染首页模板
+	RootRedirectURL string
+
+	// 启用Fiber Prefork（多进程监听同一端口），要求Redis可用以汇总各进程的点击计数，建议配合网络数据库使用
+	Prefork bool
+
+	// 点击明细（设备/浏览器等）的抽样率，0-1，1表示记录每次点击；点击计数不受影响
+	ClickSampleRate float64
+
+	// 点击统计开关：on（默认）或 off，off 时跳转不记录任何点击数据
+	ClickCounting string
+
+	// 对所有App内置浏览器（WebView）显示"在浏览器中打开"提示页，微信和QQ始终显示
+	WebViewInterstitial bool
+
+	// 数据库驱动：sqlite（默认，使用 DBPath）、postgres 或 mysql（使用 DBDSN）
+	DBDriver string
+	DBDSN    string
+
+	// TLS配置
+	TLSCertFile string // TLS证书文件路径，为空时使用HTTP
+	TLSKeyFile  string // TLS私钥文件路径
+	HTTPPort    string // 启用TLS时用于HTTP→HTTPS跳转的端口
+
+	// GeoIP配置
+	GeoIPDBPath string // MaxMind国家数据库路径，为空时关闭GeoIP统计
+
+	// 请求体大小限制（字节）
+	BodyLimit      int // 应用级上限，只用于批量创建（导入）
+	SmallBodyLimit int // 其他所有路由的上限
+
+	// 限流配置
+	RateLimitRPM int // 每个IP每分钟允许的请求数（固定窗口计数），0表示不限流（默认）
+
+	// 反向代理配置
+	ProxyHeader    string   // 读取客户端IP的请求头（如 X-Forwarded-For），为空时使用连接地址
+	TrustedProxies []string // 只信任来自这些代理（IP或CIDR）的 ProxyHeader，为空时信任所有来源
+
+	// 点击统计配置
+	ExcludeCreatorClicks bool // 不统计创建者本人（已登录）的点击
+
+	// URL校验配置
+	AllowedSchemes    []string // 允许的目标URL协议，默认 http,https，可追加 mailto,tel
+	BlockPrivateHosts bool     // 拒绝指向本地、内网和链路本地地址的目标URL
+	AllowDuplicates   bool     // 允许同一目标URL创建多个短链接
+
+	// 账户配置
+	MinPasswordLength int // 修改密码时的最小长度
+
+	// 回收站配置
+	TrashRetentionDays int // 软删除记录的保留天数，超过后永久删除，0表示不自动清理
+
+	// 标题/描述长度限制（字符数）
+	MaxTitleLength       int
+	MaxDescriptionLength int
+
+	// 标签限制
+	MaxTagsPerLink int // 每个链接最多的标签数
+	MaxTagLength   int // 单个标签的最大字符数
+
+	// 多域名配置
+	AllowedDomains []string // 创建时可选择的短链接域名，CUSTOM_DOMAIN 始终允许
+
+	// 跳转配置
+	DefaultRedirectCode int // 新建链接的默认跳转状态码
+
+	// 列表配置
+	EmptySearchReturnsAll bool // 搜索词为空时返回全部链接，false 时返回空列表直到输入搜索词
+
+	// 关闭配置
+	ShutdownTimeout int // 优雅关闭等待进行中请求的秒数
+
+	// 负缓存配置
+	NegativeCacheTTL int // 不存在或已确认不可用的短代码的缓存秒数，0表示关闭
+
+	// 自定义短代码规则
+	CustomCodeCharset   string // 允许的字符
+	CustomCodeMinLength int
+	CustomCodeMaxLength int
+
+	// 点击计数缓冲
+	MaxPendingClickKeys int // 内存中待同步的短代码数上限，超过后提前同步，0表示不限制
+
+	// 默认标题
+	AutoTitle bool // 未填写标题时根据目标URL的域名和最后一段路径生成标题
+
+	// 缓存诊断
+	LogCacheEvictions bool // URL缓存因容量不足淘汰条目时输出日志
+
+	// 时区
+	Timezone string         // IANA时区名称（如 Asia/Shanghai），为空时使用服务器本地时区
+	location *time.Location // 由 Timezone 加载，无效时为nil并由 Validate 报错
+
+	// Webhook通知
+	WebhookURL    string // 链接创建、修改、删除、恢复、过期时接收JSON通知的地址，为空时关闭
+	WebhookSecret string // 请求体 HMAC-SHA256 签名的共享密钥
+
+	// 链接事件（与webhook相同）的 JSON Lines 日志：文件路径或 stdout，为空时关闭
+	EventLog string
 }
 
 func Load() *Config {
@@ -43,6 +168,38 @@ func Load() *Config {
 	maxURLLength, _ := strconv.Atoi(getEnv("MAX_URL_LENGTH", "2048"))
 	defaultExpiry, _ := strconv.Atoi(getEnv("DEFAULT_EXPIRY", "8760")) // 1年
 
+	bodyLimit, _ := strconv.Atoi(getEnv("BODY_LIMIT", "4194304"))          // 4MB
+	smallBodyLimit, _ := strconv.Atoi(getEnv("SMALL_BODY_LIMIT", "65536")) // 64KB
+	rateLimitRPM, _ := strconv.Atoi(getEnv("RATE_LIMIT_RPM", "0"))
+	minPasswordLength, _ := strconv.Atoi(getEnv("MIN_PASSWORD_LENGTH", "8"))
+	trashRetentionDays, _ := strconv.Atoi(getEnv("TRASH_RETENTION_DAYS", "30"))
+	maxTitleLength, _ := strconv.Atoi(getEnv("MAX_TITLE_LENGTH", "200"))
+	maxDescriptionLength, _ := strconv.Atoi(getEnv("MAX_DESCRIPTION_LENGTH", "1000"))
+	maxTagsPerLink, _ := strconv.Atoi(getEnv("MAX_TAGS_PER_LINK", "10"))
+	maxTagLength, _ := strconv.Atoi(getEnv("MAX_TAG_LENGTH", "32"))
+	defaultRedirectCode, _ := strconv.Atoi(getEnv("DEFAULT_REDIRECT_CODE", "302"))
+	shutdownTimeout, _ := strconv.Atoi(getEnv("SHUTDOWN_TIMEOUT", "10"))
+	negativeCacheTTL, _ := strconv.Atoi(getEnv("NEGATIVE_CACHE_TTL", "30"))
+	customCodeMinLength, _ := strconv.Atoi(getEnv("CUSTOM_CODE_MIN_LENGTH", "3"))
+	customCodeMaxLength, _ := strconv.Atoi(getEnv("CUSTOM_CODE_MAX_LENGTH", "32"))
+	maxPendingClickKeys, _ := strconv.Atoi(getEnv("MAX_PENDING_CLICK_KEYS", "10000"))
+
+	idempotencyTTL, _ := strconv.Atoi(getEnv("IDEMPOTENCY_TTL", "24"))
+	uniqueIPWindow, _ := strconv.Atoi(getEnv("UNIQUE_IP_WINDOW", "720"))
+	clickSampleRate, err := strconv.ParseFloat(getEnv("CLICK_SAMPLE_RATE", "1"), 64)
+	if err != nil {
+		clickSampleRate = -1 // 由 Validate 报错
+	}
+
+	// time.LoadLocation("") 返回UTC而不是本地时区，未设置时直接使用 time.Local
+	timezone := getEnv("TIMEZONE", "")
+	location := time.Local
+	if timezone != "" {
+		if location, err = time.LoadLocation(timezone); err != nil {
+			location = nil
+		}
+	}
+
 	// 解析账户配置
 	accounts := parseAccounts()
 
@@ -59,9 +216,215 @@ func Load() *Config {
 		Accounts:      accounts,
 		MaxURLLength:  maxURLLength,
 		DefaultExpiry: defaultExpiry,
+		RedisPrefix:   getEnv("REDIS_PREFIX", ""),
+
+		RedisMode:           strings.ToLower(getEnv("REDIS_MODE", RedisModeSingle)),
+		RedisAddrs:          parseAddrs(getEnv("REDIS_ADDRS", "")),
+		RedisSentinelMaster: getEnv("REDIS_SENTINEL_MASTER", ""),
+
+		IdempotencyTTL: idempotencyTTL,
+
+		UniqueIPWindow: uniqueIPWindow,
+
+		ClickSyncRetry: getEnv("CLICK_SYNC_RETRY", "true") == "true",
+
+		QueryStringMode: strings.ToLower(getEnv("QUERY_STRING_MODE", QueryStringStrip)),
+
+		RootRedirectURL: getEnv("ROOT_REDIRECT_URL", ""),
+
+		Prefork: getEnv("PREFORK", "false") == "true",
+
+		ClickSampleRate: clickSampleRate,
+
+		ClickCounting: strings.ToLower(getEnv("CLICK_COUNTING", ClickCountingOn)),
+
+		WebViewInterstitial: getEnv("WEBVIEW_INTERSTITIAL", "false") == "true",
+
+		DBDriver: strings.ToLower(getEnv("DB_DRIVER", models.DriverSQLite)),
+		DBDSN:    getEnv("DB_DSN", ""),
+
+		TLSCertFile: getEnv("TLS_CERT_FILE", ""),
+		TLSKeyFile:  getEnv("TLS_KEY_FILE", ""),
+		HTTPPort:    getEnv("HTTP_PORT", "80"),
+
+		GeoIPDBPath: getEnv("GEOIP_DB_PATH", ""),
+
+		BodyLimit:      bodyLimit,
+		SmallBodyLimit: smallBodyLimit,
+
+		RateLimitRPM: rateLimitRPM,
+
+		ProxyHeader:    getEnv("PROXY_HEADER", ""),
+		TrustedProxies: parseAddrs(getEnv("TRUSTED_PROXIES", "")),
+
+		ExcludeCreatorClicks: getEnv("EXCLUDE_CREATOR_CLICKS", "false") == "true",
+
+		AllowedSchemes:    parseList(getEnv("ALLOWED_SCHEMES", "http,https")),
+		BlockPrivateHosts: getEnv("BLOCK_PRIVATE_HOSTS", "true") == "true",
+		AllowDuplicates:   getEnv("ALLOW_DUPLICATE_URLS", "false") == "true",
+
+		MinPasswordLength: minPasswordLength,
+
+		TrashRetentionDays: trashRetentionDays,
+
+		MaxTitleLength:       maxTitleLength,
+		MaxDescriptionLength: maxDescriptionLength,
+
+		MaxTagsPerLink: maxTagsPerLink,
+		MaxTagLength:   maxTagLength,
+
+		AllowedDomains: parseList(getEnv("ALLOWED_DOMAINS", "")),
+
+		DefaultRedirectCode: defaultRedirectCode,
+
+		EmptySearchReturnsAll: getEnv("EMPTY_SEARCH_RETURNS_ALL", "true") == "true",
+
+		ShutdownTimeout: shutdownTimeout,
+
+		NegativeCacheTTL: negativeCacheTTL,
+
+		CustomCodeCharset:   getEnv("CUSTOM_CODE_CHARSET", defaultCustomCodeCharset),
+		CustomCodeMinLength: customCodeMinLength,
+		CustomCodeMaxLength: customCodeMaxLength,
+
+		MaxPendingClickKeys: maxPendingClickKeys,
+
+		AutoTitle: getEnv("AUTO_TITLE", "false") == "true",
+
+		LogCacheEvictions: getEnv("LOG_CACHE_EVICTIONS", "false") == "true",
+
+		Timezone: timezone,
+		location: location,
+
+		WebhookURL:    getEnv("WEBHOOK_URL", ""),
+		WebhookSecret: getEnv("WEBHOOK_SECRET", ""),
+
+		EventLog: getEnv("EVENT_LOG", ""),
 	}
 }
 
+// Redis部署模式
+const (
+	RedisModeSingle   = "single"
+	RedisModeSentinel = "sentinel"
+	RedisModeCluster  = "cluster"
+)
+
+// 点击统计开关
+const (
+	ClickCountingOn  = "on"
+	ClickCountingOff = "off"
+)
+
+// 短链接请求查询参数的处理方式
+const (
+	QueryStringStrip = "strip"
+	QueryStringKeep  = "keep"
+)
+
+// minShutdownTimeout 优雅关闭的最短等待时间（秒）
+const minShutdownTimeout = 1
+
+// defaultCustomCodeCharset 自定义短代码默认允许的字符
+const defaultCustomCodeCharset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_"
+
+// routingUnsafeChars 会破坏路由匹配的字符，不能出现在短代码字符集中
+const routingUnsafeChars = "/?#%\\"
+
+// Location 返回配置的时区，用于按时间段跳转、按日期筛选和展示等与本地时间相关的计算（存储始终使用UTC）
+func (c *Config) Location() *time.Location {
+	if c.location == nil {
+		return time.Local
+	}
+	return c.location
+}
+
+// ClickCountingEnabled 是否统计点击（点击计数、设备明细和国家统计）
+func (c *Config) ClickCountingEnabled() bool {
+	return c.ClickCounting != ClickCountingOff
+}
+
+// DatabaseSource 返回 InitDatabase 使用的数据源：SQLite 为文件路径，其他驱动为 DSN
+func (c *Config) DatabaseSource() string {
+	if c.DBDriver == models.DriverSQLite {
+		return c.DBPath
+	}
+	return c.DBDSN
+}
+
+// TLSEnabled 是否启用TLS
+func (c *Config) TLSEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+// Validate 校验配置的有效性
+func (c *Config) Validate() error {
+	// TLS证书和私钥必须同时配置
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return errors.New("TLS_CERT_FILE 和 TLS_KEY_FILE 必须同时配置")
+	}
+	if c.TLSEnabled() {
+		if _, err := tls.LoadX509KeyPair(c.TLSCertFile, c.TLSKeyFile); err != nil {
+			return fmt.Errorf("加载TLS证书失败: %v", err)
+		}
+	}
+	switch c.DefaultRedirectCode {
+	case 301, 302, 307, 308:
+	default:
+		return fmt.Errorf("DEFAULT_REDIRECT_CODE 只能是 301、302、307 或 308，当前为 %d", c.DefaultRedirectCode)
+	}
+	if strings.ContainsAny(c.CustomCodeCharset, routingUnsafeChars) || strings.IndexFunc(c.CustomCodeCharset, unicode.IsSpace) >= 0 {
+		return errors.New("CUSTOM_CODE_CHARSET 不能包含 / ? # % \\ 或空白字符")
+	}
+	if c.CustomCodeMinLength < 1 || c.CustomCodeMaxLength < c.CustomCodeMinLength {
+		return fmt.Errorf("自定义短代码长度范围无效: %d-%d", c.CustomCodeMinLength, c.CustomCodeMaxLength)
+	}
+	if c.WebhookURL != "" && c.WebhookSecret == "" {
+		return errors.New("配置 WEBHOOK_URL 时必须同时配置 WEBHOOK_SECRET")
+	}
+	if c.location == nil {
+		return fmt.Errorf("无法加载时区 TIMEZONE=%s", c.Timezone)
+	}
+	switch c.DBDriver {
+	case models.DriverSQLite, models.DriverPostgres, models.DriverMySQL:
+	default:
+		return fmt.Errorf("DB_DRIVER 只能是 sqlite、postgres 或 mysql，当前为 %s", c.DBDriver)
+	}
+	switch c.RedisMode {
+	case RedisModeSingle, RedisModeCluster:
+	case RedisModeSentinel:
+		if c.RedisSentinelMaster == "" {
+			return errors.New("REDIS_MODE=sentinel 时必须配置 REDIS_SENTINEL_MASTER")
+		}
+	default:
+		return fmt.Errorf("REDIS_MODE 只能是 single、sentinel 或 cluster，当前为 %s", c.RedisMode)
+	}
+	if c.RootRedirectURL != "" {
+		if u, err := url.Parse(c.RootRedirectURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("ROOT_REDIRECT_URL 必须是完整的 http(s) 地址，当前为 %s", c.RootRedirectURL)
+		}
+	}
+	if c.ClickSampleRate < 0 || c.ClickSampleRate > 1 {
+		return errors.New("CLICK_SAMPLE_RATE 必须是 0 到 1 之间的数字")
+	}
+	if c.UniqueIPWindow <= 0 {
+		return fmt.Errorf("UNIQUE_IP_WINDOW 必须大于0，当前为 %d", c.UniqueIPWindow)
+	}
+	if c.SmallBodyLimit > c.BodyLimit {
+		return fmt.Errorf("SMALL_BODY_LIMIT (%d) 不能大于 BODY_LIMIT (%d)", c.SmallBodyLimit, c.BodyLimit)
+	}
+	if c.ClickCounting != ClickCountingOn && c.ClickCounting != ClickCountingOff {
+		return fmt.Errorf("CLICK_COUNTING 只能是 on 或 off，当前为 %s", c.ClickCounting)
+	}
+	if c.QueryStringMode != QueryStringStrip && c.QueryStringMode != QueryStringKeep {
+		return fmt.Errorf("QUERY_STRING_MODE 只能是 strip 或 keep，当前为 %s", c.QueryStringMode)
+	}
+	if c.ShutdownTimeout < minShutdownTimeout {
+		return fmt.Errorf("SHUTDOWN_TIMEOUT 不能小于 %d 秒，当前为 %d", minShutdownTimeout, c.ShutdownTimeout)
+	}
+	return nil
+}
+
 // parseAccounts 解析账户配置
 // 格式：ACCOUNTS=admin:password123:admin,user1:pass456:user
 func parseAccounts() []Account {
@@ -92,6 +455,29 @@ func parseAccounts() []Account {
 	return accounts
 }
 
+// parseList 解析逗号分隔的列表（去除空白并转为小写）
+func parseList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseAddrs 解析逗号分隔的地址列表（去除空白，保留大小写）
+func parseAddrs(value string) []string {
+	var addrs []string
+	for _, addr := range strings.Split(value, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -0,0 +1,243 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestConfig 加载默认配置，测试在此基础上修改单个字段
+func newTestConfig(t *testing.T) *Config {
+	t.Helper()
+	cfg := Load()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("default config invalid: %v", err)
+	}
+	return cfg
+}
+
+// writeKeyPair 生成自签名证书和私钥，返回两个文件的路径
+func writeKeyPair(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestValidateTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeKeyPair(t, dir, "a")
+	otherCert, _ := writeKeyPair(t, dir, "b")
+
+	cases := []struct {
+		name    string
+		cert    string
+		key     string
+		wantErr string
+		wantTLS bool
+	}{
+		{name: "plain HTTP by default"},
+		{name: "valid pair", cert: certFile, key: keyFile, wantTLS: true},
+		{name: "cert only", cert: certFile, wantErr: "必须同时配置"},
+		{name: "key only", key: keyFile, wantErr: "必须同时配置"},
+		{name: "missing files", cert: filepath.Join(dir, "nope.crt"), key: filepath.Join(dir, "nope.key"), wantErr: "加载TLS证书失败"},
+		{name: "mismatched pair", cert: otherCert, key: keyFile, wantErr: "加载TLS证书失败"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := newTestConfig(t)
+			cfg.TLSCertFile, cfg.TLSKeyFile = tc.cert, tc.key
+
+			err := cfg.Validate()
+			if tc.wantErr == "" && err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Fatalf("Validate error = %v, want it to mention %q", err, tc.wantErr)
+			}
+			if cfg.TLSEnabled() != tc.wantTLS && tc.wantErr == "" {
+				t.Fatalf("TLSEnabled() = %v, want %v", cfg.TLSEnabled(), tc.wantTLS)
+			}
+		})
+	}
+}
+
+func TestHostValidationEnv(t *testing.T) {
+	cfg := newTestConfig(t)
+	if !cfg.BlockPrivateHosts {
+		t.Error("BlockPrivateHosts defaults to false")
+	}
+	if got := strings.Join(cfg.AllowedSchemes, ","); got != "http,https" {
+		t.Errorf("AllowedSchemes default = %q", got)
+	}
+
+	t.Setenv("BLOCK_PRIVATE_HOSTS", "false")
+	t.Setenv("ALLOWED_SCHEMES", " HTTPS , mailto,, ")
+	cfg = Load()
+	if cfg.BlockPrivateHosts {
+		t.Error("BLOCK_PRIVATE_HOSTS=false ignored")
+	}
+	if got := strings.Join(cfg.AllowedSchemes, ","); got != "https,mailto" {
+		t.Errorf("AllowedSchemes = %q, want https,mailto", got)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	if cfg := newTestConfig(t); cfg.ShutdownTimeout != 10 {
+		t.Errorf("default ShutdownTimeout = %d, want 10", cfg.ShutdownTimeout)
+	}
+
+	cases := map[string]bool{"30": true, "1": true, "0": false, "-5": false, "soon": false}
+	for value, valid := range cases {
+		t.Setenv("SHUTDOWN_TIMEOUT", value)
+		err := Load().Validate()
+		if valid && err != nil {
+			t.Errorf("SHUTDOWN_TIMEOUT=%s rejected: %v", value, err)
+		}
+		if !valid && (err == nil || !strings.Contains(err.Error(), "SHUTDOWN_TIMEOUT")) {
+			t.Errorf("SHUTDOWN_TIMEOUT=%s: err = %v, want a SHUTDOWN_TIMEOUT error", value, err)
+		}
+	}
+}
+
+func TestCustomCodeCharsetValidation(t *testing.T) {
+	cases := map[string]bool{
+		"abcdefghijklmnopqrstuvwxyz0123456789": true,
+		"abc.-_":                               true,
+		"abc/":                                 false,
+		"abc?":                                 false,
+		"abc#":                                 false,
+		"abc%":                                 false,
+		`abc\`:                                 false,
+		"ab c":                                 false,
+		"abc\t":                                false,
+	}
+	for charset, valid := range cases {
+		cfg := newTestConfig(t)
+		cfg.CustomCodeCharset = charset
+		if err := cfg.Validate(); (err == nil) != valid {
+			t.Errorf("CUSTOM_CODE_CHARSET=%q: err = %v, want valid=%v", charset, err, valid)
+		}
+	}
+
+	bounds := []struct {
+		min, max int
+		valid    bool
+	}{{3, 32, true}, {5, 5, true}, {0, 10, false}, {8, 4, false}}
+	for _, b := range bounds {
+		cfg := newTestConfig(t)
+		cfg.CustomCodeMinLength, cfg.CustomCodeMaxLength = b.min, b.max
+		if err := cfg.Validate(); (err == nil) != b.valid {
+			t.Errorf("custom code length %d-%d: err = %v, want valid=%v", b.min, b.max, err, b.valid)
+		}
+	}
+}
+
+func TestRootRedirectURLValidation(t *testing.T) {
+	cases := map[string]bool{
+		"":                           true,
+		"https://www.example.com/":   true,
+		"http://example.com/landing": true,
+		"www.example.com":            false,
+		"/landing":                   false,
+		"javascript:alert(1)":        false,
+		"https://":                   false,
+	}
+	for value, valid := range cases {
+		t.Setenv("ROOT_REDIRECT_URL", value)
+		err := Load().Validate()
+		if valid && err != nil {
+			t.Errorf("ROOT_REDIRECT_URL=%q rejected: %v", value, err)
+		}
+		if !valid && (err == nil || !strings.Contains(err.Error(), "ROOT_REDIRECT_URL")) {
+			t.Errorf("ROOT_REDIRECT_URL=%q: err = %v, want a ROOT_REDIRECT_URL error", value, err)
+		}
+	}
+}
+
+func TestQueryStringMode(t *testing.T) {
+	if cfg := newTestConfig(t); cfg.QueryStringMode != QueryStringStrip {
+		t.Errorf("default QueryStringMode = %q, want %q", cfg.QueryStringMode, QueryStringStrip)
+	}
+	cases := map[string]bool{"strip": true, "keep": true, "KEEP": true, "forward": false}
+	for value, valid := range cases {
+		t.Setenv("QUERY_STRING_MODE", value)
+		err := Load().Validate()
+		if valid && err != nil {
+			t.Errorf("QUERY_STRING_MODE=%s rejected: %v", value, err)
+		}
+		if !valid && (err == nil || !strings.Contains(err.Error(), "QUERY_STRING_MODE")) {
+			t.Errorf("QUERY_STRING_MODE=%s: err = %v, want a QUERY_STRING_MODE error", value, err)
+		}
+	}
+}
+
+func TestTimezone(t *testing.T) {
+	// 未设置时使用服务器本地时区，而不是 time.LoadLocation("") 返回的UTC
+	t.Setenv("TIMEZONE", "")
+	if loc := newTestConfig(t).Location(); loc != time.Local {
+		t.Errorf("unset TIMEZONE: Location() = %v, want time.Local", loc)
+	}
+
+	t.Setenv("TIMEZONE", "Asia/Tokyo")
+	if loc := newTestConfig(t).Location(); loc.String() != "Asia/Tokyo" {
+		t.Errorf("TIMEZONE=Asia/Tokyo: Location() = %v", loc)
+	}
+
+	t.Setenv("TIMEZONE", "Mars/Olympus")
+	if err := Load().Validate(); err == nil || !strings.Contains(err.Error(), "TIMEZONE") {
+		t.Errorf("TIMEZONE=Mars/Olympus: err = %v, want a TIMEZONE error", err)
+	}
+}
+
+func TestDBDriver(t *testing.T) {
+	t.Setenv("DB_PATH", "test.db")
+	t.Setenv("DB_DSN", "host=db user=surl")
+	cases := map[string]string{"": "test.db", "sqlite": "test.db", "postgres": "host=db user=surl", "MySQL": "host=db user=surl"}
+	for driver, source := range cases {
+		t.Setenv("DB_DRIVER", driver)
+		if got := newTestConfig(t).DatabaseSource(); got != source {
+			t.Errorf("DB_DRIVER=%s: DatabaseSource() = %q, want %q", driver, got, source)
+		}
+	}
+
+	t.Setenv("DB_DRIVER", "oracle")
+	if err := Load().Validate(); err == nil || !strings.Contains(err.Error(), "DB_DRIVER") {
+		t.Errorf("DB_DRIVER=oracle: err = %v, want a DB_DRIVER error", err)
+	}
+}
@@ -0,0 +1,172 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 事件类型
+const (
+	LinkCreated  = "link.created"
+	LinkUpdated  = "link.updated"
+	LinkDeleted  = "link.deleted"
+	LinkRestored = "link.restored"
+	LinkExpired  = "link.expired"
+)
+
+// SignatureHeader 请求体的 HMAC-SHA256 签名，格式为 sha256=<十六进制>
+const SignatureHeader = "X-Surl-Signature"
+
+// EventHeader 事件类型
+const EventHeader = "X-Surl-Event"
+
+const (
+	queueSize      = 256              // 待发送事件的缓冲数，满时丢弃新事件
+	maxAttempts    = 4                // 首次发送加重试的总次数
+	initialBackoff = time.Second      // 首次重试前的等待时间，之后每次翻倍
+	requestTimeout = 10 * time.Second // 单次请求超时
+)
+
+// Event 发送给webhook的事件
+type Event struct {
+	Type      string      `json:"event"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// Dispatcher 异步发送webhook通知，发送延迟和失败不会影响请求处理
+// nil 表示未配置webhook，所有方法均可安全调用
+type Dispatcher struct {
+	url    string
+	secret []byte
+	client *http.Client
+	queue  chan Event
+	done   chan struct{}
+
+	mutex  sync.RWMutex // 保护 closed，保证 Close 之后不会再向已关闭的队列发送
+	closed bool
+}
+
+// NewDispatcher 创建并启动webhook发送器，url为空时返回nil（功能关闭）
+func NewDispatcher(url, secret string) *Dispatcher {
+	if url == "" {
+		return nil
+	}
+
+	d := &Dispatcher{
+		url:    url,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: requestTimeout},
+		queue:  make(chan Event, queueSize),
+		done:   make(chan struct{}),
+	}
+	go d.run()
+	return d
+}
+
+// Emit 将事件加入发送队列，不阻塞；队列已满或已关闭时丢弃并记录日志
+func (d *Dispatcher) Emit(eventType string, data interface{}) {
+	if d == nil {
+		return
+	}
+
+	event := Event{Type: eventType, Timestamp: time.Now().UTC(), Data: data}
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	if d.closed {
+		log.Printf("Webhook已关闭，丢弃事件: %s", eventType)
+		return
+	}
+	select {
+	case d.queue <- event:
+	default:
+		log.Printf("Webhook队列已满，丢弃事件: %s", eventType)
+	}
+}
+
+// Close 停止接收新事件，等待队列中的事件发送完成或 ctx 结束
+// 关闭后调用 Emit（例如关闭超时后仍在处理的请求）的事件会被丢弃；重复调用只等待发送完成
+func (d *Dispatcher) Close(ctx context.Context) error {
+	if d == nil {
+		return nil
+	}
+
+	d.mutex.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	d.mutex.Unlock()
+	select {
+	case <-d.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *Dispatcher) run() {
+	defer close(d.done)
+	for event := range d.queue {
+		d.deliver(event)
+	}
+}
+
+// deliver 发送事件，失败时按指数退避重试，超过次数后丢弃
+func (d *Dispatcher) deliver(event Event) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Webhook事件序列化失败 [%s]: %v", event.Type, err)
+		return
+	}
+
+	backoff := initialBackoff
+	for attempt := 1; ; attempt++ {
+		err := d.post(event.Type, body)
+		if err == nil {
+			return
+		}
+		if attempt >= maxAttempts {
+			log.Printf("Webhook发送失败，已放弃 [%s]: %v", event.Type, err)
+			return
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (d *Dispatcher) post(eventType string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, d.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, eventType)
+	req.Header.Set(SignatureHeader, "sha256="+Sign(d.secret, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("响应状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign 计算请求体的 HMAC-SHA256 签名（十六进制），接收方用同一密钥校验
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,119 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type delivery struct {
+	header http.Header
+	body   []byte
+}
+
+func newWebhookServer(t *testing.T, status int) (*httptest.Server, <-chan delivery) {
+	t.Helper()
+	received := make(chan delivery, 16)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- delivery{header: r.Header.Clone(), body: body}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, received
+}
+
+func closeWithin(t *testing.T, closer func(context.Context) error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := closer(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func TestDispatcherDeliversSignedEvent(t *testing.T) {
+	srv, received := newWebhookServer(t, http.StatusOK)
+	d := NewDispatcher(srv.URL, "secret")
+
+	d.Emit(LinkCreated, map[string]string{"short_code": "abc"})
+	closeWithin(t, d.Close)
+
+	select {
+	case got := <-received:
+		if want := "sha256=" + Sign([]byte("secret"), got.body); got.header.Get(SignatureHeader) != want {
+			t.Errorf("signature = %q, want %q", got.header.Get(SignatureHeader), want)
+		}
+		if got.header.Get(EventHeader) != LinkCreated {
+			t.Errorf("event header = %q, want %q", got.header.Get(EventHeader), LinkCreated)
+		}
+		var event struct {
+			Type string            `json:"event"`
+			Data map[string]string `json:"data"`
+		}
+		if err := json.Unmarshal(got.body, &event); err != nil {
+			t.Fatal(err)
+		}
+		if event.Type != LinkCreated || event.Data["short_code"] != "abc" {
+			t.Errorf("body = %s", got.body)
+		}
+	default:
+		t.Fatal("event not delivered before Close returned")
+	}
+}
+
+func TestDispatcherEmitAfterClose(t *testing.T) {
+	srv, received := newWebhookServer(t, http.StatusOK)
+	d := NewDispatcher(srv.URL, "secret")
+	closeWithin(t, d.Close)
+
+	// 关闭后发送的事件被丢弃而不是向已关闭的队列写入导致 panic
+	d.Emit(LinkDeleted, nil)
+	closeWithin(t, d.Close)
+
+	select {
+	case got := <-received:
+		t.Fatalf("event delivered after Close: %s", got.body)
+	default:
+	}
+}
+
+func TestDispatcherRetriesFailedDelivery(t *testing.T) {
+	if testing.Short() {
+		t.Skip("retry backoff takes seconds")
+	}
+
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	d := NewDispatcher(srv.URL, "")
+	d.Emit(LinkUpdated, nil)
+	closeWithin(t, d.Close)
+
+	if attempts != 2 {
+		t.Fatalf("attempts = %d, want 2", attempts)
+	}
+}
+
+func TestNilDispatcher(t *testing.T) {
+	var d *Dispatcher
+	d.Emit(LinkCreated, nil)
+	if err := d.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if NewDispatcher("", "secret") != nil {
+		t.Fatal("NewDispatcher without url should return nil")
+	}
+}
@@ -0,0 +1,115 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// EventLogStdout 作为事件日志路径时写入标准输出
+const EventLogStdout = "stdout"
+
+// EventLog 将链接事件按 JSON Lines 格式（每行一个 Event）追加写入文件或标准输出，供外部系统索引
+// 写入在后台进行，不阻塞请求；与访问日志相互独立
+// nil 表示未配置事件日志，所有方法均可安全调用
+type EventLog struct {
+	out   io.Writer
+	file  *os.File // 写入文件时需要在关闭时释放，标准输出时为nil
+	queue chan []byte
+	done  chan struct{}
+
+	mutex     sync.RWMutex // 保护 closed，保证 Close 之后不会再向已关闭的队列发送
+	closed    bool
+	closeFile sync.Once
+}
+
+// NewEventLog 创建并启动事件日志，path 为空时返回nil（功能关闭），为 "stdout" 时写入标准输出
+func NewEventLog(path string) (*EventLog, error) {
+	switch path {
+	case "":
+		return nil, nil
+	case EventLogStdout:
+		return newEventLog(os.Stdout, nil), nil
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return newEventLog(file, file), nil
+}
+
+func newEventLog(out io.Writer, file *os.File) *EventLog {
+	l := &EventLog{
+		out:   out,
+		file:  file,
+		queue: make(chan []byte, queueSize),
+		done:  make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+// Emit 序列化事件并加入写入队列，不阻塞；队列已满或已关闭时丢弃并记录日志
+// 在调用时序列化，之后对 data 的修改不会影响已记录的内容
+func (l *EventLog) Emit(eventType string, data interface{}) {
+	if l == nil {
+		return
+	}
+
+	line, err := json.Marshal(Event{Type: eventType, Timestamp: time.Now().UTC(), Data: data})
+	if err != nil {
+		log.Printf("事件日志序列化失败 [%s]: %v", eventType, err)
+		return
+	}
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	if l.closed {
+		log.Printf("事件日志已关闭，丢弃事件: %s", eventType)
+		return
+	}
+	select {
+	case l.queue <- append(line, '\n'):
+	default:
+		log.Printf("事件日志队列已满，丢弃事件: %s", eventType)
+	}
+}
+
+// Close 停止接收新事件，等待队列中的事件写入完成或 ctx 结束，写入完成后关闭文件
+// 关闭后调用 Emit 的事件会被丢弃；重复调用只等待写入完成，文件只关闭一次
+func (l *EventLog) Close(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mutex.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.queue)
+	}
+	l.mutex.Unlock()
+	select {
+	case <-l.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	var err error
+	if l.file != nil {
+		l.closeFile.Do(func() { err = l.file.Close() })
+	}
+	return err
+}
+
+func (l *EventLog) run() {
+	defer close(l.done)
+	for line := range l.queue {
+		if _, err := l.out.Write(line); err != nil {
+			log.Printf("写入事件日志失败: %v", err)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readEvents(t *testing.T, path string) []Event {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestEventLogAppendsJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	l, err := NewEventLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l.Emit(LinkCreated, map[string]string{"short_code": "abc"})
+	l.Emit(LinkDeleted, map[string]string{"short_code": "abc"})
+	closeWithin(t, l.Close)
+
+	events := readEvents(t, path)
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	if events[0].Type != LinkCreated || events[1].Type != LinkDeleted {
+		t.Errorf("types = %q, %q", events[0].Type, events[1].Type)
+	}
+	if data, _ := events[0].Data.(map[string]interface{}); data["short_code"] != "abc" {
+		t.Errorf("data = %v", events[0].Data)
+	}
+}
+
+func TestEventLogEmitAfterClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	l, err := NewEventLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	closeWithin(t, l.Close)
+
+	// 关闭后的事件被丢弃，重复关闭不会再次关闭文件
+	l.Emit(LinkUpdated, nil)
+	closeWithin(t, l.Close)
+
+	if events := readEvents(t, path); len(events) != 0 {
+		t.Fatalf("got %d events after Close, want 0", len(events))
+	}
+}
+
+func TestNilEventLog(t *testing.T) {
+	l, err := NewEventLog("")
+	if err != nil || l != nil {
+		t.Fatalf("NewEventLog(\"\") = %v, %v; want nil, nil", l, err)
+	}
+	l.Emit(LinkCreated, nil)
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
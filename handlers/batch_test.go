@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/justseemore/surl/models"
+)
+
+type batchCreateResponse struct {
+	Created int                 `json:"created"`
+	Failed  int                 `json:"failed"`
+	Results []batchCreateResult `json:"results"`
+}
+
+func TestBatchCreateMixedItems(t *testing.T) {
+	env := newTestEnv(t, nil)
+	items := []map[string]interface{}{
+		{"original_url": "https://example.com/one"},
+		{"original_url": "javascript:alert(1)"},
+		{"original_url": "https://example.com/two", "custom_code": "promo"},
+		{"original_url": "https://example.com/three", "custom_code": "promo"}, // 与上一条冲突
+		{"original_url": ""},
+		{"original_url": "https://example.com/four", "tags": []string{"A", "a"}},
+	}
+	var resp batchCreateResponse
+	env.do("POST", "/api/urls/batch/create", env.token("alice"), map[string]interface{}{"items": items}, 200, &resp)
+
+	if resp.Created != 3 || resp.Failed != 3 || len(resp.Results) != len(items) {
+		t.Fatalf("created/failed/results = %d/%d/%d", resp.Created, resp.Failed, len(resp.Results))
+	}
+	wantOK := []bool{true, false, true, false, false, true}
+	for i, result := range resp.Results {
+		if result.Index != i || result.Success != wantOK[i] {
+			t.Errorf("result %d = %+v, want success=%v", i, result, wantOK[i])
+			continue
+		}
+		if result.Success && (result.ID == 0 || result.ShortCode == "" || !strings.HasSuffix(result.ShortURL, "/"+result.ShortCode)) {
+			t.Errorf("result %d missing details: %+v", i, result)
+		}
+		if !result.Success && result.Error == "" {
+			t.Errorf("result %d has no error message", i)
+		}
+	}
+	if resp.Results[2].ShortCode != "promo" {
+		t.Errorf("custom code = %q, want promo", resp.Results[2].ShortCode)
+	}
+
+	// 失败的条目不影响成功的条目，成功的条目属于调用者
+	var stored []models.URL
+	if err := env.db.Order("id").Find(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 3 {
+		t.Fatalf("stored %d urls, want 3", len(stored))
+	}
+	for _, url := range stored {
+		if url.CreatedBy != "alice" {
+			t.Errorf("url %s created_by = %q", url.ShortCode, url.CreatedBy)
+		}
+	}
+}
+
+func TestBatchCreateLimits(t *testing.T) {
+	env := newTestEnv(t, nil)
+	env.do("POST", "/api/urls/batch/create", env.token("alice"), map[string]interface{}{"items": []interface{}{}}, 400, nil)
+
+	items := make([]map[string]string, maxBatchCreateItems+1)
+	for i := range items {
+		items[i] = map[string]string{"original_url": "https://example.com/x"}
+	}
+	env.do("POST", "/api/urls/batch/create", env.token("alice"), map[string]interface{}{"items": items}, 400, nil)
+	env.do("POST", "/api/urls/batch/create", "", map[string]interface{}{"items": items[:1]}, 401, nil)
+
+	var count int64
+	env.db.Model(&models.URL{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("rejected batches created %d urls", count)
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/justseemore/surl/config"
+	"github.com/justseemore/surl/models"
+	"github.com/justseemore/surl/services"
+)
+
+const weChatUA = "Mozilla/5.0 (iPhone; CPU iPhone OS 16_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 MicroMessenger/8.0.38"
+
+func TestBlockPageEscapesTitle(t *testing.T) {
+	env := newTestEnv(t, nil)
+	url := env.create("alice", "https://example.com/promo?a=1&b=2", services.URLOptions{})
+
+	// 绕过服务层的清理直接写入数据库，确认模板本身也会转义
+	const raw = `<script>alert("x")</script>`
+	if err := env.db.Model(&models.URL{}).Where("id = ?", url.ID).Update("title", raw).Error; err != nil {
+		t.Fatal(err)
+	}
+	env.cache.DeleteURL(url.ShortCode)
+
+	resp := env.get("/"+url.ShortCode, "", "User-Agent", weChatUA)
+	body, _ := io.ReadAll(resp.Body)
+	page := string(body)
+	if resp.StatusCode != 200 || !strings.Contains(page, "微信访问提示") {
+		t.Fatalf("status = %d, want the block page: %.200s", resp.StatusCode, page)
+	}
+	if strings.Contains(page, raw) {
+		t.Fatal("title rendered without escaping")
+	}
+	if !strings.Contains(page, "&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;") {
+		t.Fatalf("escaped title not found in page")
+	}
+	if !strings.Contains(page, "https://example.com/promo?a=1&amp;b=2") {
+		t.Fatal("destination not escaped in the url box")
+	}
+}
+
+func TestWebViewInterstitial(t *testing.T) {
+	const (
+		instagramUA = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 Instagram 312.0.0.0"
+		webViewUA   = "Mozilla/5.0 (Linux; Android 13; Pixel 7; wv) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/120.0.0.0 Mobile Safari/537.36"
+		chromeUA    = "Mozilla/5.0 (Linux; Android 13; Pixel 7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36"
+	)
+	for _, enabled := range []bool{false, true} {
+		env := newTestEnv(t, func(cfg *config.Config) { cfg.WebViewInterstitial = enabled })
+		url := env.create("alice", "https://example.com/app", services.URLOptions{})
+
+		cases := []struct {
+			ua        string
+			page      bool
+			wantTitle string
+		}{
+			{chromeUA, false, ""},
+			{instagramUA, enabled, "Instagram访问提示"},
+			{webViewUA, enabled, "访问提示"},
+			{weChatUA, true, "微信访问提示"},
+		}
+		for _, tc := range cases {
+			resp := env.get("/"+url.ShortCode, "", "User-Agent", tc.ua)
+			body, _ := io.ReadAll(resp.Body)
+			if !tc.page {
+				if resp.StatusCode != 302 || resp.Header.Get("Location") != url.OriginalURL {
+					t.Errorf("interstitial=%v %s: status = %d, want redirect", enabled, tc.ua, resp.StatusCode)
+				}
+				continue
+			}
+			if resp.StatusCode != 200 || !strings.Contains(string(body), tc.wantTitle) {
+				t.Errorf("interstitial=%v %s: status = %d, want page with %q", enabled, tc.ua, resp.StatusCode, tc.wantTitle)
+			}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/justseemore/surl/config"
+	"github.com/justseemore/surl/models"
+	"github.com/justseemore/surl/services"
+)
+
+func TestClickCountingOff(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.ClickCounting = config.ClickCountingOff })
+	url := env.create("alice", "https://example.com/private", services.URLOptions{})
+
+	for i := 0; i < 3; i++ {
+		resp := env.get("/"+url.ShortCode, "", "User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0.0.0")
+		if resp.StatusCode != 302 || resp.Header.Get("Location") != url.OriginalURL {
+			t.Fatalf("redirect = %d %q, want 302 to %s", resp.StatusCode, resp.Header.Get("Location"), url.OriginalURL)
+		}
+	}
+	settle()
+	if got := env.cache.GetPendingClicks(url.ShortCode); got != 0 {
+		t.Fatalf("pending clicks = %d, want 0", got)
+	}
+	env.urls.SyncClickCounts()
+	env.clicks.SyncClicks()
+	if got := env.clickCount(url); got != 0 {
+		t.Fatalf("click count = %d, want 0", got)
+	}
+	var details int64
+	if err := env.db.Model(&models.Click{}).Count(&details).Error; err != nil {
+		t.Fatal(err)
+	}
+	if details != 0 {
+		t.Fatalf("stored %d click details, want 0", details)
+	}
+
+	// 统计接口说明点击统计已关闭，点击数为0
+	var stats struct {
+		Stats         services.URLStats `json:"stats"`
+		ClickCounting bool              `json:"click_counting"`
+	}
+	env.do("GET", "/api/stats", env.token("alice"), nil, 200, &stats)
+	if stats.ClickCounting || stats.Stats.TotalClicks != 0 || stats.Stats.TodayClicks != 0 || stats.Stats.TotalURLs != 1 {
+		t.Fatalf("stats = %+v, click_counting = %v", stats.Stats, stats.ClickCounting)
+	}
+
+	// 依赖点击计数的最大点击次数无法设置
+	env.do("POST", "/api/create", env.token("alice"), map[string]interface{}{"original_url": "https://example.com/limited", "max_clicks": 10}, 400, nil)
+}
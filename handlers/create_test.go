@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justseemore/surl/config"
+	"gorm.io/gorm"
+)
+
+type createResponse struct {
+	Success   bool       `json:"success"`
+	ID        uint       `json:"id"`
+	ShortURL  string     `json:"short_url"`
+	ShortCode string     `json:"short_code"`
+	QRCode    string     `json:"qr_code"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func TestCreateResponseSchemeAndHost(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) {
+		cfg.CustomDomain = "s.example"
+		cfg.AllowedDomains = []string{"go.example"}
+	})
+
+	cases := []struct {
+		name    string
+		body    map[string]interface{}
+		headers []string
+		want    string // 短代码之前的部分
+	}{
+		{"plain http", map[string]interface{}{}, nil, "http://s.example/"},
+		{"behind TLS proxy", map[string]interface{}{}, []string{"X-Forwarded-Proto", "https"}, "https://s.example/"},
+		{"proxy without TLS", map[string]interface{}{}, []string{"X-Forwarded-Proto", "http"}, "http://s.example/"},
+		{"per-link domain", map[string]interface{}{"domain": "go.example"}, []string{"X-Forwarded-Proto", "https"}, "https://go.example/"},
+	}
+	for i, tc := range cases {
+		tc.body["original_url"] = "https://example.com/create/" + string(rune('a'+i))
+		resp := env.request("POST", "/api/create", env.token("alice"), tc.body, tc.headers...)
+		var out createResponse
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil || resp.StatusCode != 200 {
+			t.Fatalf("%s: status %d, decode err %v", tc.name, resp.StatusCode, err)
+		}
+		if out.ShortURL != tc.want+out.ShortCode {
+			t.Errorf("%s: short_url = %q, want %q", tc.name, out.ShortURL, tc.want+out.ShortCode)
+		}
+		// 未指定过期时间时使用默认有效期
+		if out.ID == 0 || out.CreatedAt.IsZero() || out.ExpiresAt == nil || !out.ExpiresAt.After(out.CreatedAt) {
+			t.Errorf("%s: id/created_at/expires_at = %d/%v/%v", tc.name, out.ID, out.CreatedAt, out.ExpiresAt)
+		}
+		png, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(out.QRCode, "data:image/png;base64,"))
+		if !strings.HasPrefix(out.QRCode, "data:image/png;base64,") || err != nil || !strings.HasPrefix(string(png), "\x89PNG") {
+			t.Errorf("%s: qr_code is not a PNG data URI: %.40s", tc.name, out.QRCode)
+		}
+	}
+}
+
+func TestCreateResponseExpiresAt(t *testing.T) {
+	env := newTestEnv(t, nil)
+	expires := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+	var out createResponse
+	env.do("POST", "/api/create", env.token("alice"), map[string]interface{}{
+		"original_url": "https://example.com/expiring",
+		"expires_at":   expires,
+	}, 200, &out)
+	if out.ExpiresAt == nil || !out.ExpiresAt.Equal(expires) {
+		t.Fatalf("expires_at = %v, want %v", out.ExpiresAt, expires)
+	}
+}
+
+func TestCreatePlainText(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.CustomDomain = "s.example" })
+	token := env.token("alice")
+
+	resp := env.request("POST", "/api/create", token, map[string]interface{}{"original_url": "https://example.com/cli"}, "Accept", "text/plain")
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain") {
+		t.Fatalf("status = %d, Content-Type = %q, want 200 text/plain", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	code := strings.TrimPrefix(string(body), "http://s.example/")
+	if code == string(body) || code == "" || strings.ContainsAny(code, "/\n{") {
+		t.Fatalf("body = %q, want only the short URL", body)
+	}
+	if url, err := env.urls.FindByShortCode(code); err != nil || url.OriginalURL != "https://example.com/cli" {
+		t.Fatalf("short code %q from the body does not resolve: %v", code, err)
+	}
+
+	// 错误同样以纯文本返回
+	resp = env.request("POST", "/api/create", token, map[string]interface{}{}, "Accept", "text/plain")
+	body, _ = io.ReadAll(resp.Body)
+	if resp.StatusCode != 400 || string(body) != "原始链接不能为空" {
+		t.Fatalf("error = %d %q, want 400 plain text", resp.StatusCode, body)
+	}
+
+	// 未指定或接受JSON时返回JSON
+	for i, accept := range []string{"", "application/json", "*/*", "application/json, text/plain"} {
+		headers := []string{}
+		if accept != "" {
+			headers = []string{"Accept", accept}
+		}
+		resp := env.request("POST", "/api/create", token, map[string]interface{}{"original_url": fmt.Sprintf("https://example.com/json/%d", i)}, headers...)
+		var out createResponse
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil || out.ShortCode == "" {
+			t.Errorf("Accept %q: decode err %v, short_code %q; want JSON", accept, err, out.ShortCode)
+		}
+	}
+}
+
+func TestCreateValidationErrors(t *testing.T) {
+	env := newTestEnv(t, nil)
+	token := env.token("alice")
+	createFails := func(name string, body map[string]interface{}) {
+		t.Helper()
+		var out struct {
+			Error string `json:"error"`
+		}
+		env.do("POST", "/api/create", token, body, 400, &out)
+		if out.Error == "" || strings.HasPrefix(out.Error, "创建短链接失败") {
+			t.Errorf("%s: error = %q, want the validation reason", name, out.Error)
+		}
+	}
+
+	// 参数未通过校验时返回400和具体原因，而不是500
+	createFails("scheme", map[string]interface{}{"original_url": "javascript:alert(1)"})
+	createFails("long alias", map[string]interface{}{"original_url": "https://example.com/alias", "custom_code": strings.Repeat("a", 100)})
+	createFails("negative unique IPs", map[string]interface{}{"original_url": "https://example.com/ips", "max_unique_ips": -1})
+	env.cfg.BlockPrivateHosts = true // IP字面量无需解析主机名
+	createFails("private host", map[string]interface{}{"original_url": "http://127.0.0.1/admin"})
+	env.cfg.BlockPrivateHosts = false
+
+	// 数据库错误仍返回500
+	if err := env.db.Callback().Create().Before("gorm:create").Register("test:fail_create", func(tx *gorm.DB) {
+		tx.AddError(errors.New("database is locked"))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	env.do("POST", "/api/create", token, map[string]interface{}{"original_url": "https://example.com/db"}, 500, nil)
+}
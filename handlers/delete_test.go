@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/justseemore/surl/services"
+)
+
+func TestDeleteURLTwiceSucceeds(t *testing.T) {
+	env := newTestEnv(t, nil)
+	url := env.create("alice", "https://example.com/retry", services.URLOptions{})
+	path := fmt.Sprintf("/api/urls/%d/delete", url.ID)
+
+	// 客户端重试删除请求时两次都返回成功
+	for i := 0; i < 2; i++ {
+		var resp struct {
+			Success bool `json:"success"`
+		}
+		env.do("POST", path, env.token("alice"), nil, 200, &resp)
+		if !resp.Success {
+			t.Fatalf("delete #%d: success = false", i+1)
+		}
+	}
+
+	// 未被删除的他人链接仍然返回403
+	other := env.create("alice", "https://example.com/kept", services.URLOptions{})
+	env.do("POST", fmt.Sprintf("/api/urls/%d/delete", other.ID), env.token("bob"), nil, 403, nil)
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/justseemore/surl/services"
+)
+
+func TestDestinationDoesNotCountClicks(t *testing.T) {
+	env := newTestEnv(t, nil)
+	url := env.create("alice", "https://example.com/embed?x=1", services.URLOptions{})
+	path := "/api/urls/code/" + url.ShortCode + "/destination"
+
+	for _, user := range []string{"alice", "admin"} {
+		var resp struct {
+			ShortCode   string `json:"short_code"`
+			OriginalURL string `json:"original_url"`
+		}
+		env.do("GET", path, env.token(user), nil, 200, &resp)
+		if resp.ShortCode != url.ShortCode || resp.OriginalURL != "https://example.com/embed?x=1" {
+			t.Fatalf("%s: destination = %+v", user, resp)
+		}
+	}
+	settle()
+	if got := env.clickCount(url); got != 0 {
+		t.Fatalf("destination lookups counted %d clicks", got)
+	}
+
+	// 需要登录，且只能查看自己的链接
+	env.do("GET", path, "", nil, 401, nil)
+	env.do("GET", path, env.token("bob"), nil, 403, nil)
+	env.do("GET", "/api/urls/code/nosuchcode/destination", env.token("alice"), nil, 404, nil)
+
+	// 对照：跳转计入点击
+	env.get("/"+url.ShortCode, "")
+	env.waitClicks(url, 1)
+}
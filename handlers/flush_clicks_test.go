@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/justseemore/surl/services"
+)
+
+func TestAdminFlushClicks(t *testing.T) {
+	env := newTestEnv(t, nil)
+	hot := env.create("alice", "https://example.com/hot", services.URLOptions{})
+	other := env.create("alice", "https://example.com/other", services.URLOptions{})
+	for i := 0; i < 3; i++ {
+		env.get("/"+hot.ShortCode, "")
+	}
+	env.get("/"+other.ShortCode, "")
+	env.waitClicks(hot, 3)
+	env.waitClicks(other, 1)
+
+	path := fmt.Sprintf("/api/admin/urls/%d/flush-clicks", hot.ID)
+	env.do("POST", path, env.token("alice"), nil, 403, nil)
+
+	var resp struct {
+		ClickCount int64 `json:"click_count"`
+	}
+	env.do("POST", path, env.token("admin"), nil, 200, &resp)
+	if resp.ClickCount != 3 {
+		t.Fatalf("click_count = %d, want 3", resp.ClickCount)
+	}
+	if got := env.cache.GetPendingClicks(hot.ShortCode); got != 0 {
+		t.Fatalf("hot pending = %d, want 0", got)
+	}
+	if got := env.cache.GetPendingClicks(other.ShortCode); got != 1 {
+		t.Fatalf("other pending = %d, want 1 left for the periodic sync", got)
+	}
+	env.do("POST", "/api/admin/urls/99999/flush-clicks", env.token("admin"), nil, 404, nil)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"io"
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/justseemore/surl/middleware"
+	"github.com/justseemore/surl/models"
+	"github.com/justseemore/surl/services"
+)
+
+// stubCountries 测试IP对应的国家，未列出的IP无法识别
+var stubCountries = map[string]string{
+	"203.0.113.1": "CN",
+	"203.0.113.2": "US",
+	"203.0.113.3": "RU",
+}
+
+func TestRedirectGeoRules(t *testing.T) {
+	env := newTestEnv(t, nil)
+	geo := services.NewGeoServiceWithLookup(func(ip net.IP) string { return stubCountries[ip.String()] }, env.db)
+	handler := NewHandler(env.urls, env.auth, geo, env.clicks, middleware.NewPerfTracker(), env.cfg)
+
+	// 通过 X-Forwarded-For 模拟不同国家的访客
+	app := fiber.New(fiber.Config{ProxyHeader: fiber.HeaderXForwardedFor})
+	app.Use(middleware.UADetector())
+	app.Get("/:code", handler.Redirect)
+	visit := func(code, ip string) (int, string) {
+		req := httptest.NewRequest("GET", "/"+code, nil)
+		req.Header.Set(fiber.HeaderXForwardedFor, ip)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	anywhere := env.create("alice", "https://example.com/anywhere", services.URLOptions{})
+	allowCN := env.create("alice", "https://example.com/cn-only", services.URLOptions{GeoRules: &models.GeoRules{Allow: []string{"cn"}}})
+	denyRU := env.create("alice", "https://example.com/no-ru", services.URLOptions{GeoRules: &models.GeoRules{Deny: []string{"RU"}}})
+
+	cases := []struct {
+		url     *models.URL
+		ip      string
+		allowed bool
+	}{
+		{anywhere, "203.0.113.1", true},
+		{anywhere, "203.0.113.3", true},
+		{anywhere, "198.51.100.1", true},
+		{allowCN, "203.0.113.1", true},
+		{allowCN, "203.0.113.2", false},
+		{allowCN, "198.51.100.1", false}, // 无法识别的国家不在允许列表中
+		{denyRU, "203.0.113.2", true},
+		{denyRU, "203.0.113.3", false},
+		{denyRU, "198.51.100.1", true},
+	}
+	want := map[uint]int64{}
+	for _, tc := range cases {
+		status, body := visit(tc.url.ShortCode, tc.ip)
+		if tc.allowed {
+			want[tc.url.ID]++
+			if status != 302 {
+				t.Errorf("%s from %s = %d %q, want 302", tc.url.OriginalURL, tc.ip, status, body)
+			}
+			continue
+		}
+		if status != 403 || body != "该短链接在您所在的地区不可用" {
+			t.Errorf("%s from %s = %d %q, want 403 region page", tc.url.OriginalURL, tc.ip, status, body)
+		}
+	}
+
+	// 被拒绝的访问不计入点击
+	for _, url := range []*models.URL{anywhere, allowCN, denyRU} {
+		env.waitClicks(url, want[url.ID])
+	}
+	settle()
+	for _, url := range []*models.URL{anywhere, allowCN, denyRU} {
+		if got := env.clickCount(url); got != want[url.ID] {
+			t.Errorf("%s click count = %d, want %d", url.OriginalURL, got, want[url.ID])
+		}
+	}
+}
@@ -1,27 +1,39 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"strconv"
+	"strings"
 	"time" // 添加 time 包导入
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
 	"github.com/justseemore/surl/config"
 	"github.com/justseemore/surl/middleware"
+	"github.com/justseemore/surl/models"
 	"github.com/justseemore/surl/services"
 )
 
 type Handler struct {
 	urlService  *services.URLService
 	authService *services.AuthService
+	geoService  *services.GeoService // 未配置GeoIP时为nil
+	perfTracker *middleware.PerfTracker
 	config      *config.Config
+
+	clickService *services.ClickService
 }
 
-func NewHandler(urlService *services.URLService, authService *services.AuthService, config *config.Config) *Handler {
+func NewHandler(urlService *services.URLService, authService *services.AuthService, geoService *services.GeoService, clickService *services.ClickService, perfTracker *middleware.PerfTracker, config *config.Config) *Handler {
 	return &Handler{
 		urlService:  urlService,
 		authService: authService,
+		geoService:  geoService,
+		perfTracker: perfTracker,
 		config:      config,
+
+		clickService: clickService,
 	}
 }
 
@@ -60,9 +72,27 @@ func (h *Handler) Login(c *fiber.Ctx) error {
 		})
 	}
 
+	refreshToken, err := h.authService.GenerateRefreshToken(user)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "生成令牌失败",
+		})
+	}
+
+	// 同时写入Cookie，便于跳转等非API请求识别登录用户
+	c.Cookie(&fiber.Cookie{
+		Name:     middleware.TokenCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  time.Now().Add(24 * time.Hour),
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+
 	return c.JSON(fiber.Map{
-		"success": true,
-		"token":   token,
+		"success":       true,
+		"token":         token,
+		"refresh_token": refreshToken,
 		"user": fiber.Map{
 			"username": user.Username,
 			"role":     user.Role,
@@ -70,44 +100,177 @@ func (h *Handler) Login(c *fiber.Ctx) error {
 	})
 }
 
+// createRequest 创建短链接的请求参数（单个创建和批量创建共用）
+type createRequest struct {
+	OriginalURL    string     `json:"original_url" form:"original_url"`
+	Title          string     `json:"title" form:"title"`
+	Description    string     `json:"description" form:"description"`
+	ExpiresAt      *time.Time `json:"expires_at" form:"expires_at"`
+	AllowDuplicate *bool      `json:"allow_duplicate" form:"allow_duplicate"` // 覆盖全局的 ALLOW_DUPLICATE_URLS 配置
+	MaxClicks      *int64     `json:"max_clicks" form:"max_clicks"`
+	MaxUniqueIPs   *int64     `json:"max_unique_ips" form:"max_unique_ips"`
+	StartsAt       *time.Time `json:"starts_at" form:"starts_at"`
+	Domain         string     `json:"domain" form:"domain"` // 需在 ALLOWED_DOMAINS 中
+	RedirectType   *int       `json:"redirect_type" form:"redirect_type"`
+	CustomCode     string     `json:"custom_code" form:"custom_code"`
+
+	RefererRules  []models.RefererRule  `json:"referer_rules" form:"-"`
+	ScheduleRules []models.ScheduleRule `json:"schedule_rules" form:"-"`
+	GeoRules      *models.GeoRules      `json:"geo_rules" form:"-"`
+	Tags          []string              `json:"tags" form:"tags"`
+}
+
+// errNoGeoIP 未配置GeoIP时无法识别访客国家，地区限制不会生效
+var errNoGeoIP = errors.New("未配置 GEOIP_DB_PATH，无法设置地区限制")
+
+// errNoClickCounting 关闭点击统计时点击数不会增加，最大点击次数永远不会达到
+var errNoClickCounting = errors.New("CLICK_COUNTING=off 时无法设置最大点击次数")
+
+// createURL 按请求参数为指定用户创建短链接
+func (h *Handler) createURL(req createRequest, username string) (*models.URL, error) {
+	if req.OriginalURL == "" {
+		return nil, errors.New("原始链接不能为空")
+	}
+	if req.GeoRules != nil && h.geoService == nil {
+		return nil, errNoGeoIP
+	}
+	if req.MaxClicks != nil && *req.MaxClicks > 0 && !h.config.ClickCountingEnabled() {
+		return nil, errNoClickCounting
+	}
+
+	allowDuplicate := h.config.AllowDuplicates
+	if req.AllowDuplicate != nil {
+		allowDuplicate = *req.AllowDuplicate
+	}
+
+	return h.urlService.CreateShortURL(req.OriginalURL, req.Title, req.Description, req.Domain, req.ExpiresAt, username, allowDuplicate, services.URLOptions{
+		MaxClicks:    req.MaxClicks,
+		MaxUniqueIPs: req.MaxUniqueIPs,
+		StartsAt:     req.StartsAt,
+		RedirectType: req.RedirectType,
+		CustomCode:   req.CustomCode,
+		RefererRules: req.RefererRules,
+
+		ScheduleRules: req.ScheduleRules,
+		GeoRules:      req.GeoRules,
+		Tags:          req.Tags,
+	})
+}
+
 // CreateShortURL 创建短链接（仅限认证用户）
 func (h *Handler) CreateShortURL(c *fiber.Ctx) error {
-	type CreateRequest struct {
-		OriginalURL string     `json:"original_url" form:"original_url"`
-		Title       string     `json:"title" form:"title"`
-		Description string     `json:"description" form:"description"`
-		ExpiresAt   *time.Time `json:"expires_at" form:"expires_at"`
+	// Accept: text/plain 时只返回完整短链接（便于命令行使用），错误同样以纯文本返回；默认返回JSON
+	plainText := c.Accepts(fiber.MIMEApplicationJSON, fiber.MIMETextPlain) == fiber.MIMETextPlain
+	fail := func(status int, message string) error {
+		if plainText {
+			return c.Status(status).SendString(message)
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": message,
+		})
 	}
 
-	var req CreateRequest
+	var req createRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "无效的请求格式",
-		})
+		return fail(400, "无效的请求格式")
 	}
 
 	if req.OriginalURL == "" {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "原始链接不能为空",
-		})
+		return fail(400, "原始链接不能为空")
 	}
 
 	// 从JWT中获取用户名（修复：使用username而不是user_id）
 	username := c.Locals("username").(string)
 
 	// 修复：传递username作为createdBy参数
-	shortURL, err := h.urlService.CreateShortURL(req.OriginalURL, req.Title, req.Description, h.config.CustomDomain, req.ExpiresAt, username)
+	shortURL, err := h.createURL(req, username)
+	if errors.Is(err, errNoGeoIP) || errors.Is(err, errNoClickCounting) || errors.Is(err, services.ErrInvalidInput) {
+		return fail(400, err.Error())
+	}
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": "创建短链接失败: " + err.Error(),
-		})
+		return fail(500, "创建短链接失败: "+err.Error())
+	}
+
+	fullURL := h.fullShortURL(c, shortURL)
+	if plainText {
+		return c.SendString(fullURL)
+	}
+
+	qrCode, err := qrCodeDataURI(fullURL)
+	if err != nil {
+		log.Printf("生成二维码失败 [%s]: %v", shortURL.ShortCode, err)
 	}
 
 	return c.JSON(fiber.Map{
 		"success":    true,
-		"short_url":  "https://" + h.config.CustomDomain + "/" + shortURL.ShortCode,
+		"id":         shortURL.ID,
+		"short_url":  fullURL,
 		"short_code": shortURL.ShortCode,
-		"qr_code":    "data:image/svg+xml;base64," + shortURL.ShortCode,
+		"qr_code":    qrCode,
+		"created_at": shortURL.CreatedAt,
+		"expires_at": shortURL.ExpiresAt,
+	})
+}
+
+// maxBatchCreateItems 批量创建单次最多的条目数
+const maxBatchCreateItems = 100
+
+// batchCreateResult 批量创建中单个条目的结果
+type batchCreateResult struct {
+	Index     int    `json:"index"`
+	Success   bool   `json:"success"`
+	ID        uint   `json:"id,omitempty"`
+	ShortCode string `json:"short_code,omitempty"`
+	ShortURL  string `json:"short_url,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// BatchCreateURLs 批量创建短链接，每个条目独立创建，单个失败不影响其他条目
+func (h *Handler) BatchCreateURLs(c *fiber.Ctx) error {
+	type BatchCreateRequest struct {
+		Items []createRequest `json:"items"`
+	}
+
+	var req BatchCreateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "无效的请求格式",
+		})
+	}
+
+	if len(req.Items) == 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "请提供要创建的链接",
+		})
+	}
+	if len(req.Items) > maxBatchCreateItems {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "单次最多创建" + strconv.Itoa(maxBatchCreateItems) + "个链接",
+		})
+	}
+
+	username := c.Locals("username").(string)
+	results := make([]batchCreateResult, len(req.Items))
+	created := 0
+	for i, item := range req.Items {
+		results[i].Index = i
+		shortURL, err := h.createURL(item, username)
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		results[i].Success = true
+		results[i].ID = shortURL.ID
+		results[i].ShortCode = shortURL.ShortCode
+		results[i].ShortURL = h.fullShortURL(c, shortURL)
+		created++
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"created": created,
+		"failed":  len(req.Items) - created,
+		"results": results,
 	})
 }
 
@@ -139,7 +302,65 @@ func (h *Handler) GetURLs(c *fiber.Ctx) error {
 		createdBy = username // 非管理员只能看自己的记录
 	}
 
-	urls, total, err := h.urlService.GetURLList(page, limit, search, createdBy)
+	// 搜索词为空时的行为，empty_search 参数可覆盖全局配置
+	returnAll := h.config.EmptySearchReturnsAll
+	switch c.Query("empty_search") {
+	case "all":
+		returnAll = true
+	case "none":
+		returnAll = false
+	}
+
+	filter := services.URLListFilter{
+		Search:        search,
+		CreatedBy:     createdBy,
+		RequireSearch: !returnAll,
+	}
+
+	// 多值过滤：status=active,expired；owner=alice,bob（仅管理员）
+	filter.Statuses = splitQueryList(c.Query("status"))
+	for _, status := range filter.Statuses {
+		if !services.IsValidURLStatus(status) {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "无效的状态: " + status,
+			})
+		}
+	}
+	if role == "admin" {
+		filter.Owners = splitQueryList(c.Query("owner"))
+	}
+
+	// 按目标主机过滤：host=example.com 同时匹配 example.com 和 www.example.com
+	filter.Host = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(c.Query("host"))), ".")
+	if strings.ContainsAny(filter.Host, "%_/:?# ") {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "无效的host参数: " + filter.Host,
+		})
+	}
+
+	// 按标签过滤：tag=campaign，可与搜索、状态等条件组合
+	filter.Tag = c.Query("tag")
+
+	// 日期范围：created_after/created_before 分别是 created_from/created_to 的别名
+	// 只有日期时按配置时区计算，上限包含当天；expires_before=2024-01-07 即当天结束前过期的URL
+	var err error
+	if filter.CreatedFrom, err = parseDateParam(c.Query("created_from", c.Query("created_after")), false, h.config.Location()); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "无效的created_from参数: " + err.Error(),
+		})
+	}
+	if filter.CreatedTo, err = parseDateParam(c.Query("created_to", c.Query("created_before")), true, h.config.Location()); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "无效的created_to参数: " + err.Error(),
+		})
+	}
+	if filter.ExpiresBefore, err = parseDateParam(c.Query("expires_before"), true, h.config.Location()); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "无效的expires_before参数: " + err.Error(),
+		})
+	}
+
+	urls, total, err := h.urlService.GetURLList(page, limit, filter)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error": "获取数据失败",
@@ -150,7 +371,75 @@ func (h *Handler) GetURLs(c *fiber.Ctx) error {
 	totalPages := int((total + int64(limit) - 1) / int64(limit))
 
 	return c.JSON(fiber.Map{
-		"urls":         urls,
+		"urls":         presentURLs(role, urls, h.config.Location()),
+		"total":        total,
+		"current_page": page,
+		"total_pages":  totalPages,
+		"limit":        limit,
+		"success":      true,
+	})
+}
+
+// splitQueryList 解析逗号分隔的查询参数，去除空白和空项
+func splitQueryList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseDateParam 解析日期查询参数，支持 RFC3339 和 2006-01-02 两种格式
+// 仅日期的值按配置的时区解释，上限按当天结束计算，使 created_to=2024-01-07 包含当天创建的链接
+func parseDateParam(value string, endOfDay bool, loc *time.Location) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return models.UTC(&t), nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, loc)
+	if err != nil {
+		return nil, errors.New("日期格式应为 2006-01-02 或 RFC3339")
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return models.UTC(&t), nil
+}
+
+// GetDeletedURLs 获取回收站中的URL列表（需要认证）
+func (h *Handler) GetDeletedURLs(c *fiber.Ctx) error {
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 20)
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	username := c.Locals("username").(string)
+	role := c.Locals("role").(string)
+	createdBy := ""
+	if role != "admin" {
+		createdBy = username // 非管理员只能看自己的记录
+	}
+
+	urls, total, err := h.urlService.GetDeletedURLs(page, limit, createdBy)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "获取数据失败",
+		})
+	}
+
+	totalPages := int((total + int64(limit) - 1) / int64(limit))
+
+	return c.JSON(fiber.Map{
+		"urls":         presentDeletedURLs(role, urls),
 		"total":        total,
 		"current_page": page,
 		"total_pages":  totalPages,
@@ -169,10 +458,19 @@ func (h *Handler) UpdateURL(c *fiber.Ctx) error {
 	}
 
 	type UpdateRequest struct {
-		OriginalURL string     `json:"original_url"`
-		Title       string     `json:"title"`
-		ExpiresAt   *time.Time `json:"expires_at"`
-		IsActive    bool       `json:"is_active"`
+		OriginalURL  string     `json:"original_url"`
+		Title        string     `json:"title"`
+		ExpiresAt    *time.Time `json:"expires_at"`
+		IsActive     *bool      `json:"is_active"`  // 不传表示不修改
+		MaxClicks    *int64     `json:"max_clicks"` // 0表示取消限制
+		StartsAt     *time.Time `json:"starts_at"`  // 零值表示取消
+		RedirectType *int       `json:"redirect_type"`
+		MaxUniqueIPs *int64     `json:"max_unique_ips"` // 0表示取消限制
+
+		RefererRules  []models.RefererRule  `json:"referer_rules"` // 不传表示不修改，空列表表示清除
+		ScheduleRules []models.ScheduleRule `json:"schedule_rules"`
+		GeoRules      *models.GeoRules      `json:"geo_rules"` // 不传表示不修改，两个列表都为空表示清除
+		Tags          []string              `json:"tags"`      // 不传表示不修改，空列表表示清除
 	}
 
 	var req UpdateRequest
@@ -182,9 +480,40 @@ func (h *Handler) UpdateURL(c *fiber.Ctx) error {
 		})
 	}
 
+	if req.GeoRules != nil && h.geoService == nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": errNoGeoIP.Error(),
+		})
+	}
+	if req.MaxClicks != nil && *req.MaxClicks > 0 && !h.config.ClickCountingEnabled() {
+		return c.Status(400).JSON(fiber.Map{
+			"error": errNoClickCounting.Error(),
+		})
+	}
+
 	// 修复：添加updatedBy参数
 	username := c.Locals("username").(string)
-	err = h.urlService.UpdateURL(uint(id), req.OriginalURL, req.Title, req.ExpiresAt, req.IsActive, username)
+	url, err := h.urlService.UpdateURL(uint(id), req.OriginalURL, req.Title, req.ExpiresAt, req.IsActive, username, services.URLOptions{
+		MaxClicks:    req.MaxClicks,
+		MaxUniqueIPs: req.MaxUniqueIPs,
+		StartsAt:     req.StartsAt,
+		RedirectType: req.RedirectType,
+		RefererRules: req.RefererRules,
+
+		ScheduleRules: req.ScheduleRules,
+		GeoRules:      req.GeoRules,
+		Tags:          req.Tags,
+	})
+	if errors.Is(err, services.ErrForbidden) {
+		return c.Status(403).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if errors.Is(err, services.ErrInvalidInput) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error": "更新失败: " + err.Error(),
@@ -194,6 +523,7 @@ func (h *Handler) UpdateURL(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{
 		"success": true,
 		"message": "更新成功",
+		"url":     presentURL(c.Locals("role").(string), url, h.config.Location()),
 	})
 }
 
@@ -209,6 +539,11 @@ func (h *Handler) DeleteURL(c *fiber.Ctx) error {
 	// 修复：添加deletedBy参数
 	username := c.Locals("username").(string)
 	err = h.urlService.DeleteURL(uint(id), username)
+	if errors.Is(err, services.ErrForbidden) {
+		return c.Status(403).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error": "删除失败: " + err.Error(),
@@ -221,6 +556,30 @@ func (h *Handler) DeleteURL(c *fiber.Ctx) error {
 	})
 }
 
+// RestoreURL 恢复已删除的URL（需要认证）
+func (h *Handler) RestoreURL(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "无效的ID",
+		})
+	}
+
+	username := c.Locals("username").(string)
+	url, err := h.urlService.RestoreURL(uint(id), username)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "恢复失败: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "恢复成功",
+		"url":     presentURL(c.Locals("role").(string), url, h.config.Location()),
+	})
+}
+
 // GetURLByID 根据ID获取单个URL
 func (h *Handler) GetURLByID(c *fiber.Ctx) error {
 	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
@@ -239,101 +598,359 @@ func (h *Handler) GetURLByID(c *fiber.Ctx) error {
 
 	return c.JSON(fiber.Map{
 		"success": true,
-		"url":     url,
+		"url":     presentURL(c.Locals("role").(string), url, h.config.Location()),
 	})
 }
 
-// BatchDeleteURLs 批量删除URLs
-func (h *Handler) BatchDeleteURLs(c *fiber.Ctx) error {
-	type BatchDeleteRequest struct {
-		IDs []uint `json:"ids"`
+// LookupURL 查询目标URL是否已有短链接
+// 不允许重复URL时目标URL全局唯一，因此全局查询；允许重复时非管理员只查自己的链接
+func (h *Handler) LookupURL(c *fiber.Ctx) error {
+	originalURL := c.Query("url")
+	if originalURL == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "url参数不能为空",
+		})
 	}
 
-	var req BatchDeleteRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "无效的请求格式",
+	createdBy := ""
+	if h.config.AllowDuplicates && c.Locals("role").(string) != "admin" {
+		createdBy = c.Locals("username").(string)
+	}
+
+	url, err := h.urlService.FindByOriginalURL(originalURL, createdBy)
+	if errors.Is(err, services.ErrNotFound) {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "该URL尚未创建短链接",
 		})
 	}
-	username := c.Locals("username").(string)
-	err := h.urlService.BatchDeleteURLs(req.IDs, username)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": "批量删除失败: " + err.Error(),
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
 		})
 	}
 
 	return c.JSON(fiber.Map{
-		"success": true,
-		"message": "批量删除成功",
+		"success":    true,
+		"short_code": url.ShortCode,
+		"short_url":  h.fullShortURL(c, url),
 	})
 }
 
-// GetStats 获取统计信息
-func (h *Handler) GetStats(c *fiber.Ctx) error {
-	username := c.Locals("username").(string)
-	stats, err := h.urlService.GetURLStats(username)
+// GetDestination 获取自己链接的目标地址（需要认证，不跳转也不计入点击）
+// 与公开的预览接口不同，不检查链接是否可用，供第三方自行渲染预览或二维码
+func (h *Handler) GetDestination(c *fiber.Ctx) error {
+	url, err := h.urlService.FindByShortCode(c.Params("code"))
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": "获取统计信息失败",
+		return c.Status(404).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// 非管理员只能查看自己的链接
+	username := c.Locals("username").(string)
+	role := c.Locals("role").(string)
+	if role != "admin" && url.CreatedBy != username {
+		return c.Status(403).JSON(fiber.Map{
+			"error": "无权限查看该链接",
 		})
 	}
 
 	return c.JSON(fiber.Map{
-		"success": true,
-		"stats":   stats,
+		"success":      true,
+		"short_code":   url.ShortCode,
+		"original_url": url.OriginalURL,
 	})
 }
 
-// CleanupExpired 清理过期链接
-func (h *Handler) CleanupExpired(c *fiber.Ctx) error {
-	err := h.urlService.CleanupExpiredURLs()
+// GetGeoStats 获取短链接按国家的点击统计
+func (h *Handler) GetGeoStats(c *fiber.Ctx) error {
+	if h.geoService == nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "未启用GeoIP统计",
+		})
+	}
+
+	shortCode := c.Params("code")
+	url, err := h.urlService.FindByShortCode(shortCode)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": "清理过期链接失败: " + err.Error(),
+		return c.Status(404).JSON(fiber.Map{
+			"error": err.Error(),
 		})
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"message": "清理完成",
-	})
-}
+	// 非管理员只能查看自己的链接
+	username := c.Locals("username").(string)
+	role := c.Locals("role").(string)
+	if role != "admin" && url.CreatedBy != username {
+		return c.Status(403).JSON(fiber.Map{
+			"error": "无权限查看该链接",
+		})
+	}
 
-// GetExpiredURLs 获取过期链接列表
-func (h *Handler) GetExpiredURLs(c *fiber.Ctx) error {
-	urls, err := h.urlService.GetExpiredURLs()
+	clicks, err := h.geoService.GetCountryClicks(shortCode)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
-			"error": "获取过期链接失败",
+			"error": "获取国家统计失败",
 		})
 	}
 
 	return c.JSON(fiber.Map{
-		"success": true,
-		"urls":    urls,
+		"success":    true,
+		"short_code": shortCode,
+		"countries":  clicks,
 	})
 }
 
-// GetProfile 获取用户信息
-func (h *Handler) GetProfile(c *fiber.Ctx) error {
-	username := c.Locals("username").(string)
-	accountInfo := h.authService.GetAccountInfo(username)
-	if accountInfo == nil {
-		return c.Status(404).JSON(fiber.Map{
-			"error": "用户不存在",
+// GetUABreakdown 获取短链接按设备、浏览器、操作系统的点击统计
+func (h *Handler) GetUABreakdown(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "无效的ID",
 		})
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"user":    accountInfo,
+	url, err := h.urlService.GetURLByID(uint(id))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// 非管理员只能查看自己的链接
+	username := c.Locals("username").(string)
+	role := c.Locals("role").(string)
+	if role != "admin" && url.CreatedBy != username {
+		return c.Status(403).JSON(fiber.Map{
+			"error": "无权限查看该链接",
+		})
+	}
+
+	breakdown, err := h.clickService.GetUABreakdown(url.ShortCode)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "获取访问设备统计失败",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"short_code": url.ShortCode,
+		"devices":    breakdown.Devices,
+		"browsers":   breakdown.Browsers,
+		"os":         breakdown.OS,
+	})
+}
+
+// BatchDeleteURLs 批量删除URLs
+func (h *Handler) BatchDeleteURLs(c *fiber.Ctx) error {
+	type BatchDeleteRequest struct {
+		IDs []uint `json:"ids"`
+	}
+
+	var req BatchDeleteRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "无效的请求格式",
+		})
+	}
+	username := c.Locals("username").(string)
+	err := h.urlService.BatchDeleteURLs(req.IDs, username)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "批量删除失败: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "批量删除成功",
+	})
+}
+
+// GetStats 获取统计信息
+func (h *Handler) GetStats(c *fiber.Ctx) error {
+	username := c.Locals("username").(string)
+	stats, err := h.urlService.GetURLStats(username)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "获取统计信息失败",
+		})
+	}
+
+	resp := fiber.Map{
+		"success":        true,
+		"stats":          stats,
+		"click_counting": h.config.ClickCountingEnabled(), // 关闭时点击相关的统计始终为0
+	}
+	// 缓存统计反映整个实例，只对管理员展示
+	if c.Locals("role").(string) == "admin" {
+		resp["cache"] = h.urlService.CacheStats()
+	}
+	return c.JSON(resp)
+}
+
+// CleanupExpired 清理过期链接
+func (h *Handler) CleanupExpired(c *fiber.Ctx) error {
+	err := h.urlService.CleanupExpiredURLs()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "清理过期链接失败: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "清理完成",
+	})
+}
+
+// PurgeDeleted 永久删除超过保留期的回收站记录（仅限管理员）
+func (h *Handler) PurgeDeleted(c *fiber.Ctx) error {
+	days := c.QueryInt("days", h.config.TrashRetentionDays)
+	if days < 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "无效的保留天数",
+		})
+	}
+
+	count, err := h.urlService.PurgeDeleted(time.Duration(days) * 24 * time.Hour)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "清理完成",
+		"purged":  count,
+	})
+}
+
+// GetExpiredURLs 获取过期链接列表
+func (h *Handler) GetExpiredURLs(c *fiber.Ctx) error {
+	urls, err := h.urlService.GetExpiredURLs()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "获取过期链接失败",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"urls":    presentURLs(c.Locals("role").(string), urls, h.config.Location()),
+	})
+}
+
+// GetProfile 获取用户信息
+func (h *Handler) GetProfile(c *fiber.Ctx) error {
+	username := c.Locals("username").(string)
+	accountInfo := h.authService.GetAccountInfo(username)
+	if accountInfo == nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "用户不存在",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"user":    accountInfo,
+	})
+}
+
+// ChangePassword 修改当前用户密码
+func (h *Handler) ChangePassword(c *fiber.Ctx) error {
+	type ChangePasswordRequest struct {
+		OldPassword string `json:"old_password" form:"old_password"`
+		NewPassword string `json:"new_password" form:"new_password"`
+	}
+
+	var req ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "无效的请求格式",
+		})
+	}
+
+	username := c.Locals("username").(string)
+	if err := h.authService.ChangePassword(username, req.OldPassword, req.NewPassword); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "密码修改成功",
+	})
+}
+
+// IntrospectToken 校验令牌并返回其声明
+func (h *Handler) IntrospectToken(c *fiber.Ctx) error {
+	type IntrospectRequest struct {
+		Token string `json:"token" form:"token"`
+	}
+
+	var req IntrospectRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "无效的请求格式",
+		})
+	}
+
+	if req.Token == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "令牌不能为空",
+		})
+	}
+
+	return c.JSON(h.authService.IntrospectToken(req.Token))
+}
+
+// Refresh 使用刷新令牌换取新的访问令牌
+func (h *Handler) Refresh(c *fiber.Ctx) error {
+	type RefreshRequest struct {
+		RefreshToken string `json:"refresh_token" form:"refresh_token"`
+	}
+
+	var req RefreshRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "无效的请求格式",
+		})
+	}
+
+	token, err := h.authService.Refresh(req.RefreshToken)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"token":   token,
 	})
 }
 
 // Logout 注销登录
 func (h *Handler) Logout(c *fiber.Ctx) error {
-	// 由于使用JWT，服务端无状态，客户端删除token即可
+	type LogoutRequest struct {
+		RefreshToken string `json:"refresh_token" form:"refresh_token"`
+	}
+
+	// 访问令牌无状态，客户端删除即可；提供刷新令牌时将其吊销
+	var req LogoutRequest
+	if err := c.BodyParser(&req); err == nil && req.RefreshToken != "" {
+		if err := h.authService.RevokeRefreshToken(req.RefreshToken); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	c.ClearCookie(middleware.TokenCookieName)
 	return c.JSON(fiber.Map{
 		"success": true,
 		"message": "注销成功",
@@ -342,35 +959,76 @@ func (h *Handler) Logout(c *fiber.Ctx) error {
 
 // Redirect 处理短代码重定向
 func (h *Handler) Redirect(c *fiber.Ctx) error {
-	shortCode := c.Params("code")
-	if shortCode == "" {
+	// 复制参数：Fiber 的参数引用请求缓冲区，请求结束后会被复用，而点击计数是异步写入的
+	shortCode := utils.CopyString(c.Params("code"))
+	// 路由参数本身不包含查询串，浏览器也不会发送片段；这里兜底截断，保证 ? 和 # 之后的内容不会被当作短代码
+	if i := strings.IndexAny(shortCode, "?#"); i >= 0 {
+		shortCode = shortCode[:i]
+	}
+	// 路由不会传入空参数，这里兜底处理直接调用或只含空白的短代码，不查询数据库
+	if strings.TrimSpace(shortCode) == "" {
 		return c.Status(404).SendString("短代码不能为空")
 	}
 
 	// 获取URL信息
 	url, err := h.urlService.GetURLByShortCode(shortCode)
 	if err != nil {
-		return c.Status(404).SendString("短链接不存在或已过期")
+		status, message := lookupErrorResponse(err)
+		return c.Status(status).SendString(message)
+	}
+
+	// 按规则选择目标：来源规则优先，其次是按配置时区计算的时间段规则；被拒绝的访问不计入点击
+	destination := url.OriginalURL
+	if rule := url.RefererRuleFor(c.Get(fiber.HeaderReferer)); rule != nil {
+		if rule.Block {
+			return c.Status(fiber.StatusForbidden).SendString("不允许从该来源访问此短链接")
+		}
+		destination = rule.Target
+	} else if rule := url.ScheduleRuleFor(time.Now().In(h.config.Location())); rule != nil {
+		destination = rule.Target
 	}
 
-	// 检查是否激活
-	if !url.IsActive {
-		return c.Status(404).SendString("短链接已禁用")
+	// QUERY_STRING_MODE=keep 时把请求自带的查询参数追加到目标地址
+	if h.config.QueryStringMode == config.QueryStringKeep {
+		destination = appendQuery(destination, string(c.Request().URI().QueryString()))
 	}
-	// 增加点击计数
-	h.urlService.IncrementClickCount(shortCode)
+
+	// 地区限制（未配置GeoIP时不生效），被拒绝的访问不计入点击
+	if h.geoService != nil && !url.GeoRules.IsEmpty() && !url.GeoRules.Allows(h.geoService.LookupCountry(c.IP())) {
+		return c.Status(fiber.StatusForbidden).SendString("该短链接在您所在的地区不可用")
+	}
+
+	// 独立访客数已达上限时拒绝新的访客，已访问过的IP不受影响
+	// 复制IP：设置 PROXY_HEADER 时 c.IP() 引用请求缓冲区，而内存中的访客记录会保留该字符串
+	if !h.urlService.AdmitVisitor(url, utils.CopyString(c.IP())) {
+		return c.Status(fiber.StatusForbidden).SendString("该短链接的访问人数已达上限")
+	}
+
 	// 获取UA信息
-	uaInfo := c.Locals("uaInfo")
-	if uaInfo != nil {
-		ua := uaInfo.(*middleware.UAInfo)
-		// 如果是微信或QQ访问，跳转到拦截页面
-		if ua.NeedsBlock {
+	ua, _ := c.Locals("uaInfo").(*middleware.UAInfo)
+
+	// 增加点击计数（CLICK_COUNTING=off 时不统计，可配置不统计创建者本人的点击）
+	if h.shouldCountClick(c, url.CreatedBy) {
+		h.urlService.IncrementClickCount(shortCode)
+		// 记录访问国家（未配置GeoIP时跳过）
+		if h.geoService != nil {
+			h.geoService.RecordClick(shortCode, c.IP())
+		}
+		// 记录访问设备
+		if ua != nil {
+			h.clickService.RecordClick(shortCode, ua.Device, ua.Browser, ua.OS)
+		}
+	}
+	if ua != nil {
+		// 微信或QQ访问，或开启了 WEBVIEW_INTERSTITIAL 时的其他App内置浏览器，显示拦截页面
+		if ua.NeedsBlock || (h.config.WebViewInterstitial && ua.IsWebView) {
 			return c.Render("block", fiber.Map{
 				"title":       "链接跳转提示",
-				"originalURL": url.OriginalURL,
+				"originalURL": destination,
 				"title_text":  url.Title,
 				"isWeChat":    ua.IsWeChat,
 				"isQQ":        ua.IsQQ,
+				"appName":     ua.InAppName,
 			})
 		}
 	}
@@ -378,18 +1036,170 @@ func (h *Handler) Redirect(c *fiber.Ctx) error {
 	// 记录点击（修复：传递完整的 URL 对象而不是 ID）
 	// h.urlService.RecordClick(url, c.Get("User-Agent"), c.IP(), c.Get("Referer"))
 
-	// 直接重定向
-	return c.Redirect(url.OriginalURL, 302)
+	// 按链接配置的状态码重定向，旧数据缺失时使用全局默认值
+	status := url.RedirectType
+	if !models.IsValidRedirectType(status) {
+		status = h.config.DefaultRedirectCode
+	}
+	return c.Redirect(destination, status)
+}
+
+// appendQuery 把查询串追加到目标地址，保留目标已有的参数和片段（#之后的内容）
+func appendQuery(destination, rawQuery string) string {
+	if rawQuery == "" {
+		return destination
+	}
+	base, fragment, hasFragment := strings.Cut(destination, "#")
+	switch {
+	case !strings.Contains(base, "?"):
+		base += "?" + rawQuery
+	case strings.HasSuffix(base, "?"), strings.HasSuffix(base, "&"):
+		base += rawQuery
+	default:
+		base += "&" + rawQuery
+	}
+	if hasFragment {
+		return base + "#" + fragment
+	}
+	return base
+}
+
+// GetTopURLs 获取点击数最多（by=clicks）或最新创建（by=created）的URL排行榜
+// pending=true 时计入尚未同步的点击数，见 URLService.GetTopURLs
+func (h *Handler) GetTopURLs(c *fiber.Ctx) error {
+	by := c.Query("by", services.TopByClicks)
+	if by != services.TopByClicks && by != services.TopByCreated {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "by 只能是 clicks 或 created",
+		})
+	}
+
+	urls, err := h.urlService.GetTopURLs(c.QueryInt("limit", 10), by, c.Locals("username").(string), c.QueryBool("pending"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "获取排行榜失败",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"by":      by,
+		"urls":    presentURLs(c.Locals("role").(string), urls, h.config.Location()),
+	})
+}
+
+// GetGlobalStats 获取全站统计（仅限管理员）
+func (h *Handler) GetGlobalStats(c *fiber.Ctx) error {
+	stats, err := h.urlService.GetGlobalStats()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "获取统计信息失败",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":        true,
+		"click_counting": h.config.ClickCountingEnabled(),
+		"stats":          stats.Totals,
+		"users":          stats.Users,
+		"top_urls":       presentURLs(c.Locals("role").(string), stats.TopURLs, h.config.Location()),
+	})
+}
+
+// FlushURLClicks 立即写入单个URL缓冲的点击计数（仅限管理员）
+func (h *Handler) FlushURLClicks(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "无效的ID",
+		})
+	}
+
+	clickCount, err := h.urlService.FlushURLClicks(uint(id))
+	if errors.Is(err, services.ErrNotFound) {
+		return c.Status(404).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":     true,
+		"click_count": clickCount,
+	})
+}
+
+// GetPerfStats 获取各路由的延迟统计（仅限管理员）
+func (h *Handler) GetPerfStats(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"success": true,
+		"routes":  h.perfTracker.Snapshot(),
+	})
+}
+
+// PreviewURL 预览短链接的目标地址（公开接口，不跳转也不计入点击）
+func (h *Handler) PreviewURL(c *fiber.Ctx) error {
+	url, err := h.urlService.GetURLDetailsByShortCode(c.Params("code"))
+	if err != nil {
+		status, message := lookupErrorResponse(err)
+		return c.Status(status).JSON(fiber.Map{
+			"error": message,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":      true,
+		"original_url": url.OriginalURL,
+		"title":        url.Title,
+		"description":  url.Description,
+		"is_active":    url.IsActive,
+		"expires_at":   url.ExpiresAt,
+	})
+}
+
+// lookupErrorResponse 将短链接查询错误映射为状态码和提示
+// 过期链接返回410，便于爬虫识别链接已永久失效
+func lookupErrorResponse(err error) (int, string) {
+	switch {
+	case errors.Is(err, services.ErrExpired):
+		return fiber.StatusGone, "短链接已过期"
+	case errors.Is(err, services.ErrDisabled):
+		return fiber.StatusNotFound, "短链接已被创建者禁用"
+	case errors.Is(err, services.ErrNotStarted):
+		return fiber.StatusNotFound, err.Error()
+	default:
+		return fiber.StatusNotFound, "短链接不存在"
+	}
+}
+
+// shouldCountClick 判断本次访问是否计入点击数
+func (h *Handler) shouldCountClick(c *fiber.Ctx, createdBy string) bool {
+	if !h.config.ClickCountingEnabled() {
+		return false
+	}
+	if !h.config.ExcludeCreatorClicks {
+		return true
+	}
+	username, _ := c.Locals("username").(string)
+	return username == "" || username != createdBy
 }
 
 // Index 主页
 func (h *Handler) Index(c *fiber.Ctx) error {
+	// 只部署跳转服务时，首页跳转到配置的主站
+	if h.config.RootRedirectURL != "" {
+		return c.Redirect(h.config.RootRedirectURL, fiber.StatusFound)
+	}
 	return c.Render("index", fiber.Map{
 		"title": "短链接服务",
 	})
 }
 
-// GenerateQRCode 生成二维码（占位符，需要具体实现）
+// GenerateQRCode 生成短链接的二维码
 func (h *Handler) GenerateQRCode(c *fiber.Ctx) error {
 	shortCode := c.Params("code")
 	if shortCode == "" {
@@ -398,12 +1208,84 @@ func (h *Handler) GenerateQRCode(c *fiber.Ctx) error {
 		})
 	}
 
-	// 这里可以集成二维码生成库
-	// 目前返回占位符响应
+	url, err := h.urlService.FindByShortCode(shortCode)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	qrCode, err := qrCodeDataURI(h.fullShortURL(c, url))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "生成二维码失败: " + err.Error(),
+		})
+	}
+
 	return c.JSON(fiber.Map{
 		"success":   true,
 		"shortCode": shortCode,
-		"qrcode":    "data:image/svg+xml;base64," + shortCode, // 占位符
+		"qrcode":    qrCode,
+	})
+}
+
+// maxBatchQRItems 批量获取二维码单次最多的短代码数
+const maxBatchQRItems = 100
+
+// BatchQRCodes 批量获取二维码，返回 短代码→data URI，不存在或无权限的短代码列在 missing 中
+func (h *Handler) BatchQRCodes(c *fiber.Ctx) error {
+	type BatchQRRequest struct {
+		ShortCodes []string `json:"short_codes"`
+	}
+
+	var req BatchQRRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "无效的请求格式",
+		})
+	}
+
+	if len(req.ShortCodes) == 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "请选择要生成二维码的短链接",
+		})
+	}
+	if len(req.ShortCodes) > maxBatchQRItems {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "单次最多获取" + strconv.Itoa(maxBatchQRItems) + "个二维码",
+		})
+	}
+
+	username := c.Locals("username").(string)
+	urls, err := h.urlService.FindByShortCodes(req.ShortCodes, username)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	contents := make(map[string]string, len(urls))
+	for i := range urls {
+		contents[urls[i].ShortCode] = h.fullShortURL(c, &urls[i])
+	}
+	qrCodes, err := qrCodeDataURIs(contents)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "生成二维码失败: " + err.Error(),
+		})
+	}
+
+	missing := []string{}
+	for _, code := range req.ShortCodes {
+		if _, ok := qrCodes[code]; !ok {
+			missing = append(missing, code)
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"success":  true,
+		"qr_codes": qrCodes,
+		"missing":  missing,
 	})
 }
 
@@ -441,6 +1323,86 @@ func (h *Handler) BatchToggleURLs(c *fiber.Ctx) error {
 	})
 }
 
+// BatchUpdateURLs 批量修改标题、过期时间或启用状态
+func (h *Handler) BatchUpdateURLs(c *fiber.Ctx) error {
+	type BatchUpdateRequest struct {
+		IDs       []uint     `json:"ids"`
+		Title     *string    `json:"title"`
+		ExpiresAt *time.Time `json:"expires_at"` // 零值表示取消过期时间
+		IsActive  *bool      `json:"is_active"`
+	}
+
+	var req BatchUpdateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "无效的请求格式",
+		})
+	}
+
+	if len(req.IDs) == 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "请选择要操作的URL",
+		})
+	}
+
+	username := c.Locals("username").(string)
+	updated, err := h.urlService.BatchUpdate(req.IDs, services.UpdatePatch{
+		Title:     req.Title,
+		ExpiresAt: req.ExpiresAt,
+		IsActive:  req.IsActive,
+	}, username)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "批量操作完成",
+		"updated": updated,
+	})
+}
+
+// BatchTagURLs 批量添加或移除标签
+func (h *Handler) BatchTagURLs(c *fiber.Ctx) error {
+	type BatchTagRequest struct {
+		IDs  []uint   `json:"ids"`
+		Tags []string `json:"tags"`
+		Mode string   `json:"mode"` // add（默认）或 remove
+	}
+
+	var req BatchTagRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "无效的请求格式",
+		})
+	}
+
+	if len(req.IDs) == 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "请选择要操作的URL",
+		})
+	}
+	if req.Mode == "" {
+		req.Mode = services.TagModeAdd
+	}
+
+	username := c.Locals("username").(string)
+	updated, err := h.urlService.BatchTagURLs(req.IDs, req.Tags, req.Mode, username)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "批量操作完成",
+		"updated": updated,
+	})
+}
+
 // GetClickStats 获取点击统计
 // func (h *Handler) GetClickStats(c *fiber.Ctx) error {
 // 	shortCode := c.Params("code")
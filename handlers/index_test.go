@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/justseemore/surl/config"
+	"github.com/justseemore/surl/services"
+)
+
+func TestIndexRendersByDefault(t *testing.T) {
+	env := newTestEnv(t, nil)
+	resp := env.get("/", "")
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 || !strings.Contains(string(body), "短链接服务") {
+		t.Fatalf("GET / = %d %.100q, want the index page", resp.StatusCode, body)
+	}
+	if loc := resp.Header.Get("Location"); loc != "" {
+		t.Fatalf("Location = %q, want none", loc)
+	}
+}
+
+func TestIndexRootRedirect(t *testing.T) {
+	const site = "https://www.example.com/"
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.RootRedirectURL = site })
+	resp := env.get("/", "")
+	if resp.StatusCode != 302 || resp.Header.Get("Location") != site {
+		t.Fatalf("GET / = %d Location %q, want 302 to %s", resp.StatusCode, resp.Header.Get("Location"), site)
+	}
+
+	// 短链接跳转不受影响
+	url := env.create("alice", "https://example.com/still-works", services.URLOptions{})
+	resp = env.get("/"+url.ShortCode, "")
+	if resp.StatusCode != 302 || resp.Header.Get("Location") != url.OriginalURL {
+		t.Fatalf("GET /%s = %d Location %q, want the link target", url.ShortCode, resp.StatusCode, resp.Header.Get("Location"))
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/justseemore/surl/config"
+	"github.com/justseemore/surl/services"
+)
+
+type lookupResponse struct {
+	ShortCode string `json:"short_code"`
+	ShortURL  string `json:"short_url"`
+}
+
+func lookupPath(target string) string {
+	return "/api/urls/lookup?url=" + url.QueryEscape(target)
+}
+
+func TestLookupExistingDestination(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.AllowDuplicates = false })
+	created := env.create("alice", "https://example.com/Page?a=1", services.URLOptions{})
+
+	// 查询前按创建时同样的规则规范化（协议和主机名小写）
+	for _, target := range []string{"https://example.com/Page?a=1", "HTTPS://EXAMPLE.com/Page?a=1"} {
+		var resp lookupResponse
+		env.do("GET", lookupPath(target), env.token("alice"), nil, 200, &resp)
+		if resp.ShortCode != created.ShortCode || resp.ShortURL == "" {
+			t.Errorf("lookup %q = %+v, want %s", target, resp, created.ShortCode)
+		}
+	}
+	// 不允许重复URL时目标全局唯一，其他用户也能查到
+	var resp lookupResponse
+	env.do("GET", lookupPath("https://example.com/Page?a=1"), env.token("bob"), nil, 200, &resp)
+	if resp.ShortCode != created.ShortCode {
+		t.Errorf("bob lookup = %+v", resp)
+	}
+
+	env.do("GET", lookupPath("https://example.com/page?a=1"), env.token("alice"), nil, 404, nil)
+	env.do("GET", lookupPath("https://unknown.example/"), env.token("alice"), nil, 404, nil)
+	env.do("GET", lookupPath("javascript:alert(1)"), env.token("alice"), nil, 400, nil)
+	env.do("GET", "/api/urls/lookup", env.token("alice"), nil, 400, nil)
+}
+
+func TestLookupScopedWhenDuplicatesAllowed(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.AllowDuplicates = true })
+	created := env.create("alice", "https://example.com/shared", services.URLOptions{})
+
+	env.do("GET", lookupPath("https://example.com/shared"), env.token("bob"), nil, 404, nil)
+	for _, user := range []string{"alice", "admin"} {
+		var resp lookupResponse
+		env.do("GET", lookupPath("https://example.com/shared"), env.token(user), nil, 200, &resp)
+		if resp.ShortCode != created.ShortCode {
+			t.Errorf("%s lookup = %+v, want %s", user, resp, created.ShortCode)
+		}
+	}
+}
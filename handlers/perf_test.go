@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/justseemore/surl/middleware"
+	"github.com/justseemore/surl/services"
+)
+
+func TestPerfEndpointReportsRequests(t *testing.T) {
+	env := newTestEnv(t, nil)
+	url := env.create("alice", "https://example.com/perf", services.URLOptions{})
+	for i := 0; i < 3; i++ {
+		env.get("/"+url.ShortCode, "")
+	}
+	env.get("/api/urls", env.token("alice"))
+
+	// 只有管理员可以查看
+	env.do("GET", "/api/admin/perf", env.token("alice"), nil, 403, nil)
+
+	var resp struct {
+		Routes []middleware.PerfStat `json:"routes"`
+	}
+	env.do("GET", "/api/admin/perf", env.token("admin"), nil, 200, &resp)
+	counts := make(map[string]int64)
+	for _, stat := range resp.Routes {
+		counts[stat.Route] = stat.Count
+	}
+	if counts["GET /:code"] != 3 {
+		t.Errorf("GET /:code count = %d, want 3: %+v", counts["GET /:code"], resp.Routes)
+	}
+	if counts["GET /api/urls"] != 1 {
+		t.Errorf("GET /api/urls count = %d, want 1: %+v", counts["GET /api/urls"], resp.Routes)
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/justseemore/surl/models"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// qrCodeSize 二维码图片边长（像素）
+const qrCodeSize = 256
+
+// qrCodeDataURI 将内容编码为PNG二维码的data URI
+func qrCodeDataURI(content string) (string, error) {
+	png, err := qrcode.Encode(content, qrcode.Medium, qrCodeSize)
+	if err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(png), nil
+}
+
+// fullShortURL 按当前请求的协议生成完整短链接
+// 未启用TLS时返回http链接；反向代理后由 X-Forwarded-Proto 决定
+func (h *Handler) fullShortURL(c *fiber.Ctx, url *models.URL) string {
+	return url.GetFullURL(c.Protocol(), h.config.CustomDomain)
+}
+
+// qrCodeWorkers 批量生成二维码时的并发数
+const qrCodeWorkers = 4
+
+// qrCodeDataURIs 并发生成多个二维码，contents 为 键→内容，返回 键→data URI
+// 任一二维码生成失败时返回该错误
+func qrCodeDataURIs(contents map[string]string) (map[string]string, error) {
+	results := make(map[string]string, len(contents))
+	var (
+		mutex    sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+	slots := make(chan struct{}, qrCodeWorkers)
+	for key, content := range contents {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(key, content string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			dataURI, err := qrCodeDataURI(content)
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("%s: %v", key, err)
+				}
+				return
+			}
+			results[key] = dataURI
+		}(key, content)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return results, nil
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image/png"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/justseemore/surl/models"
+	"github.com/justseemore/surl/services"
+)
+
+type batchQRResponse struct {
+	QRCodes map[string]string `json:"qr_codes"`
+	Missing []string          `json:"missing"`
+}
+
+// decodeQRDataURI 检查 data URI 是可解码的PNG图片
+func decodeQRDataURI(t *testing.T, dataURI string) {
+	t.Helper()
+	const prefix = "data:image/png;base64,"
+	if !strings.HasPrefix(dataURI, prefix) {
+		t.Fatalf("not a PNG data URI: %.40s", dataURI)
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(dataURI, prefix))
+	if err != nil {
+		t.Fatalf("invalid base64: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("invalid PNG: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != qrCodeSize || b.Dy() != qrCodeSize {
+		t.Fatalf("image size = %dx%d, want %d", b.Dx(), b.Dy(), qrCodeSize)
+	}
+}
+
+func TestBatchQRCodes(t *testing.T) {
+	env := newTestEnv(t, nil)
+	var own []*models.URL
+	for _, path := range []string{"a", "b", "c"} {
+		own = append(own, env.create("alice", "https://example.com/qr/"+path, services.URLOptions{}))
+	}
+	bobs := env.create("bob", "https://example.com/qr/bob", services.URLOptions{})
+
+	codes := []string{own[0].ShortCode, own[1].ShortCode, own[2].ShortCode, bobs.ShortCode, "nosuchcode"}
+	var got batchQRResponse
+	env.do("POST", "/api/urls/batch/qr", env.token("alice"), map[string]interface{}{"short_codes": codes}, 200, &got)
+
+	if len(got.QRCodes) != len(own) {
+		t.Fatalf("got %d QR codes, want %d", len(got.QRCodes), len(own))
+	}
+	for _, url := range own {
+		dataURI, ok := got.QRCodes[url.ShortCode]
+		if !ok {
+			t.Fatalf("no QR code for %s", url.ShortCode)
+		}
+		decodeQRDataURI(t, dataURI)
+		// 每个二维码编码的是对应的完整短链接
+		want, err := qrCodeDataURI(url.GetFullURL("http", env.cfg.CustomDomain))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dataURI != want {
+			t.Errorf("QR code for %s does not encode its short URL", url.ShortCode)
+		}
+	}
+	// 其他用户的链接和不存在的短代码列在 missing 中
+	if want := []string{bobs.ShortCode, "nosuchcode"}; !reflect.DeepEqual(got.Missing, want) {
+		t.Fatalf("missing = %v, want %v", got.Missing, want)
+	}
+
+	// 管理员可以获取所有用户的二维码
+	var admin batchQRResponse
+	env.do("POST", "/api/urls/batch/qr", env.token("admin"), map[string]interface{}{"short_codes": codes[2:4]}, 200, &admin)
+	if len(admin.QRCodes) != 2 || len(admin.Missing) != 0 {
+		t.Fatalf("admin: %d QR codes, missing %v; want 2 and none", len(admin.QRCodes), admin.Missing)
+	}
+}
+
+func TestBatchQRCodesLimits(t *testing.T) {
+	env := newTestEnv(t, nil)
+	token := env.token("alice")
+	env.do("POST", "/api/urls/batch/qr", token, map[string]interface{}{"short_codes": []string{}}, 400, nil)
+
+	tooMany := make([]string, maxBatchQRItems+1)
+	for i := range tooMany {
+		tooMany[i] = "code"
+	}
+	env.do("POST", "/api/urls/batch/qr", token, map[string]interface{}{"short_codes": tooMany}, 400, nil)
+	env.do("POST", "/api/urls/batch/qr", token, map[string]interface{}{"short_codes": tooMany[1:]}, 200, nil)
+}
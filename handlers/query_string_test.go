@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/justseemore/surl/config"
+	"github.com/justseemore/surl/services"
+)
+
+func TestAppendQuery(t *testing.T) {
+	cases := []struct {
+		destination, query, want string
+	}{
+		{"https://example.com/a", "", "https://example.com/a"},
+		{"https://example.com/a", "x=1", "https://example.com/a?x=1"},
+		{"https://example.com/a?y=2", "x=1", "https://example.com/a?y=2&x=1"},
+		{"https://example.com/a?", "x=1", "https://example.com/a?x=1"},
+		{"https://example.com/a?y=2&", "x=1", "https://example.com/a?y=2&x=1"},
+		{"https://example.com/a#top", "x=1", "https://example.com/a?x=1#top"},
+		{"https://example.com/a?y=2#top", "x=1&z=3", "https://example.com/a?y=2&x=1&z=3#top"},
+	}
+	for _, tc := range cases {
+		if got := appendQuery(tc.destination, tc.query); got != tc.want {
+			t.Errorf("appendQuery(%q, %q) = %q, want %q", tc.destination, tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestRedirectQueryStringModes(t *testing.T) {
+	for _, mode := range []string{config.QueryStringStrip, config.QueryStringKeep} {
+		env := newTestEnv(t, func(cfg *config.Config) { cfg.QueryStringMode = mode })
+		url := env.create("alice", "https://example.com/landing?ref=short", services.URLOptions{})
+
+		cases := []struct {
+			suffix, keep string
+		}{
+			{"", "https://example.com/landing?ref=short"},
+			{"?", "https://example.com/landing?ref=short"},
+			{"?utm_source=mail", "https://example.com/landing?ref=short&utm_source=mail"},
+			{"/?utm_source=mail&x=1", "https://example.com/landing?ref=short&utm_source=mail&x=1"},
+			{"#section", "https://example.com/landing?ref=short"},
+			{"?utm_source=mail#section", "https://example.com/landing?ref=short&utm_source=mail"},
+		}
+		for _, tc := range cases {
+			want := url.OriginalURL
+			if mode == config.QueryStringKeep {
+				want = tc.keep
+			}
+			resp := env.get("/"+url.ShortCode+tc.suffix, "")
+			if resp.StatusCode != 302 || resp.Header.Get("Location") != want {
+				t.Errorf("%s: GET /%s%s = %d %q, want %q", mode, url.ShortCode, tc.suffix, resp.StatusCode, resp.Header.Get("Location"), want)
+			}
+		}
+	}
+}
+
+func TestRedirectCodeExcludesEncodedQueryAndFragment(t *testing.T) {
+	env := newTestEnv(t, nil)
+	url := env.create("alice", "https://example.com/encoded", services.URLOptions{})
+
+	// UnescapePath 时 %3F 和 %23 会作为 ? 和 # 出现在路由参数中，短代码在这之前截断
+	app := fiber.New(fiber.Config{UnescapePath: true})
+	app.Get("/:code", env.handler.Redirect)
+	for _, suffix := range []string{"%3Fx=1", "%23frag", "%3Fx=1%23frag"} {
+		resp, err := app.Test(httptest.NewRequest("GET", "/"+url.ShortCode+suffix, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != 302 || resp.Header.Get("Location") != url.OriginalURL {
+			t.Errorf("GET /%s%s = %d %q, want %q", url.ShortCode, suffix, resp.StatusCode, resp.Header.Get("Location"), url.OriginalURL)
+		}
+	}
+}
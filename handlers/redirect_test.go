@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/justseemore/surl/config"
+	"github.com/justseemore/surl/middleware"
+	"github.com/justseemore/surl/models"
+	"github.com/justseemore/surl/services"
+)
+
+func TestRedirectExcludesCreatorClicks(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.ExcludeCreatorClicks = true })
+	url := env.create("alice", "https://example.com/creator", services.URLOptions{})
+	path := "/" + url.ShortCode
+
+	// 创建者通过请求头或登录Cookie访问时不计数
+	if resp := env.get(path, env.token("alice")); resp.StatusCode != 302 {
+		t.Fatalf("creator redirect status = %d, want 302", resp.StatusCode)
+	}
+	if resp := env.get(path, "", "Cookie", middleware.TokenCookieName+"="+env.token("alice")); resp.StatusCode != 302 {
+		t.Fatalf("creator cookie redirect status = %d, want 302", resp.StatusCode)
+	}
+	settle()
+	if got := env.clickCount(url); got != 0 {
+		t.Fatalf("creator clicks counted: %d", got)
+	}
+
+	// 匿名访问、其他用户和无效令牌都计数
+	env.get(path, "")
+	env.get(path, env.token("bob"))
+	env.get(path, "invalid-token")
+	env.waitClicks(url, 3)
+}
+
+func TestRedirectCountsCreatorClicksByDefault(t *testing.T) {
+	env := newTestEnv(t, nil)
+	url := env.create("alice", "https://example.com/default", services.URLOptions{})
+
+	env.get("/"+url.ShortCode, env.token("alice"))
+	env.waitClicks(url, 1)
+}
+
+func TestRedirectBeforeStartsAt(t *testing.T) {
+	env := newTestEnv(t, nil)
+	startsAt := time.Now().Add(100 * time.Millisecond)
+	url := env.create("alice", "https://example.com/launch", services.URLOptions{StartsAt: &startsAt})
+
+	resp := env.get("/"+url.ShortCode, "")
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 404 || string(body) != "链接尚未生效" {
+		t.Fatalf("before StartsAt: %d %q, want 404 链接尚未生效", resp.StatusCode, body)
+	}
+	settle()
+	if got := env.clickCount(url); got != 0 {
+		t.Fatalf("click counted before StartsAt: %d", got)
+	}
+
+	time.Sleep(time.Until(startsAt))
+	if resp := env.get("/"+url.ShortCode, ""); resp.StatusCode != 302 {
+		t.Fatalf("after StartsAt: status = %d, want 302", resp.StatusCode)
+	}
+}
+
+func TestRedirectDistinguishesFailures(t *testing.T) {
+	env := newTestEnv(t, nil)
+	disabled := env.create("alice", "https://example.com/off", services.URLOptions{})
+	if err := env.urls.ToggleURLStatus(disabled.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	expired := env.create("alice", "https://example.com/old", services.URLOptions{})
+	past := time.Now().Add(-time.Minute)
+	if _, err := env.urls.UpdateURL(expired.ID, "", "", &past, nil, "alice", services.URLOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	active := env.create("alice", "https://example.com/on", services.URLOptions{})
+
+	cases := []struct {
+		code   string
+		status int
+		body   string
+	}{
+		{active.ShortCode, 302, ""},
+		{expired.ShortCode, 410, "短链接已过期"},
+		{disabled.ShortCode, 404, "短链接已被创建者禁用"},
+		{"missing1", 404, "短链接不存在"},
+	}
+	for _, tc := range cases {
+		resp := env.get("/"+tc.code, "")
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != tc.status || (tc.body != "" && string(body) != tc.body) {
+			t.Errorf("GET /%s = %d %q, want %d %q", tc.code, resp.StatusCode, body, tc.status, tc.body)
+		}
+	}
+	settle()
+	if got := env.clickCount(disabled) + env.clickCount(expired); got != 0 {
+		t.Errorf("failed redirects counted %d clicks", got)
+	}
+}
+
+func TestRedirectAndPreviewWithLargeDescription(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.MaxDescriptionLength = 0 })
+	description := strings.Repeat("d", 20000)
+	url, err := env.urls.CreateShortURL("https://example.com/big", "", description, "", nil, "alice", true, services.URLOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := env.get("/"+url.ShortCode, "")
+	if resp.StatusCode != 302 || resp.Header.Get("Location") != "https://example.com/big" {
+		t.Fatalf("redirect = %d %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	var preview struct {
+		Description string `json:"description"`
+	}
+	env.do("GET", "/api/preview/"+url.ShortCode, "", nil, 200, &preview)
+	if preview.Description != description {
+		t.Fatalf("preview description has %d bytes, want %d", len(preview.Description), len(description))
+	}
+}
+
+func TestRedirectByReferer(t *testing.T) {
+	env := newTestEnv(t, nil)
+	url := env.create("alice", "https://example.com/default", services.URLOptions{
+		RefererRules: []models.RefererRule{
+			{Match: "hotlink.example", Block: true},
+			{Match: "t.co", Target: "https://example.com/social"},
+			{Match: "facebook.com", Target: "https://example.com/social"},
+			{Match: "none", Target: "https://example.com/direct"},
+		},
+	})
+	path := "/" + url.ShortCode
+
+	cases := []struct {
+		referer  string
+		status   int
+		location string
+	}{
+		{"https://t.co/abc", 302, "https://example.com/social"},
+		{"https://m.facebook.com/story", 302, "https://example.com/social"},
+		{"https://T.CO/upper", 302, "https://example.com/social"},
+		{"", 302, "https://example.com/direct"},
+		{"https://news.example/article", 302, "https://example.com/default"},
+		{"https://notfacebook.com/", 302, "https://example.com/default"},
+		{"https://img.hotlink.example/page", 403, ""},
+	}
+	for _, tc := range cases {
+		var resp *http.Response
+		if tc.referer == "" {
+			resp = env.get(path, "")
+		} else {
+			resp = env.get(path, "", "Referer", tc.referer)
+		}
+		if resp.StatusCode != tc.status || resp.Header.Get("Location") != tc.location {
+			t.Errorf("Referer %q: %d %q, want %d %q", tc.referer, resp.StatusCode, resp.Header.Get("Location"), tc.status, tc.location)
+		}
+	}
+	// 被拒绝的访问不计入点击
+	env.waitClicks(url, 6)
+	settle()
+	if got := env.clickCount(url); got != 6 {
+		t.Fatalf("click count = %d, want 6", got)
+	}
+}
+
+func TestRedirectWithoutRefererRules(t *testing.T) {
+	env := newTestEnv(t, nil)
+	url := env.create("alice", "https://example.com/single", services.URLOptions{})
+	for _, referer := range []string{"https://t.co/x", "https://anything.example/"} {
+		resp := env.get("/"+url.ShortCode, "", "Referer", referer)
+		if resp.StatusCode != 302 || resp.Header.Get("Location") != "https://example.com/single" {
+			t.Errorf("Referer %q: %d %q", referer, resp.StatusCode, resp.Header.Get("Location"))
+		}
+	}
+}
+
+func TestRedirectBySchedule(t *testing.T) {
+	t.Setenv("TIMEZONE", "Asia/Tokyo")
+	env := newTestEnv(t, nil)
+	now := time.Now().In(env.cfg.Location())
+	clock := func(d time.Duration) string { return now.Add(d).Format("15:04") }
+
+	inside := env.create("alice", "https://example.com/default-a", services.URLOptions{
+		ScheduleRules: []models.ScheduleRule{{Start: clock(-time.Hour), End: clock(time.Hour), Target: "https://example.com/open"}},
+	})
+	outside := env.create("alice", "https://example.com/default-b", services.URLOptions{
+		ScheduleRules: []models.ScheduleRule{{Start: clock(2 * time.Hour), End: clock(3 * time.Hour), Target: "https://example.com/later"}},
+	})
+	// 今天以外的星期不生效
+	otherDay := env.create("alice", "https://example.com/default-c", services.URLOptions{
+		ScheduleRules: []models.ScheduleRule{{Days: []time.Weekday{(now.Weekday() + 3) % 7}, Start: clock(-time.Hour), End: clock(time.Hour), Target: "https://example.com/weekday"}},
+	})
+
+	cases := map[string]string{
+		inside.ShortCode:   "https://example.com/open",
+		outside.ShortCode:  "https://example.com/default-b",
+		otherDay.ShortCode: "https://example.com/default-c",
+	}
+	for code, want := range cases {
+		resp := env.get("/"+code, "")
+		if resp.StatusCode != 302 || resp.Header.Get("Location") != want {
+			t.Errorf("GET /%s = %d %q, want %q", code, resp.StatusCode, resp.Header.Get("Location"), want)
+		}
+	}
+}
+
+func TestRedirectEmptyCodeGuard(t *testing.T) {
+	env := newTestEnv(t, nil)
+	// 可选参数让空短代码直接到达 Redirect，验证兜底检查；UnescapePath 让 %20 以空格传入
+	app := fiber.New(fiber.Config{UnescapePath: true})
+	app.Get("/:code?", env.handler.Redirect)
+
+	for _, path := range []string{"/", "/%20"} {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != 404 || string(body) != "短代码不能为空" {
+			t.Errorf("GET %s = %d %q, want 404 短代码不能为空", path, resp.StatusCode, body)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/justseemore/surl/services"
+)
+
+type statsResponse struct {
+	Stats services.URLStats `json:"stats"`
+}
+
+func TestStatsTodayClicksAfterRedirect(t *testing.T) {
+	env := newTestEnv(t, nil)
+	url := env.create("alice", "https://example.com/stats-today", services.URLOptions{})
+	token := env.token("alice")
+
+	var before statsResponse
+	env.do("GET", "/api/stats", token, nil, 200, &before)
+
+	env.get("/"+url.ShortCode, "")
+	env.clicks.SyncClicks()
+
+	var after statsResponse
+	env.do("GET", "/api/stats", token, nil, 200, &after)
+	if after.Stats.TodayClicks != before.Stats.TodayClicks+1 {
+		t.Fatalf("today_clicks = %d after a click, want %d", after.Stats.TodayClicks, before.Stats.TodayClicks+1)
+	}
+}
+
+func TestStatsScopedByRole(t *testing.T) {
+	env := newTestEnv(t, nil)
+	a := env.create("alice", "https://example.com/alice", services.URLOptions{})
+	env.create("bob", "https://example.com/bob-1", services.URLOptions{})
+	b := env.create("bob", "https://example.com/bob-2", services.URLOptions{})
+	env.get("/"+a.ShortCode, "")
+	env.get("/"+b.ShortCode, "")
+	env.get("/"+b.ShortCode, "")
+	env.waitClicks(a, 1)
+	env.waitClicks(b, 2)
+	env.urls.SyncClickCounts()
+
+	stats := func(username string) (services.URLStats, bool) {
+		var resp struct {
+			Stats services.URLStats `json:"stats"`
+			Cache interface{}       `json:"cache"`
+		}
+		env.do("GET", "/api/stats", env.token(username), nil, 200, &resp)
+		return resp.Stats, resp.Cache != nil
+	}
+	cases := map[string]services.URLStats{
+		"alice": {TotalURLs: 1, ActiveURLs: 1, TotalClicks: 1, TodayClicks: 1},
+		"bob":   {TotalURLs: 2, ActiveURLs: 2, TotalClicks: 2, TodayClicks: 2},
+		"admin": {}, // /api/stats 只统计自己的链接，全站统计见 /api/stats/global
+	}
+	env.clicks.SyncClicks()
+	for user, want := range cases {
+		got, hasCache := stats(user)
+		if got != want {
+			t.Errorf("%s: stats = %+v, want %+v", user, got, want)
+		}
+		// 缓存统计只对管理员展示
+		if hasCache != (user == "admin") {
+			t.Errorf("%s: cache stats shown = %v", user, hasCache)
+		}
+	}
+
+	var global struct {
+		Totals services.URLStats `json:"stats"`
+	}
+	env.do("GET", "/api/stats/global", env.token("admin"), nil, 200, &global)
+	if want := (services.URLStats{TotalURLs: 3, ActiveURLs: 3, TotalClicks: 3, TodayClicks: 3}); global.Totals != want {
+		t.Errorf("global totals = %+v, want %+v", global.Totals, want)
+	}
+	env.do("GET", "/api/stats/global", env.token("alice"), nil, 403, nil)
+}
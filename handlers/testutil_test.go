@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/template/html/v2"
+	"github.com/justseemore/surl/cache"
+	"github.com/justseemore/surl/config"
+	"github.com/justseemore/surl/middleware"
+	"github.com/justseemore/surl/models"
+	"github.com/justseemore/surl/services"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testAccounts 测试使用的账户，admin 为管理员，alice 和 bob 为普通用户
+const testAccounts = "admin:admin123:admin,alice:alicepass:user,bob:bobpass1:user"
+
+// testEnv 使用内存SQLite和内存缓存的完整处理器
+type testEnv struct {
+	t       *testing.T
+	app     *fiber.App
+	db      *gorm.DB
+	cfg     *config.Config
+	cache   *cache.Manager
+	urls    *services.URLService
+	auth    *services.AuthService
+	clicks  *services.ClickService
+	handler *Handler
+}
+
+// newTestEnv 创建测试环境，configure 在创建服务之前修改默认配置，可以为nil
+// 路由与 main.go 的 setupRoutes 一致，但不挂载限流、幂等和请求体大小限制中间件
+func newTestEnv(t *testing.T, configure func(cfg *config.Config)) *testEnv {
+	t.Helper()
+	t.Setenv("ACCOUNTS", testAccounts)
+	cfg := config.Load()
+	cfg.BlockPrivateHosts = false // 测试不访问网络
+	if configure != nil {
+		configure(cfg)
+	}
+	middleware.SetJWTSecret(cfg.JWTSecret)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger:  logger.Default.LogMode(logger.Silent),
+		NowFunc: models.Now,
+	})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("sqlite handle: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1) // 每个 :memory: 连接都是独立的数据库
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&models.URL{}, &models.CountryClick{}, &models.Account{}, &models.Click{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	cacheManager := cache.NewCacheManager("", "", 0, 60, 1000, "")
+	urlService := services.NewURLService(cacheManager, db, cfg, nil)
+	authService := services.NewAuthService(cfg, cacheManager, db)
+	if err := authService.SeedAccounts(); err != nil {
+		t.Fatalf("seed accounts: %v", err)
+	}
+	clickService := services.NewClickService(db, cfg.ClickSampleRate)
+	perfTracker := middleware.NewPerfTracker()
+	handler := NewHandler(urlService, authService, nil, clickService, perfTracker, cfg)
+
+	app := fiber.New(fiber.Config{Views: html.New("../templates", ".html")})
+	app.Use(perfTracker.Middleware())
+	app.Use(middleware.UADetector())
+	registerRoutes(app, handler)
+
+	return &testEnv{
+		t:       t,
+		app:     app,
+		db:      db,
+		cfg:     cfg,
+		cache:   cacheManager,
+		urls:    urlService,
+		auth:    authService,
+		clicks:  clickService,
+		handler: handler,
+	}
+}
+
+func registerRoutes(app *fiber.App, h *Handler) {
+	app.Get("/", h.Index)
+	app.Post("/api/login", h.Login)
+	app.Post("/api/refresh", h.Refresh)
+	app.Get("/api/preview/:code", h.PreviewURL)
+
+	api := app.Group("/api")
+	api.Use(middleware.JWTMiddleware())
+	api.Post("/create", h.CreateShortURL)
+	api.Get("/urls", h.GetURLs)
+	api.Get("/urls/trash", h.GetDeletedURLs)
+	api.Get("/urls/top", h.GetTopURLs)
+	api.Get("/urls/:id<int>", h.GetURLByID)
+	api.Post("/urls/:id<int>/update", h.UpdateURL)
+	api.Post("/urls/:id<int>/delete", h.DeleteURL)
+	api.Post("/urls/:id<int>/restore", h.RestoreURL)
+	api.Get("/urls/:id<int>/ua-breakdown", h.GetUABreakdown)
+	api.Get("/urls/lookup", h.LookupURL)
+	api.Get("/urls/code/:code/destination", h.GetDestination)
+	api.Post("/urls/batch/create", h.BatchCreateURLs)
+	api.Post("/urls/batch/delete", h.BatchDeleteURLs)
+	api.Post("/urls/batch/toggle", h.BatchToggleURLs)
+	api.Post("/urls/batch/tags", h.BatchTagURLs)
+	api.Post("/urls/batch/update", h.BatchUpdateURLs)
+	api.Post("/urls/batch/qr", h.BatchQRCodes)
+	api.Get("/stats", h.GetStats)
+	api.Get("/stats/global", middleware.AdminMiddleware(), h.GetGlobalStats)
+	api.Post("/admin/urls/:id<int>/flush-clicks", middleware.AdminMiddleware(), h.FlushURLClicks)
+	api.Get("/admin/perf", middleware.AdminMiddleware(), h.GetPerfStats)
+	api.Post("/token/introspect", h.IntrospectToken)
+	api.Get("/qrcode/:code", h.GenerateQRCode)
+	api.Post("/logout", h.Logout)
+
+	app.Get("/:code", middleware.OptionalJWTMiddleware(), h.Redirect)
+}
+
+// token 为测试账户签发访问令牌
+func (e *testEnv) token(username string) string {
+	e.t.Helper()
+	role := "user"
+	if username == "admin" {
+		role = "admin"
+	}
+	token, err := e.auth.GenerateToken(&services.AuthUser{Username: username, Role: role})
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return token
+}
+
+// request 发送请求，body 不为nil时编码为JSON；headers 为可选的键值对
+func (e *testEnv) request(method, path, token string, body interface{}, headers ...string) *http.Response {
+	e.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			e.t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		if headers[i] == "Host" {
+			req.Host = headers[i+1]
+			continue
+		}
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	resp, err := e.app.Test(req, -1)
+	if err != nil {
+		e.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	return resp
+}
+
+// get 发送不带请求体的GET请求
+func (e *testEnv) get(path, token string, headers ...string) *http.Response {
+	e.t.Helper()
+	return e.request("GET", path, token, nil, headers...)
+}
+
+// do 发送请求并检查状态码，响应为JSON时解码到 out（可以为nil）
+func (e *testEnv) do(method, path, token string, body interface{}, wantStatus int, out interface{}) {
+	e.t.Helper()
+	resp := e.request(method, path, token, body)
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != wantStatus {
+		e.t.Fatalf("%s %s: status = %d, want %d: %s", method, path, resp.StatusCode, wantStatus, data)
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			e.t.Fatalf("%s %s: decode %s: %v", method, path, data, err)
+		}
+	}
+}
+
+// create 以指定用户创建短链接
+func (e *testEnv) create(username, originalURL string, opts services.URLOptions) *models.URL {
+	e.t.Helper()
+	url, err := e.urls.CreateShortURL(originalURL, "", "", "", nil, username, true, opts)
+	if err != nil {
+		e.t.Fatalf("create %s: %v", originalURL, err)
+	}
+	return url
+}
+
+// clickCount 数据库中的点击数加上尚未同步的点击数
+func (e *testEnv) clickCount(url *models.URL) int64 {
+	e.t.Helper()
+	var stored models.URL
+	if err := e.db.First(&stored, url.ID).Error; err != nil {
+		e.t.Fatal(err)
+	}
+	return stored.ClickCount + e.cache.GetPendingClicks(url.ShortCode)
+}
+
+// waitClicks 等待异步的点击计数达到 want
+func (e *testEnv) waitClicks(url *models.URL, want int64) {
+	e.t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for e.clickCount(url) < want {
+		if time.Now().After(deadline) {
+			e.t.Fatalf("click count for %s = %d, want %d", url.ShortCode, e.clickCount(url), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// settle 等待异步的点击计数写入缓存，用于确认没有计数的场景
+func settle() {
+	time.Sleep(20 * time.Millisecond)
+}
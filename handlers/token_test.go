@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/justseemore/surl/services"
+)
+
+func TestIntrospectValidToken(t *testing.T) {
+	env := newTestEnv(t, nil)
+	// 自省的令牌不必是调用者自己的
+	target := env.token("alice")
+
+	var got services.TokenIntrospection
+	env.do("POST", "/api/token/introspect", env.token("bob"), map[string]string{"token": target}, 200, &got)
+	if !got.Active || got.Username != "alice" || got.Role != "user" || got.TokenType != "access" {
+		t.Fatalf("introspection = %+v", got)
+	}
+	if got.Exp <= time.Now().Unix() || got.Iat > time.Now().Unix() || got.Iat == 0 {
+		t.Fatalf("exp/iat = %d/%d", got.Exp, got.Iat)
+	}
+}
+
+func TestIntrospectExpiredToken(t *testing.T) {
+	env := newTestEnv(t, nil)
+	expired, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"username": "alice",
+		"role":     "user",
+		"exp":      time.Now().Add(-time.Minute).Unix(),
+		"iat":      time.Now().Add(-25 * time.Hour).Unix(),
+	}).SignedString([]byte(env.cfg.JWTSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, token := range map[string]string{
+		"expired":  expired,
+		"tampered": env.token("alice") + "x",
+		"garbage":  "not-a-jwt",
+	} {
+		var got map[string]interface{}
+		env.do("POST", "/api/token/introspect", env.token("bob"), map[string]string{"token": token}, 200, &got)
+		if active, _ := got["active"].(bool); active || len(got) != 1 {
+			t.Errorf("%s token: introspection = %v, want only active=false", name, got)
+		}
+	}
+}
+
+func TestIntrospectRequiresToken(t *testing.T) {
+	env := newTestEnv(t, nil)
+	env.do("POST", "/api/token/introspect", env.token("bob"), map[string]string{}, 400, nil)
+	env.do("POST", "/api/token/introspect", "", map[string]string{"token": env.token("bob")}, 401, nil)
+}
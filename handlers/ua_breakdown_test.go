@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/justseemore/surl/config"
+	"github.com/justseemore/surl/models"
+	"github.com/justseemore/surl/services"
+)
+
+func TestUABreakdownMatchesClicks(t *testing.T) {
+	const (
+		iPhoneUA  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1"
+		androidUA = "Mozilla/5.0 (Linux; Android 13; Pixel 7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36"
+		windowsUA = "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:121.0) Gecko/20100101 Firefox/121.0"
+	)
+	env := newTestEnv(t, nil)
+	url := env.create("alice", "https://example.com/ua", services.URLOptions{})
+	other := env.create("alice", "https://example.com/other", services.URLOptions{})
+
+	clicks := map[string]int{iPhoneUA: 3, androidUA: 2, windowsUA: 1}
+	for ua, n := range clicks {
+		for i := 0; i < n; i++ {
+			env.get("/"+url.ShortCode, "", "User-Agent", ua)
+		}
+	}
+	env.get("/"+other.ShortCode, "", "User-Agent", windowsUA) // 其他链接的点击不计入
+	env.clicks.SyncClicks()
+
+	var got services.UABreakdown
+	env.do("GET", fmt.Sprintf("/api/urls/%d/ua-breakdown", url.ID), env.token("alice"), nil, 200, &got)
+	want := services.UABreakdown{
+		Devices:  []services.BreakdownItem{{Value: "Mobile", Count: 5}, {Value: "Desktop", Count: 1}},
+		Browsers: []services.BreakdownItem{{Value: "Safari", Count: 3}, {Value: "Chrome", Count: 2}, {Value: "Firefox", Count: 1}},
+		OS:       []services.BreakdownItem{{Value: "macOS", Count: 3}, {Value: "Linux", Count: 2}, {Value: "Windows", Count: 1}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("breakdown = %+v, want %+v", got, want)
+	}
+
+	// 只有创建者和管理员可以查看
+	env.do("GET", fmt.Sprintf("/api/urls/%d/ua-breakdown", url.ID), env.token("bob"), nil, 403, nil)
+	env.do("GET", fmt.Sprintf("/api/urls/%d/ua-breakdown", url.ID), env.token("admin"), nil, 200, nil)
+	env.do("GET", "/api/urls/99999/ua-breakdown", env.token("alice"), nil, 404, nil)
+}
+
+func TestSampledClicksStillCounted(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) { cfg.ClickSampleRate = 0.1 })
+	url := env.create("alice", "https://example.com/sampled", services.URLOptions{})
+
+	const clicks = 500
+	for i := 0; i < clicks; i++ {
+		env.get("/"+url.ShortCode, "", "User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0.0.0")
+	}
+	// 点击计数不受抽样影响
+	env.waitClicks(url, clicks)
+	env.clicks.SyncClicks()
+
+	var stored int64
+	if err := env.db.Model(&models.Click{}).Where("short_code = ?", url.ShortCode).Count(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored == 0 || stored > clicks/4 {
+		t.Fatalf("stored %d detail records for %d clicks at 10%% sampling", stored, clicks)
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/justseemore/surl/middleware"
+	"github.com/justseemore/surl/services"
+)
+
+func TestRedirectUniqueIPCap(t *testing.T) {
+	env := newTestEnv(t, nil)
+	limit := int64(2)
+	url := env.create("alice", "https://example.com/private-share", services.URLOptions{MaxUniqueIPs: &limit})
+	open := env.create("alice", "https://example.com/public", services.URLOptions{})
+
+	// 测试请求没有真实的来源地址，通过 X-Forwarded-For 模拟不同的访客
+	app := fiber.New(fiber.Config{ProxyHeader: fiber.HeaderXForwardedFor})
+	app.Use(middleware.UADetector())
+	app.Get("/:code", env.handler.Redirect)
+	visit := func(code, ip string) (int, string) {
+		req := httptest.NewRequest("GET", "/"+code, nil)
+		req.Header.Set(fiber.HeaderXForwardedFor, ip)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	for i, ip := range []string{"203.0.113.1", "203.0.113.2", "203.0.113.1"} {
+		if status, body := visit(url.ShortCode, ip); status != 302 {
+			t.Fatalf("visit %d from %s = %d %q, want 302", i, ip, status, body)
+		}
+	}
+	status, body := visit(url.ShortCode, "203.0.113.3")
+	if status != 403 || body != "该短链接的访问人数已达上限" {
+		t.Fatalf("third visitor = %d %q, want 403 limit page", status, body)
+	}
+	// 已访问过的IP仍可访问，被拒绝的访问不计入点击
+	if status, _ := visit(url.ShortCode, "203.0.113.2"); status != 302 {
+		t.Fatalf("repeat visitor = %d, want 302", status)
+	}
+	env.waitClicks(url, 4)
+	settle()
+	if got := env.clickCount(url); got != 4 {
+		t.Fatalf("click count = %d, want 4 admitted visits", got)
+	}
+
+	// 未设置上限的链接不受影响
+	for i := 0; i < 5; i++ {
+		if status, _ := visit(open.ShortCode, fmt.Sprintf("198.51.100.%d", i)); status != 302 {
+			t.Fatalf("uncapped link visit %d = %d, want 302", i, status)
+		}
+	}
+
+	// 上限改为0表示取消限制
+	zero := int64(0)
+	if _, err := env.urls.UpdateURL(url.ID, "", "", nil, nil, "alice", services.URLOptions{MaxUniqueIPs: &zero}); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := visit(url.ShortCode, "203.0.113.9"); status != 302 {
+		t.Fatalf("visit after removing the cap = %d, want 302", status)
+	}
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/justseemore/surl/services"
+)
+
+// updateResponse 更新接口返回的链接字段
+type updateResponse struct {
+	URL struct {
+		Title           string `json:"title"`
+		IsActive        bool   `json:"is_active"`
+		EffectiveStatus string `json:"effective_status"`
+	} `json:"url"`
+}
+
+func TestUpdateTogglesActive(t *testing.T) {
+	env := newTestEnv(t, nil)
+	url := env.create("alice", "https://example.com/toggle", services.URLOptions{})
+	path := fmt.Sprintf("/api/urls/%d/update", url.ID)
+	token := env.token("alice")
+	redirect := func() (int, string) {
+		resp := env.get("/"+url.ShortCode, "")
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	var got updateResponse
+	env.do("POST", path, token, map[string]interface{}{"is_active": false}, 200, &got)
+	if got.URL.IsActive || got.URL.EffectiveStatus != "inactive" {
+		t.Fatalf("after disabling: is_active=%v effective_status=%q", got.URL.IsActive, got.URL.EffectiveStatus)
+	}
+	if status, body := redirect(); status != 404 || body != "短链接已被创建者禁用" {
+		t.Fatalf("redirect after disabling = %d %q", status, body)
+	}
+
+	// 不传 is_active 时保持原状态
+	env.do("POST", path, token, map[string]interface{}{"title": "renamed"}, 200, &got)
+	if got.URL.IsActive || got.URL.Title != "renamed" {
+		t.Fatalf("after title update: is_active=%v title=%q, want still disabled", got.URL.IsActive, got.URL.Title)
+	}
+
+	env.do("POST", path, token, map[string]interface{}{"is_active": true}, 200, &got)
+	if !got.URL.IsActive || got.URL.EffectiveStatus != "active" {
+		t.Fatalf("after enabling: is_active=%v effective_status=%q", got.URL.IsActive, got.URL.EffectiveStatus)
+	}
+	if status, _ := redirect(); status != 302 {
+		t.Fatalf("redirect after enabling = %d, want 302", status)
+	}
+
+	// 其他用户不能修改状态
+	env.do("POST", path, env.token("bob"), map[string]interface{}{"is_active": false}, 403, nil)
+	if status, _ := redirect(); status != 302 {
+		t.Fatalf("redirect after another user's update = %d, want 302", status)
+	}
+}
+
+func TestUpdateValidationErrors(t *testing.T) {
+	env := newTestEnv(t, nil)
+	url := env.create("alice", "https://example.com/valid", services.URLOptions{})
+	path := fmt.Sprintf("/api/urls/%d/update", url.ID)
+
+	var out struct {
+		Error string `json:"error"`
+	}
+	env.do("POST", path, env.token("alice"), map[string]interface{}{"original_url": "javascript:alert(1)"}, 400, &out)
+	if out.Error == "" {
+		t.Fatal("missing validation reason")
+	}
+	if stored, err := env.urls.FindByShortCode(url.ShortCode); err != nil || stored.OriginalURL != url.OriginalURL {
+		t.Fatalf("rejected update changed the link: %+v, %v", stored, err)
+	}
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/justseemore/surl/config"
+	"github.com/justseemore/surl/models"
+	"github.com/justseemore/surl/services"
+)
+
+// listTargets 请求URL列表并返回排序后的目标地址
+func (e *testEnv) listTargets(username, query string) []string {
+	e.t.Helper()
+	var resp struct {
+		URLs []struct {
+			OriginalURL string `json:"original_url"`
+		} `json:"urls"`
+	}
+	e.do("GET", "/api/urls?empty_search=all&"+query, e.token(username), nil, 200, &resp)
+	targets := make([]string, len(resp.URLs))
+	for i, url := range resp.URLs {
+		targets[i] = url.OriginalURL
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// createAt 创建短链接并把创建时间改为指定时刻
+func (e *testEnv) createAt(username, originalURL string, createdAt time.Time) *models.URL {
+	e.t.Helper()
+	url := e.create(username, originalURL, services.URLOptions{})
+	if err := e.db.Model(&models.URL{}).Where("id = ?", url.ID).Update("created_at", createdAt.UTC()).Error; err != nil {
+		e.t.Fatal(err)
+	}
+	return url
+}
+
+func TestListCreatedDateParams(t *testing.T) {
+	t.Setenv("TIMEZONE", "Asia/Shanghai")
+	env := newTestEnv(t, nil)
+	loc := env.cfg.Location()
+	env.createAt("alice", "https://example.com/dec31", time.Date(2023, 12, 31, 23, 59, 0, 0, loc))
+	env.createAt("alice", "https://example.com/jan1", time.Date(2024, 1, 1, 0, 0, 0, 0, loc))
+	env.createAt("alice", "https://example.com/jan7-late", time.Date(2024, 1, 7, 23, 30, 0, 0, loc))
+	env.createAt("alice", "https://example.com/jan8", time.Date(2024, 1, 8, 0, 0, 0, 0, loc))
+
+	cases := map[string][]string{
+		// 仅日期时按配置时区计算，上限包含当天
+		"created_from=2024-01-01&created_to=2024-01-07":      {"https://example.com/jan1", "https://example.com/jan7-late"},
+		"created_after=2024-01-01&created_before=2024-01-01": {"https://example.com/jan1"},
+		"created_to=2023-12-31":                              {"https://example.com/dec31"},
+		// RFC3339 按给定时刻精确比较
+		"created_from=2024-01-07T15:00:00Z": {"https://example.com/jan7-late", "https://example.com/jan8"},
+	}
+	for query, want := range cases {
+		if got := env.listTargets("alice", query); !slices.Equal(got, want) {
+			t.Errorf("%s: got %v, want %v", query, got, want)
+		}
+	}
+
+	for _, query := range []string{"created_from=yesterday", "created_to=2024-13-01", "created_from=2024-01-01T00:00"} {
+		env.do("GET", "/api/urls?"+query, env.token("alice"), nil, 400, nil)
+	}
+}
+
+func TestEmptySearchBehavior(t *testing.T) {
+	for _, returnAll := range []bool{true, false} {
+		env := newTestEnv(t, func(cfg *config.Config) { cfg.EmptySearchReturnsAll = returnAll })
+		env.create("alice", "https://example.com/a", services.URLOptions{})
+		env.create("alice", "https://example.com/b", services.URLOptions{})
+
+		count := func(query string) int {
+			var resp struct {
+				URLs  []interface{} `json:"urls"`
+				Total int64         `json:"total"`
+			}
+			env.do("GET", "/api/urls?"+query, env.token("alice"), nil, 200, &resp)
+			if int64(len(resp.URLs)) != resp.Total {
+				t.Fatalf("%s: %d urls, total %d", query, len(resp.URLs), resp.Total)
+			}
+			return len(resp.URLs)
+		}
+
+		want := 0
+		if returnAll {
+			want = 2
+		}
+		if got := count(""); got != want {
+			t.Errorf("EmptySearchReturnsAll=%v: empty search returned %d, want %d", returnAll, got, want)
+		}
+		// empty_search 参数覆盖配置，有搜索词时两种配置结果相同
+		if got := count("empty_search=all"); got != 2 {
+			t.Errorf("EmptySearchReturnsAll=%v: empty_search=all returned %d", returnAll, got)
+		}
+		if got := count("empty_search=none"); got != 0 {
+			t.Errorf("EmptySearchReturnsAll=%v: empty_search=none returned %d", returnAll, got)
+		}
+		if got := count("search=/b"); got != 1 {
+			t.Errorf("EmptySearchReturnsAll=%v: search returned %d", returnAll, got)
+		}
+	}
+}
+
+func TestListOwnerFilterIsAdminOnly(t *testing.T) {
+	env := newTestEnv(t, nil)
+	env.create("alice", "https://example.com/alice", services.URLOptions{})
+	env.create("bob", "https://example.com/bob", services.URLOptions{})
+	env.create("admin", "https://example.com/admin", services.URLOptions{})
+
+	if got, want := env.listTargets("admin", "owner=alice,+bob"), []string{"https://example.com/alice", "https://example.com/bob"}; !slices.Equal(got, want) {
+		t.Errorf("admin owner filter: got %v, want %v", got, want)
+	}
+	// 普通用户的 owner 参数被忽略，只能看到自己的链接
+	if got, want := env.listTargets("alice", "owner=bob,admin"), []string{"https://example.com/alice"}; !slices.Equal(got, want) {
+		t.Errorf("non-admin owner filter: got %v, want %v", got, want)
+	}
+	if got, want := env.listTargets("admin", "status=active,inactive&owner=bob"), []string{"https://example.com/bob"}; !slices.Equal(got, want) {
+		t.Errorf("status and owner: got %v, want %v", got, want)
+	}
+	env.do("GET", "/api/urls?status=active,deleted", env.token("admin"), nil, 400, nil)
+}
+
+func TestListHostParam(t *testing.T) {
+	env := newTestEnv(t, nil)
+	env.create("alice", "https://example.com/a", services.URLOptions{})
+	env.create("alice", "https://www.example.com/b", services.URLOptions{})
+	env.create("alice", "https://other.org/c", services.URLOptions{})
+	env.create("bob", "https://example.com/bob", services.URLOptions{})
+
+	want := []string{"https://example.com/a", "https://www.example.com/b"}
+	// 大小写、首尾空格和末尾的点不影响匹配；普通用户只能看到自己的链接
+	for _, host := range []string{"example.com", "EXAMPLE.com", "+example.com.+"} {
+		if got := env.listTargets("alice", "host="+host); !slices.Equal(got, want) {
+			t.Errorf("host=%s: got %v, want %v", host, got, want)
+		}
+	}
+	if got, want := env.listTargets("alice", "host=other.org"), []string{"https://other.org/c"}; !slices.Equal(got, want) {
+		t.Errorf("host=other.org: got %v, want %v", got, want)
+	}
+	for _, host := range []string{"exa%25mple.com", "ex_ample.com", "example.com/a", "example.com:443"} {
+		env.do("GET", "/api/urls?host="+host, env.token("alice"), nil, 400, nil)
+	}
+}
+
+func TestListStatusAndExpiryParams(t *testing.T) {
+	t.Setenv("TIMEZONE", "Asia/Shanghai")
+	env := newTestEnv(t, nil)
+	loc := env.cfg.Location()
+	setExpiry := func(url *models.URL, at time.Time) {
+		if err := env.db.Model(&models.URL{}).Where("id = ?", url.ID).Update("expires_at", at.UTC()).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+
+	endOfWeek := env.createAt("alice", "https://example.com/end-of-week", today)
+	setExpiry(endOfWeek, today.AddDate(0, 0, 7).Add(-time.Minute))
+	nextWeek := env.createAt("alice", "https://example.com/next-week", today)
+	setExpiry(nextWeek, today.AddDate(0, 0, 8))
+	expired := env.createAt("alice", "https://example.com/expired", today.AddDate(0, 0, -30))
+	setExpiry(expired, now.Add(-time.Hour))
+	disabled := env.createAt("alice", "https://example.com/disabled", today.AddDate(0, 0, -30))
+	setExpiry(disabled, now.Add(-time.Hour))
+	env.do("POST", "/api/urls/batch/toggle", env.token("alice"), map[string]interface{}{"ids": []uint{disabled.ID}, "active": false}, 200, nil)
+
+	weekEnd := today.AddDate(0, 0, 6).Format("2006-01-02") // 上限包含当天
+	yesterday := today.AddDate(0, 0, -1).Format("2006-01-02")
+	cases := map[string][]string{
+		"status=expired":                                      {"https://example.com/expired"},
+		"status=inactive":                                     {"https://example.com/disabled"},
+		"status=active":                                       {"https://example.com/end-of-week", "https://example.com/next-week"},
+		"expires_before=" + weekEnd:                           {"https://example.com/disabled", "https://example.com/end-of-week", "https://example.com/expired"},
+		"status=active&expires_before=" + weekEnd:             {"https://example.com/end-of-week"},
+		"status=expired&created_after=" + yesterday:           {},
+		"status=expired,inactive&created_before=" + yesterday: {"https://example.com/disabled", "https://example.com/expired"},
+	}
+	for query, want := range cases {
+		if got := env.listTargets("alice", query); !slices.Equal(got, want) {
+			t.Errorf("%s: got %v, want %v", query, got, want)
+		}
+	}
+
+	var page struct {
+		URLs       []struct{} `json:"urls"`
+		Total      int64      `json:"total"`
+		TotalPages int        `json:"total_pages"`
+	}
+	env.do("GET", "/api/urls?empty_search=all&expires_before="+weekEnd+"&limit=2&page=2", env.token("alice"), nil, 200, &page)
+	if len(page.URLs) != 1 || page.Total != 3 || page.TotalPages != 2 {
+		t.Fatalf("page 2 = %d urls, total %d, %d pages; want 1, 3, 2", len(page.URLs), page.Total, page.TotalPages)
+	}
+
+	for _, query := range []string{"status=archived", "expires_before=next-week"} {
+		env.do("GET", "/api/urls?"+query, env.token("alice"), nil, 400, nil)
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/justseemore/surl/models"
+	"github.com/justseemore/surl/services"
+)
+
+// urlView URL的响应视图，附带计算出的实际状态；非管理员看不到所有者等内部字段
+type urlView struct {
+	*models.URL
+	CreatedBy       string `json:"created_by,omitempty"`
+	IsExpired       bool   `json:"is_expired"`
+	EffectiveStatus string `json:"effective_status"`
+	ExpiresAtLocal  string `json:"expires_at_local,omitempty"` // 按配置时区显示的过期时间
+}
+
+// localTimeLayout 按配置时区展示时间的格式
+const localTimeLayout = "2006-01-02 15:04:05 MST"
+
+// newURLView 根据角色构造URL视图，is_expired 和 effective_status 基于同一时刻计算
+func newURLView(role string, url *models.URL, loc *time.Location) urlView {
+	now := time.Now()
+	view := urlView{
+		URL:             url,
+		IsExpired:       url.IsExpiredAt(now),
+		EffectiveStatus: url.EffectiveStatusAt(now),
+	}
+	if url.ExpiresAt != nil {
+		view.ExpiresAtLocal = url.ExpiresAt.In(loc).Format(localTimeLayout)
+	}
+	if role == "admin" {
+		view.CreatedBy = url.CreatedBy
+	}
+	return view
+}
+
+// deletedURLView 非管理员看到的回收站URL
+type deletedURLView struct {
+	*services.DeletedURL
+	CreatedBy string `json:"created_by,omitempty"`
+}
+
+// presentURL 根据角色返回URL的响应视图
+func presentURL(role string, url *models.URL, loc *time.Location) interface{} {
+	return newURLView(role, url, loc)
+}
+
+// presentURLs 根据角色返回URL列表的响应视图
+func presentURLs(role string, urls []models.URL, loc *time.Location) interface{} {
+	views := make([]urlView, len(urls))
+	for i := range urls {
+		views[i] = newURLView(role, &urls[i], loc)
+	}
+	return views
+}
+
+// presentDeletedURLs 根据角色返回回收站列表的响应视图
+func presentDeletedURLs(role string, urls []services.DeletedURL) interface{} {
+	if role == "admin" {
+		return urls
+	}
+	views := make([]deletedURLView, len(urls))
+	for i := range urls {
+		views[i] = deletedURLView{DeletedURL: &urls[i]}
+	}
+	return views
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/justseemore/surl/models"
+	"github.com/justseemore/surl/services"
+)
+
+func TestURLResponsesHideOwnerFromNonAdmins(t *testing.T) {
+	env := newTestEnv(t, nil)
+	url := env.create("alice", "https://example.com/owned", services.URLOptions{})
+	deleted := env.create("alice", "https://example.com/trashed", services.URLOptions{})
+	if err := env.urls.DeleteURL(deleted.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	paths := map[string]string{
+		"list":   "/api/urls",
+		"single": fmt.Sprintf("/api/urls/%d", url.ID),
+		"trash":  "/api/urls/trash",
+	}
+	for _, user := range []string{"alice", "admin"} {
+		for name, path := range paths {
+			var resp map[string]interface{}
+			env.do("GET", path, env.token(user), nil, 200, &resp)
+
+			var items []interface{}
+			switch name {
+			case "single":
+				items = []interface{}{resp["url"]}
+			default:
+				items, _ = resp["urls"].([]interface{})
+			}
+			if len(items) != 1 {
+				t.Fatalf("%s %s: got %d urls: %v", user, name, len(items), resp)
+			}
+			item := items[0].(map[string]interface{})
+			owner, ok := item["created_by"]
+			if user == "admin" && owner != "alice" {
+				t.Errorf("admin %s: created_by = %v, want alice", name, owner)
+			}
+			if user != "admin" && ok {
+				t.Errorf("non-admin %s: created_by exposed: %v", name, owner)
+			}
+		}
+	}
+}
+
+func TestEffectiveStatusInResponses(t *testing.T) {
+	env := newTestEnv(t, nil)
+	active := env.create("alice", "https://example.com/active", services.URLOptions{})
+	expired := env.create("alice", "https://example.com/expired", services.URLOptions{})
+	past := time.Now().Add(-time.Minute)
+	if _, err := env.urls.UpdateURL(expired.ID, "", "", &past, nil, "alice", services.URLOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	disabled := env.create("alice", "https://example.com/disabled", services.URLOptions{})
+	if err := env.urls.ToggleURLStatus(disabled.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[uint]string{
+		active.ID:   models.StatusActive,
+		expired.ID:  models.StatusExpired, // is_active 仍为true，但实际已过期
+		disabled.ID: models.StatusInactive,
+	}
+
+	type view struct {
+		ID              uint   `json:"id"`
+		IsActive        bool   `json:"is_active"`
+		EffectiveStatus string `json:"effective_status"`
+	}
+	var list struct {
+		URLs []view `json:"urls"`
+	}
+	env.do("GET", "/api/urls", env.token("alice"), nil, 200, &list)
+	if len(list.URLs) != len(want) {
+		t.Fatalf("got %d urls, want %d", len(list.URLs), len(want))
+	}
+	for _, v := range list.URLs {
+		if v.EffectiveStatus != want[v.ID] {
+			t.Errorf("list: url %d effective_status = %q, want %q", v.ID, v.EffectiveStatus, want[v.ID])
+		}
+		if v.ID == expired.ID && !v.IsActive {
+			t.Errorf("expired link reported is_active=false")
+		}
+	}
+
+	for id, status := range want {
+		var detail struct {
+			URL view `json:"url"`
+		}
+		env.do("GET", fmt.Sprintf("/api/urls/%d", id), env.token("alice"), nil, 200, &detail)
+		if detail.URL.EffectiveStatus != status {
+			t.Errorf("detail: url %d effective_status = %q, want %q", id, detail.URL.EffectiveStatus, status)
+		}
+	}
+}
+
+func TestURLViewIsExpiredMatchesModel(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	for _, url := range []*models.URL{
+		{IsActive: true},
+		{IsActive: true, ExpiresAt: &past},
+		{IsActive: true, ExpiresAt: &future},
+		{IsActive: false, ExpiresAt: &past},
+	} {
+		view := newURLView("user", url, time.UTC)
+		if view.IsExpired != url.IsExpired() || view.EffectiveStatus != url.EffectiveStatus() {
+			t.Errorf("view is_expired/effective_status = %v/%q, model %v/%q", view.IsExpired, view.EffectiveStatus, url.IsExpired(), url.EffectiveStatus())
+		}
+		data, err := json.Marshal(view)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded map[string]interface{}
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if decoded["is_expired"] != url.IsExpired() {
+			t.Errorf("serialized is_expired = %v, want %v", decoded["is_expired"], url.IsExpired())
+		}
+		if _, ok := decoded["expires_at_local"]; ok != (url.ExpiresAt != nil) {
+			t.Errorf("expires_at_local present = %v with ExpiresAt %v", ok, url.ExpiresAt)
+		}
+	}
+}
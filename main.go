@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
@@ -16,6 +18,7 @@ import (
 
 	"github.com/justseemore/surl/cache"
 	"github.com/justseemore/surl/config"
+	"github.com/justseemore/surl/events"
 	"github.com/justseemore/surl/handlers"
 	"github.com/justseemore/surl/middleware"
 	"github.com/justseemore/surl/models"
@@ -23,28 +26,70 @@ import (
 )
 
 func main() {
+	// 命令行工具：生成bcrypt密码哈希
+	if len(os.Args) == 3 && os.Args[1] == "hash-password" {
+		hash, err := services.HashPassword(os.Args[2])
+		if err != nil {
+			log.Fatal("Failed to hash password:", err)
+		}
+		fmt.Println(hash)
+		return
+	}
+
 	// 加载配置
 	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		log.Fatal("Invalid configuration:", err)
+	}
 
 	// 设置JWT密钥
 	middleware.SetJWTSecret(cfg.JWTSecret)
 
 	// 初始化数据库
-	if err := models.InitDatabase(cfg.DBPath); err != nil {
+	if err := models.InitDatabase(cfg.DBDriver, cfg.DatabaseSource()); err != nil {
 		log.Fatal("Failed to initialize database:", err)
 	}
 
 	// 初始化服务 - 使用带内存限制的缓存管理器
-	cacheManager := cache.NewCacheManager(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB, cfg.CacheExpiry, cfg.CacheMaxItems)
-	urlService := services.NewURLService(cacheManager, models.DB, cfg)
-	authService := services.NewAuthService(cfg)
+	cacheManager := cache.NewCacheManagerWithRedis(redisOptions(cfg), cfg.CacheExpiry, cfg.CacheMaxItems, cfg.RedisPrefix)
+	if cfg.NegativeCacheTTL > 0 {
+		cacheManager.SetTTL(cache.EntryNotFound, time.Duration(cfg.NegativeCacheTTL)*time.Second)
+		cacheManager.SetTTL(cache.EntryUnavailable, time.Duration(cfg.NegativeCacheTTL)*time.Second)
+	}
+	cacheManager.SetMaxClickKeys(cfg.MaxPendingClickKeys)
+	cacheManager.SetLogEvictions(cfg.LogCacheEvictions)
+	dispatcher := events.NewDispatcher(cfg.WebhookURL, cfg.WebhookSecret)
+	urlService := services.NewURLService(cacheManager, models.DB, cfg, dispatcher)
+	eventLog, err := events.NewEventLog(cfg.EventLog)
+	if err != nil {
+		log.Fatal("Failed to open event log:", err)
+	}
+	urlService.SetEventLog(eventLog)
+	authService := services.NewAuthService(cfg, cacheManager, models.DB)
+	if err := authService.SeedAccounts(); err != nil {
+		log.Fatal("Failed to seed accounts:", err)
+	}
+	geoService, err := services.NewGeoService(cfg.GeoIPDBPath, models.DB)
+	if err != nil {
+		log.Fatal("Failed to initialize GeoIP:", err)
+	}
+
+	clickService := services.NewClickService(models.DB, cfg.ClickSampleRate)
 
 	// 启动异步任务
-	go urlService.StartClickCountSync()
+	startClickSync(cfg, urlService, clickService, geoService)
+	urlService.StartTrashPurge()
 
 	// 启动缓存预热
 	urlService.WarmupCache()
 
+	// 每个子进程都会执行检查，子进程连接Redis失败时同样退出
+	if cfg.Prefork {
+		if err := checkPrefork(cfg, cacheManager); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	// 创建模板引擎
 	engine := html.New("./templates", ".html")
 	// engine.AddFunc("sub", func(a, b int) int { return a - b })
@@ -52,10 +97,7 @@ func main() {
 	// engine.AddFunc("div", func(a, b int) int { return a / b })
 
 	// 创建Fiber应用
-	app := fiber.New(fiber.Config{
-		Views:   engine,
-		Prefork: true,
-	})
+	app := fiber.New(appConfig(cfg, engine))
 
 	// 中间件
 	app.Use(logger.New())
@@ -67,18 +109,37 @@ func main() {
 	// app.Static("/static", "./static")
 
 	// 初始化处理器
-	handler := handlers.NewHandler(urlService, authService, cfg)
+	perfTracker := middleware.NewPerfTracker()
+	handler := handlers.NewHandler(urlService, authService, geoService, clickService, perfTracker, cfg)
 
 	// 设置路由
-	setupRoutes(app, handler)
+	setupRoutes(app, handler, cfg, cacheManager, perfTracker)
 
 	// 启动服务器
 	go func() {
+		if cfg.TLSEnabled() {
+			if err := app.ListenTLS(":"+cfg.Port, cfg.TLSCertFile, cfg.TLSKeyFile); err != nil {
+				log.Fatal("Failed to start server:", err)
+			}
+			return
+		}
 		if err := app.Listen(":" + cfg.Port); err != nil {
 			log.Fatal("Failed to start server:", err)
 		}
 	}()
 
+	// 启用TLS时，HTTP端口只负责跳转到HTTPS（Prefork模式下仅在主进程中监听）
+	if cfg.TLSEnabled() && cfg.HTTPPort != "" && !fiber.IsChild() {
+		redirectApp := fiber.New(fiber.Config{DisableStartupMessage: true})
+		redirectApp.Use(middleware.HTTPSRedirect(cfg.Port))
+		go func() {
+			if err := redirectApp.Listen(":" + cfg.HTTPPort); err != nil {
+				log.Printf("Failed to start HTTP redirect server: %v", err)
+			}
+		}()
+		defer redirectApp.Shutdown()
+	}
+
 	log.Printf("Server started on port %s", cfg.Port)
 
 	// 优雅关闭
@@ -87,52 +148,193 @@ func main() {
 	<-c
 
 	log.Println("Shutting down server...")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := shutdownContext(cfg)
 	defer cancel()
 
+	// 1. 停止接收新请求，并等待进行中的请求完成（超时后不再等待，继续后续步骤）
 	if err := app.ShutdownWithContext(ctx); err != nil {
-		log.Fatal("Server shutdown failed:", err)
+		log.Printf("Server shutdown failed: %v", err)
+	}
+
+	// 2. 所有请求结束后停止后台任务并写入剩余的点击计数，避免丢失最后一段时间的访问
+	syncCtx, syncCancel := shutdownContext(cfg)
+	defer syncCancel()
+	if err := urlService.Shutdown(syncCtx); err != nil {
+		log.Printf("Click sync shutdown timed out: %v", err)
+	}
+	if geoService != nil {
+		geoService.SyncCountryClicks()
+	}
+	clickService.SyncClicks()
+	if err := dispatcher.Close(syncCtx); err != nil {
+		log.Printf("Webhook delivery shutdown timed out: %v", err)
+	}
+	if err := eventLog.Close(syncCtx); err != nil {
+		log.Printf("Event log shutdown failed: %v", err)
+	}
+
+	// 3. 最后关闭缓存连接
+	if err := cacheManager.Close(); err != nil {
+		log.Printf("Failed to close cache: %v", err)
 	}
 
 	log.Println("Server shutdown complete")
 }
 
-func setupRoutes(app *fiber.App, handler *handlers.Handler) {
+// startClickSync 启动点击计数、点击明细和国家统计的同步任务，关闭点击统计时都不启动
+func startClickSync(cfg *config.Config, urlService *services.URLService, clickService *services.ClickService, geoService *services.GeoService) {
+	if !cfg.ClickCountingEnabled() {
+		return
+	}
+	urlService.StartClickCountSync()
+	clickService.StartClickSync()
+	if geoService != nil {
+		geoService.StartCountryClickSync()
+	}
+}
+
+// shutdownContext 关闭的每个阶段最多等待 SHUTDOWN_TIMEOUT 秒
+func shutdownContext(cfg *config.Config) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeout)*time.Second)
+}
+
+// checkPrefork 检查Prefork的运行环境
+// 各进程的点击计数必须经由Redis汇总，没有Redis时拒绝启动；
+// SQLite 会被每个子进程单独打开，导致锁竞争，只输出警告
+func checkPrefork(cfg *config.Config, cacheManager *cache.Manager) error {
+	if !cacheManager.RedisEnabled() {
+		return errors.New("PREFORK=true requires a reachable Redis so click counts and cache are shared between workers; configure REDIS_ADDR or set PREFORK=false")
+	}
+	if cfg.DBDriver != models.DriverSQLite || fiber.IsChild() {
+		return nil
+	}
+
+	log.Println("==================================================================")
+	log.Println("WARNING: PREFORK=true with SQLite: every worker opens the same database file,")
+	log.Println("which causes lock contention under load.")
+	log.Println("Use a networked database (DB_DRIVER=postgres or mysql) with prefork.")
+	log.Println("==================================================================")
+	return nil
+}
+
+// redisOptions 根据配置构建Redis连接参数，sentinel/cluster 未配置 REDIS_ADDRS 时回退到 REDIS_ADDR
+func redisOptions(cfg *config.Config) cache.RedisOptions {
+	addrs := cfg.RedisAddrs
+	if cfg.RedisMode == config.RedisModeSingle || len(addrs) == 0 {
+		addrs = nil
+		if cfg.RedisAddr != "" {
+			addrs = []string{cfg.RedisAddr}
+		}
+	}
+	return cache.RedisOptions{
+		Mode:       cfg.RedisMode,
+		Addrs:      addrs,
+		MasterName: cfg.RedisSentinelMaster,
+		Password:   cfg.RedisPassword,
+		DB:         cfg.RedisDB,
+	}
+}
+
+// appConfig 构建Fiber配置；设置 PROXY_HEADER 后 c.IP() 取代理转发的客户端IP，限流按真实访客计数
+func appConfig(cfg *config.Config, views fiber.Views) fiber.Config {
+	return fiber.Config{
+		Views:                   views,
+		Prefork:                 cfg.Prefork,
+		BodyLimit:               cfg.BodyLimit,
+		ProxyHeader:             cfg.ProxyHeader,
+		EnableIPValidation:      cfg.ProxyHeader != "",
+		EnableTrustedProxyCheck: len(cfg.TrustedProxies) > 0,
+		TrustedProxies:          cfg.TrustedProxies,
+	}
+}
+
+func setupRoutes(app *fiber.App, handler *handlers.Handler, cfg *config.Config, cacheManager *cache.Manager, perfTracker *middleware.PerfTracker) {
+	// 按路由组区分计数的限流器
+	rateLimit := func(prefix string) fiber.Handler {
+		return middleware.RateLimiter(middleware.RateLimiterOptions{
+			Store:     cacheManager,
+			Max:       cfg.RateLimitRPM,
+			Window:    time.Minute,
+			KeyPrefix: prefix,
+		})
+	}
+
+	// 记录各路由的处理耗时
+	app.Use(perfTracker.Middleware())
+	// 请求体默认使用 SMALL_BODY_LIMIT，只有批量创建（导入）可以使用应用级的 BODY_LIMIT
+	app.Use(middleware.BodyLimit(cfg.SmallBodyLimit, map[string]int{
+		"/api/urls/batch/create": cfg.BodyLimit,
+	}))
 	// 添加UA检测中间件到需要检测的路由
 	app.Use(middleware.UADetector())
+	// 首页必须在 /:code 之前注册；/:code 不匹配空参数，"/" 和 "//" 等只有斜杠的路径不会进入 Redirect
 	app.Get("/", handler.Index)
 	// 公开路由
 	app.Get("/login", handler.LoginPage)
 	app.Post("/api/login", handler.Login)
+	app.Post("/api/refresh", handler.Refresh)
+	app.Get("/api/preview/:code", rateLimit("preview"), handler.PreviewURL)
 	// 需要认证的管理路由
 	app.Get("/admin.html", handler.Admin)
 	// 需要认证的API路由组
 	api := app.Group("/api")
 	api.Use(middleware.JWTMiddleware())
 	// URL基础操作
-	api.Post("/create", handler.CreateShortURL)
+	idempotency := middleware.Idempotency(middleware.IdempotencyOptions{
+		Store: cacheManager,
+		TTL:   time.Duration(cfg.IdempotencyTTL) * time.Hour,
+	})
+	api.Post("/create", rateLimit("create"), idempotency, handler.CreateShortURL)
 	api.Get("/urls", handler.GetURLs)
-	api.Get("/urls/:id<int>", handler.GetURLByID) // 新增：根据ID获取单个URL
+	api.Get("/urls/trash", handler.GetDeletedURLs)  // 回收站
+	api.Get("/urls/top", handler.GetTopURLs)        // 排行榜
+	api.Get("/urls/:id<int>", handler.GetURLByID)   // 新增：根据ID获取单个URL
+	api.Get("/urls/:code/geo", handler.GetGeoStats) // 按国家的点击统计
 	api.Post("/urls/:id<int>/update", handler.UpdateURL)
 	api.Post("/urls/:id<int>/delete", handler.DeleteURL)
+	api.Post("/urls/:id<int>/restore", handler.RestoreURL) // 恢复已删除的URL
+	api.Get("/urls/:id<int>/ua-breakdown", handler.GetUABreakdown)
+	// 查询目标URL是否已有短链接
+	api.Get("/urls/lookup", handler.LookupURL)
+	// 只返回目标地址，不计入点击
+	api.Get("/urls/code/:code/destination", handler.GetDestination)
 
 	// 批量操作
+	api.Post("/urls/batch/create", rateLimit("create"), handler.BatchCreateURLs)
 	api.Post("/urls/batch/delete", handler.BatchDeleteURLs) // 新增：批量删除URLs
 	api.Post("/urls/batch/toggle", handler.BatchToggleURLs) // 新增：批量切换URL状态
+	api.Post("/urls/batch/tags", handler.BatchTagURLs)
+	api.Post("/urls/batch/update", handler.BatchUpdateURLs)
+	api.Post("/urls/batch/qr", handler.BatchQRCodes)
 
 	// 统计相关
 	api.Get("/stats", handler.GetStats) // 新增：获取统计信息
+	api.Get("/stats/global", middleware.AdminMiddleware(), handler.GetGlobalStats)
 
 	// 清理操作
 	api.Post("/cleanup/expired", handler.CleanupExpired) // 新增：清理过期链接
 	api.Get("/expired", handler.GetExpiredURLs)          // 新增：获取过期链接列表
 
+	// 永久删除回收站记录（仅限管理员）
+	api.Post("/cleanup/purge", middleware.AdminMiddleware(), handler.PurgeDeleted)
+
+	// 各路由延迟统计（仅限管理员）
+	api.Get("/admin/perf", middleware.AdminMiddleware(), handler.GetPerfStats)
+
+	// 立即写入单个链接缓冲的点击计数（仅限管理员，用于排查热门链接）
+	api.Post("/admin/urls/:id<int>/flush-clicks", middleware.AdminMiddleware(), handler.FlushURLClicks)
+
 	// 用户相关
 	api.Get("/profile", handler.GetProfile) // 新增：获取用户信息
+	api.Post("/profile/password", handler.ChangePassword)
+	api.Post("/logout", handler.Logout)
+
+	// 令牌自省
+	api.Post("/token/introspect", rateLimit("introspect"), handler.IntrospectToken)
 
 	// 二维码生成
 	api.Get("/qrcode/:code", handler.GenerateQRCode) // 新增：生成二维码
 
-	// 重定向路由（放在最后以避免冲突）
-	app.Get("/:code", handler.Redirect)
+	// 重定向路由（放在最后以避免冲突，站点路径之外的单段路径都视为短代码）
+	app.Get("/:code", rateLimit("redirect"), middleware.OptionalJWTMiddleware(), handler.Redirect)
 }
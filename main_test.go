@@ -0,0 +1,335 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/template/html/v2"
+	"github.com/justseemore/surl/cache"
+	"github.com/justseemore/surl/config"
+	"github.com/justseemore/surl/handlers"
+	"github.com/justseemore/surl/middleware"
+	"github.com/justseemore/surl/models"
+	"github.com/justseemore/surl/services"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// serve 在随机端口上启动应用，返回访问地址
+func serve(t *testing.T, app *fiber.App) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go app.Listener(ln)
+	return "http://" + ln.Addr().String()
+}
+
+func TestShutdownContextUsesConfiguredTimeout(t *testing.T) {
+	for _, seconds := range []int{1, 30} {
+		cfg := config.Load()
+		cfg.ShutdownTimeout = seconds
+		ctx, cancel := shutdownContext(cfg)
+		deadline, ok := ctx.Deadline()
+		cancel()
+		if want := time.Duration(seconds) * time.Second; !ok || time.Until(deadline) > want || time.Until(deadline) < want-time.Second {
+			t.Errorf("ShutdownTimeout=%d: deadline in %v, want %v", seconds, time.Until(deadline), want)
+		}
+	}
+}
+
+func TestShutdownStopsWaitingAfterTimeout(t *testing.T) {
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	app.Get("/export", func(c *fiber.Ctx) error {
+		close(started)
+		<-release
+		return c.SendString("done")
+	})
+	base := serve(t, app)
+	go http.Get(base + "/export")
+	<-started
+
+	// 请求耗时超过关闭超时，ShutdownWithContext 在超时后返回而不是一直等待
+	cfg := config.Load()
+	cfg.ShutdownTimeout = 1
+	ctx, cancel := shutdownContext(cfg)
+	defer cancel()
+	start := time.Now()
+	err := app.ShutdownWithContext(ctx)
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond || elapsed > 3*time.Second {
+		t.Fatalf("ShutdownWithContext returned after %v, want about 1s", elapsed)
+	}
+	if !errors.Is(err, ctx.Err()) || ctx.Err() == nil {
+		t.Fatalf("ShutdownWithContext err = %v, want the context deadline error", err)
+	}
+}
+
+func TestInFlightClickSyncedOnShutdown(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	defer sqlDB.Close()
+	if err := db.AutoMigrate(&models.URL{}); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Load()
+	cfg.BlockPrivateHosts = false
+	cacheManager := cache.NewCacheManager("", "", 0, 60, 1000, "")
+	urlService := services.NewURLService(cacheManager, db, cfg, nil)
+	url, err := urlService.CreateShortURL("https://example.com/inflight", "", "", "", nil, "alice", true, services.URLOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	urlService.StartClickCountSync()
+
+	// 慢请求在关闭开始之后才计入点击
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	started := make(chan struct{})
+	app.Get("/:code", func(c *fiber.Ctx) error {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		urlService.IncrementClickCount(c.Params("code"))
+		return c.SendStatus(fiber.StatusFound)
+	})
+	base := serve(t, app)
+	done := make(chan int)
+	go func() {
+		resp, err := http.Get(base + "/" + url.ShortCode)
+		if err != nil {
+			done <- 0
+			return
+		}
+		resp.Body.Close()
+		done <- resp.StatusCode
+	}()
+	<-started
+
+	// 与 main 相同的顺序：先等待进行中的请求完成，再停止同步并写入剩余计数
+	ctx, cancel := shutdownContext(cfg)
+	defer cancel()
+	if err := app.ShutdownWithContext(ctx); err != nil {
+		t.Fatalf("ShutdownWithContext: %v", err)
+	}
+	if status := <-done; status != fiber.StatusFound {
+		t.Fatalf("in-flight request status = %d, want 302", status)
+	}
+	// IncrementClick 是异步的，等待计入缓冲区后再关闭
+	deadline := time.Now().Add(2 * time.Second)
+	for cacheManager.GetPendingClicks(url.ShortCode) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	syncCtx, syncCancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer syncCancel()
+	if err := urlService.Shutdown(syncCtx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	var stored models.URL
+	if err := db.First(&stored, url.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.ClickCount != 1 {
+		t.Fatalf("click_count = %d, want the in-flight click persisted", stored.ClickCount)
+	}
+}
+
+// newRoutedApp 使用 setupRoutes 注册完整路由，数据库和缓存均在内存中
+func newRoutedApp(t *testing.T, configure func(cfg *config.Config)) (*fiber.App, *services.URLService) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&models.URL{}, &models.CountryClick{}, &models.Account{}, &models.Click{}); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Load()
+	cfg.BlockPrivateHosts = false
+	if configure != nil {
+		configure(cfg)
+	}
+
+	cacheManager := cache.NewCacheManager("", "", 0, 60, 1000, "")
+	urlService := services.NewURLService(cacheManager, db, cfg, nil)
+	authService := services.NewAuthService(cfg, cacheManager, db)
+	clickService := services.NewClickService(db, cfg.ClickSampleRate)
+	perfTracker := middleware.NewPerfTracker()
+	handler := handlers.NewHandler(urlService, authService, nil, clickService, perfTracker, cfg)
+
+	app := fiber.New(appConfig(cfg, html.New("./templates", ".html")))
+	setupRoutes(app, handler, cfg, cacheManager, perfTracker)
+	return app, urlService
+}
+
+func TestRootAndShortCodeRouting(t *testing.T) {
+	app, urlService := newRoutedApp(t, nil)
+	url, err := urlService.CreateShortURL("https://example.com/routed", "", "", "", nil, "alice", true, services.URLOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 与站点页面同名的短代码不能覆盖这些页面
+	for _, code := range []string{"login", "admin.html"} {
+		if _, err := urlService.CreateShortURL("https://example.com/"+code, code, "", "", nil, "alice", true, services.URLOptions{}); err != nil {
+			t.Fatalf("create %s: %v", code, err)
+		}
+	}
+
+	cases := []struct {
+		path     string
+		status   int
+		body     string
+		location string
+	}{
+		{path: "/", status: 200, body: "短链接服务"},
+		{path: "/?code=abc", status: 200, body: "短链接服务"},
+		{path: "/" + url.ShortCode, status: 302, location: url.OriginalURL},
+		{path: "/" + url.ShortCode + "/", status: 302, location: url.OriginalURL},
+		{path: "/" + url.ShortCode + "?utm=x", status: 302, location: url.OriginalURL},
+		{path: "/abc", status: 404, body: "短链接不存在"},
+		{path: "//", status: 404, body: "Cannot GET //"},
+		{path: "/%20", status: 404, body: "短链接不存在"},
+		{path: "/login", status: 200, body: "管理员登录"},
+		{path: "/admin.html", status: 200, body: "后台管理"},
+		{path: "/abc/def", status: 404},
+	}
+	for _, tc := range cases {
+		resp, err := app.Test(httptest.NewRequest("GET", tc.path, nil), -1)
+		if err != nil {
+			t.Fatalf("GET %s: %v", tc.path, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != tc.status {
+			t.Errorf("GET %s: status = %d, want %d: %.100s", tc.path, resp.StatusCode, tc.status, body)
+			continue
+		}
+		if tc.body != "" && !strings.Contains(string(body), tc.body) {
+			t.Errorf("GET %s: body %.100q does not contain %q", tc.path, body, tc.body)
+		}
+		if got := resp.Header.Get("Location"); got != tc.location {
+			t.Errorf("GET %s: Location = %q, want %q", tc.path, got, tc.location)
+		}
+	}
+}
+
+func TestPreforkRequiresRedis(t *testing.T) {
+	cfg := config.Load()
+	cfg.Prefork = true
+	if err := checkPrefork(cfg, cache.NewCacheManager("", "", 0, 60, 1000, "")); err == nil {
+		t.Fatal("checkPrefork accepted a memory-only cache; click counts would be split between workers")
+	}
+}
+
+func TestClickSyncNotStartedWhenCountingOff(t *testing.T) {
+	for _, counting := range []string{config.ClickCountingOn, config.ClickCountingOff} {
+		db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sqlDB, _ := db.DB()
+		sqlDB.SetMaxOpenConns(1)
+		defer sqlDB.Close()
+		if err := db.AutoMigrate(&models.URL{}, &models.Click{}); err != nil {
+			t.Fatal(err)
+		}
+		cfg := config.Load()
+		cfg.BlockPrivateHosts = false
+		cfg.ClickCounting = counting
+		cacheManager := cache.NewCacheManager("", "", 0, 60, 1000, "")
+		urlService := services.NewURLService(cacheManager, db, cfg, nil)
+		url, err := urlService.CreateShortURL("https://example.com/"+counting, "", "", "", nil, "alice", true, services.URLOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// 缓冲一个短代码即触发提前同步，运行中的同步任务会立即写入数据库
+		cacheManager.SetMaxClickKeys(1)
+		startClickSync(cfg, urlService, services.NewClickService(db, cfg.ClickSampleRate), nil)
+		urlService.IncrementClickCount(url.ShortCode)
+
+		stored := func() int64 {
+			var u models.URL
+			if err := db.First(&u, url.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			return u.ClickCount
+		}
+		deadline := time.Now().Add(500 * time.Millisecond)
+		for stored() == 0 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if synced := stored() == 1; synced != (counting == config.ClickCountingOn) {
+			t.Errorf("CLICK_COUNTING=%s: click sync ran = %v", counting, synced)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		urlService.Shutdown(ctx)
+		cancel()
+	}
+}
+
+func TestRateLimitKeysOnForwardedClientIP(t *testing.T) {
+	previewFrom := func(app *fiber.App, ip string) int {
+		req := httptest.NewRequest("GET", "/api/preview/missing", nil)
+		req.Header.Set(fiber.HeaderXForwardedFor, ip+", 10.0.0.1")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+
+	// 未开启时不限流
+	app, _ := newRoutedApp(t, nil)
+	for i := 0; i < 3; i++ {
+		if status := previewFrom(app, "203.0.113.1"); status != fiber.StatusNotFound {
+			t.Fatalf("RATE_LIMIT_RPM unset: request %d status = %d, want 404", i, status)
+		}
+	}
+
+	// 代理之后的不同访客各自计数
+	app, _ = newRoutedApp(t, func(cfg *config.Config) {
+		cfg.RateLimitRPM = 1
+		cfg.ProxyHeader = fiber.HeaderXForwardedFor
+	})
+	if status := previewFrom(app, "203.0.113.1"); status != fiber.StatusNotFound {
+		t.Fatalf("first client: status = %d, want 404", status)
+	}
+	if status := previewFrom(app, "203.0.113.1"); status != fiber.StatusTooManyRequests {
+		t.Fatalf("first client again: status = %d, want 429", status)
+	}
+	if status := previewFrom(app, "198.51.100.2"); status != fiber.StatusNotFound {
+		t.Fatalf("second client shares the first client's bucket: status = %d, want 404", status)
+	}
+
+	// 请求不是来自受信任代理时忽略转发头，按连接地址计数
+	app, _ = newRoutedApp(t, func(cfg *config.Config) {
+		cfg.RateLimitRPM = 1
+		cfg.ProxyHeader = fiber.HeaderXForwardedFor
+		cfg.TrustedProxies = []string{"192.0.2.10"}
+	})
+	if status := previewFrom(app, "203.0.113.1"); status != fiber.StatusNotFound {
+		t.Fatalf("untrusted proxy, first request: status = %d, want 404", status)
+	}
+	if status := previewFrom(app, "198.51.100.2"); status != fiber.StatusTooManyRequests {
+		t.Fatalf("untrusted proxy: forwarded header was honoured, status = %d, want 429", status)
+	}
+}
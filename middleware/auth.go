@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"errors"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
@@ -9,6 +10,9 @@ import (
 
 var jwtSecret []byte // 改为可配置的密钥
 
+// TokenCookieName 登录后写入的令牌Cookie名称
+const TokenCookieName = "token"
+
 // SetJWTSecret 设置JWT密钥
 func SetJWTSecret(secret string) {
 	jwtSecret = []byte(secret)
@@ -26,36 +30,72 @@ func JWTMiddleware() fiber.Handler {
 
 		tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
 
-		// 使用 MapClaims 解析token，与 AuthService 保持一致
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return jwtSecret, nil
-		})
-
-		if err != nil || !token.Valid {
+		claims, err := parseToken(tokenString)
+		if err != nil {
 			return c.Status(401).JSON(fiber.Map{
-				"error": "Invalid token",
-			})
-		}
-
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			return c.Status(401).JSON(fiber.Map{
-				"error": "Invalid token claims",
+				"error": err.Error(),
 			})
 		}
 
 		// 将用户信息存储到上下文中（移除user_id）
-		if username, ok := claims["username"].(string); ok {
-			c.Locals("username", username)
+		setUserLocals(c, claims)
+
+		return c.Next()
+	}
+}
+
+// OptionalJWTMiddleware 可选的JWT解析中间件，令牌缺失或无效时不拦截请求
+// 令牌可以来自 Authorization 头或登录时写入的 token Cookie
+func OptionalJWTMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		tokenString := strings.Replace(c.Get("Authorization"), "Bearer ", "", 1)
+		if tokenString == "" {
+			tokenString = c.Cookies(TokenCookieName)
 		}
-		if role, ok := claims["role"].(string); ok {
-			c.Locals("role", role)
+		if tokenString == "" {
+			return c.Next()
 		}
 
+		if claims, err := parseToken(tokenString); err == nil {
+			setUserLocals(c, claims)
+		}
 		return c.Next()
 	}
 }
 
+// parseToken 解析并校验JWT令牌
+func parseToken(tokenString string) (jwt.MapClaims, error) {
+	// 使用 MapClaims 解析token，与 AuthService 保持一致
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return jwtSecret, nil
+	})
+
+	if err != nil || !token.Valid {
+		return nil, errors.New("Invalid token")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("Invalid token claims")
+	}
+
+	// 刷新令牌只能用于 /api/refresh，不能作为访问令牌
+	if tokenType, _ := claims["type"].(string); tokenType == "refresh" {
+		return nil, errors.New("Invalid token type")
+	}
+	return claims, nil
+}
+
+// setUserLocals 将令牌中的用户信息存储到上下文中
+func setUserLocals(c *fiber.Ctx, claims jwt.MapClaims) {
+	if username, ok := claims["username"].(string); ok {
+		c.Locals("username", username)
+	}
+	if role, ok := claims["role"].(string); ok {
+		c.Locals("role", role)
+	}
+}
+
 // AdminMiddleware 管理员权限中间件
 func AdminMiddleware() fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+// BodyLimit 按路径限制请求体大小：overrides 中的路径（如批量导入）使用各自的上限，其他请求使用 limit
+// 应用级的 fiber.Config.BodyLimit 需不小于所有上限，它在读取请求体时就会拒绝更大的请求
+func BodyLimit(limit int, overrides map[string]int) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		max := limit
+		if override, ok := overrides[c.Path()]; ok {
+			max = override
+		}
+		if max > 0 && len(c.Body()) > max {
+			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+				"error": "Request body too large",
+			})
+		}
+		return c.Next()
+	}
+}
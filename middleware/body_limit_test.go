@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func newBodyLimitApp() *fiber.App {
+	app := fiber.New(fiber.Config{BodyLimit: 1024})
+	app.Use(BodyLimit(64, map[string]int{"/import": 1024}))
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) }
+	app.Post("/create", ok)
+	app.Post("/import", ok)
+	return app
+}
+
+func postBody(t *testing.T, app *fiber.App, path string, size int) int {
+	t.Helper()
+	req := httptest.NewRequest("POST", path, strings.NewReader(strings.Repeat("x", size)))
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("POST %s: %v", path, err)
+	}
+	return resp.StatusCode
+}
+
+func TestBodyLimitPerRoute(t *testing.T) {
+	app := newBodyLimitApp()
+
+	tests := []struct {
+		path string
+		size int
+		want int
+	}{
+		{"/create", 64, fiber.StatusOK},
+		{"/create", 65, fiber.StatusRequestEntityTooLarge},
+		// 导入路由使用更大的上限
+		{"/import", 512, fiber.StatusOK},
+		{"/import", 1024, fiber.StatusOK},
+	}
+	for _, tt := range tests {
+		if got := postBody(t, app, tt.path, tt.size); got != tt.want {
+			t.Errorf("POST %s with %d bytes = %d, want %d", tt.path, tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestBodyLimitAppLimitRejectsOversizedImport(t *testing.T) {
+	app := newBodyLimitApp()
+	// 超过应用级上限的请求在读取请求体时被拒绝，app.Test 以错误的形式返回
+	req := httptest.NewRequest("POST", "/import", strings.NewReader(strings.Repeat("x", 2048)))
+	resp, err := app.Test(req)
+	if err == nil && resp.StatusCode != fiber.StatusRequestEntityTooLarge {
+		t.Errorf("oversized import = %d, want rejection", resp.StatusCode)
+	}
+}
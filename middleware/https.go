@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HTTPSRedirect 将HTTP请求重定向到HTTPS
+func HTTPSRedirect(httpsPort string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		host := c.Hostname()
+		// 去掉请求中的端口，使用HTTPS端口
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != "" && httpsPort != "443" {
+			host = net.JoinHostPort(strings.Trim(host, "[]"), httpsPort)
+		}
+		// 请求行可能是绝对形式（GET http://host/path），只保留路径和查询参数
+		return c.Redirect("https://"+host+string(c.Request().URI().RequestURI()), fiber.StatusMovedPermanently)
+	}
+}
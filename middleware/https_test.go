@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestHTTPSRedirect(t *testing.T) {
+	cases := []struct {
+		port   string
+		host   string
+		target string
+		want   string
+	}{
+		{port: "443", host: "s.example:80", target: "/abc?x=1", want: "https://s.example/abc?x=1"},
+		{port: "8443", host: "s.example:8080", target: "/abc", want: "https://s.example:8443/abc"},
+		{port: "8443", host: "[::1]:8080", target: "/", want: "https://[::1]:8443/"},
+		// 绝对形式的请求行
+		{port: "443", target: "http://s.example/abc?x=1", want: "https://s.example/abc?x=1"},
+	}
+	for _, tc := range cases {
+		app := fiber.New()
+		app.Use(HTTPSRedirect(tc.port))
+
+		req := httptest.NewRequest("GET", tc.target, nil)
+		if tc.host != "" {
+			req.Host = tc.host
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusMovedPermanently {
+			t.Errorf("%s: status = %d, want 301", tc.target, resp.StatusCode)
+		}
+		if got := resp.Header.Get("Location"); got != tc.want {
+			t.Errorf("%s: Location = %q, want %q", tc.target, got, tc.want)
+		}
+	}
+}
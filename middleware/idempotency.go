@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/justseemore/surl/cache"
+)
+
+// IdempotencyHeader 客户端重试时携带相同值的请求头
+const IdempotencyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength 幂等键的最大长度
+const maxIdempotencyKeyLength = 255
+
+// IdempotencyOptions 幂等配置
+type IdempotencyOptions struct {
+	Store *cache.Manager // 结果存储（Redis可用时使用Redis，否则使用内存）
+	TTL   time.Duration  // 结果保留时间，默认24小时
+}
+
+// Idempotency 带 Idempotency-Key 的请求只处理一次，之后相同键的请求直接返回首次的响应
+// 键按登录用户隔离，需放在JWT中间件之后；首次处理失败（5xx）时释放键，允许重试
+func Idempotency(opts IdempotencyOptions) fiber.Handler {
+	if opts.TTL <= 0 {
+		opts.TTL = 24 * time.Hour
+	}
+
+	return func(c *fiber.Ctx) error {
+		key := c.Get(IdempotencyHeader)
+		if key == "" || opts.Store == nil {
+			return c.Next()
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Idempotency-Key too long",
+			})
+		}
+
+		scope, _ := c.Locals("username").(string)
+		sum := sha256.Sum256(c.Body())
+		requestHash := hex.EncodeToString(sum[:])
+
+		if !opts.Store.ReserveIdempotent(scope, key, requestHash, opts.TTL) {
+			return replayIdempotent(c, opts.Store, scope, key, requestHash)
+		}
+
+		err := c.Next()
+		status := c.Response().StatusCode()
+		if err != nil || status >= 500 {
+			opts.Store.ReleaseIdempotent(scope, key)
+			return err
+		}
+
+		opts.Store.SetIdempotent(scope, key, cache.IdempotentResponse{
+			RequestHash: requestHash,
+			Status:      status,
+			ContentType: string(c.Response().Header.ContentType()),
+			Body:        append([]byte(nil), c.Response().Body()...),
+		}, opts.TTL)
+		return nil
+	}
+}
+
+// replayIdempotent 返回已使用的幂等键对应的首次响应
+func replayIdempotent(c *fiber.Ctx, store *cache.Manager, scope, key, requestHash string) error {
+	resp, found := store.GetIdempotent(scope, key)
+	if !found || resp.Pending() {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "A request with this Idempotency-Key is still being processed",
+		})
+	}
+	if resp.RequestHash != requestHash {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": "Idempotency-Key was already used with a different request body",
+		})
+	}
+
+	c.Set("Idempotent-Replayed", "true")
+	c.Set(fiber.HeaderContentType, resp.ContentType)
+	return c.Status(resp.Status).Send(resp.Body)
+}
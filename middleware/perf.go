@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// perfWindowSize 每个路由保留的最近请求样本数
+const perfWindowSize = 1024
+
+// PerfStat 单个路由的延迟/吞吐统计
+type PerfStat struct {
+	Route      string  `json:"route"`
+	Count      int64   `json:"count"`       // 累计请求数
+	Errors     int64   `json:"errors"`      // 累计5xx响应数
+	Samples    int     `json:"samples"`     // 参与延迟计算的样本数
+	AvgMs      float64 `json:"avg_ms"`      // 平均延迟
+	P50Ms      float64 `json:"p50_ms"`      // 中位数
+	P95Ms      float64 `json:"p95_ms"`      // 95分位
+	P99Ms      float64 `json:"p99_ms"`      // 99分位
+	MaxMs      float64 `json:"max_ms"`      // 最大延迟
+	LastMinute int     `json:"last_minute"` // 最近一分钟的请求数
+}
+
+type perfSample struct {
+	at       time.Time
+	duration time.Duration
+}
+
+// perfRing 固定大小的环形缓冲区，只保留最近的样本
+type perfRing struct {
+	samples []perfSample
+	next    int
+	count   int64
+	errors  int64
+}
+
+// PerfTracker 进程内的按路由延迟统计（Prefork模式下每个子进程各自统计）
+type PerfTracker struct {
+	routes map[string]*perfRing
+	mutex  sync.Mutex
+}
+
+// NewPerfTracker 创建延迟统计器
+func NewPerfTracker() *PerfTracker {
+	return &PerfTracker{
+		routes: make(map[string]*perfRing),
+	}
+}
+
+// Middleware 记录每个请求的处理耗时，按 "方法 路由模板" 归类
+func (t *PerfTracker) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			if e, ok := err.(*fiber.Error); ok {
+				status = e.Code
+			} else {
+				status = fiber.StatusInternalServerError
+			}
+		}
+		// 使用路由模板而不是原始路径，避免短代码等参数导致键无限增长
+		t.record(c.Method()+" "+c.Route().Path, start, time.Since(start), status >= 500)
+		return err
+	}
+}
+
+func (t *PerfTracker) record(route string, at time.Time, duration time.Duration, failed bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	ring, ok := t.routes[route]
+	if !ok {
+		ring = &perfRing{samples: make([]perfSample, 0, perfWindowSize)}
+		t.routes[route] = ring
+	}
+
+	sample := perfSample{at: at, duration: duration}
+	if len(ring.samples) < perfWindowSize {
+		ring.samples = append(ring.samples, sample)
+	} else {
+		ring.samples[ring.next] = sample
+	}
+	ring.next = (ring.next + 1) % perfWindowSize
+	ring.count++
+	if failed {
+		ring.errors++
+	}
+}
+
+// Snapshot 返回所有路由的统计，按平均延迟从高到低排序
+func (t *PerfTracker) Snapshot() []PerfStat {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	since := time.Now().Add(-time.Minute)
+	stats := make([]PerfStat, 0, len(t.routes))
+	for route, ring := range t.routes {
+		durations := make([]time.Duration, len(ring.samples))
+		var total time.Duration
+		lastMinute := 0
+		for i, sample := range ring.samples {
+			durations[i] = sample.duration
+			total += sample.duration
+			if sample.at.After(since) {
+				lastMinute++
+			}
+		}
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+		stat := PerfStat{
+			Route:      route,
+			Count:      ring.count,
+			Errors:     ring.errors,
+			Samples:    len(durations),
+			LastMinute: lastMinute,
+		}
+		if n := len(durations); n > 0 {
+			stat.AvgMs = toMs(total / time.Duration(n))
+			stat.P50Ms = toMs(percentile(durations, 0.50))
+			stat.P95Ms = toMs(percentile(durations, 0.95))
+			stat.P99Ms = toMs(percentile(durations, 0.99))
+			stat.MaxMs = toMs(durations[n-1])
+		}
+		stats = append(stats, stat)
+	}
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].AvgMs > stats[j].AvgMs })
+	return stats
+}
+
+// percentile 从已排序的样本中取分位数
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	idx := int(float64(len(sorted)-1) * p)
+	return sorted[idx]
+}
+
+func toMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func perfStat(t *testing.T, tracker *PerfTracker, route string) PerfStat {
+	t.Helper()
+	for _, stat := range tracker.Snapshot() {
+		if stat.Route == route {
+			return stat
+		}
+	}
+	t.Fatalf("no perf entry for %q in %+v", route, tracker.Snapshot())
+	return PerfStat{}
+}
+
+func TestPerfTrackerRecordsRoutes(t *testing.T) {
+	tracker := NewPerfTracker()
+	app := fiber.New()
+	app.Use(tracker.Middleware())
+	app.Get("/slow/:code", func(c *fiber.Ctx) error {
+		time.Sleep(5 * time.Millisecond)
+		return c.SendString(c.Params("code"))
+	})
+	app.Get("/fail", func(c *fiber.Ctx) error {
+		return fiber.NewError(fiber.StatusBadGateway, "upstream")
+	})
+
+	for _, path := range []string{"/slow/a", "/slow/b", "/slow/c", "/fail"} {
+		if _, err := app.Test(httptest.NewRequest("GET", path, nil), -1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 不同参数的请求归入同一个路由模板
+	slow := perfStat(t, tracker, "GET /slow/:code")
+	if slow.Count != 3 || slow.Samples != 3 || slow.LastMinute != 3 || slow.Errors != 0 {
+		t.Fatalf("slow entry = %+v", slow)
+	}
+	if slow.P50Ms < 5 || slow.MaxMs < slow.P50Ms || slow.AvgMs < 5 {
+		t.Fatalf("slow latencies = %+v, want at least 5ms", slow)
+	}
+	if fail := perfStat(t, tracker, "GET /fail"); fail.Count != 1 || fail.Errors != 1 {
+		t.Fatalf("fail entry = %+v", fail)
+	}
+	// 按平均延迟从高到低排序
+	if first := tracker.Snapshot()[0]; first.Route != "GET /slow/:code" {
+		t.Fatalf("first entry = %q, want the slowest route", first.Route)
+	}
+}
+
+func TestPerfRingIsBounded(t *testing.T) {
+	tracker := NewPerfTracker()
+	old := time.Now().Add(-time.Hour)
+	for i := 0; i < perfWindowSize; i++ {
+		tracker.record("GET /x", old, time.Second, false)
+	}
+	for i := 0; i < 10; i++ {
+		tracker.record("GET /x", time.Now(), time.Millisecond, false)
+	}
+
+	stat := perfStat(t, tracker, "GET /x")
+	if stat.Count != perfWindowSize+10 || stat.Samples != perfWindowSize {
+		t.Fatalf("count/samples = %d/%d, want %d/%d", stat.Count, stat.Samples, perfWindowSize+10, perfWindowSize)
+	}
+	if stat.LastMinute != 10 {
+		t.Fatalf("last_minute = %d, want 10", stat.LastMinute)
+	}
+	// 最旧的样本被覆盖
+	if stat.P50Ms != 1000 || stat.MaxMs != 1000 {
+		t.Fatalf("p50/max = %v/%v", stat.P50Ms, stat.MaxMs)
+	}
+	if want := (float64(perfWindowSize-10)*1000 + 10) / perfWindowSize; stat.AvgMs < want-1 || stat.AvgMs > want+1 {
+		t.Fatalf("avg = %v, want about %v", stat.AvgMs, want)
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/justseemore/surl/cache"
+)
+
+// RateLimiterOptions 限流配置
+type RateLimiterOptions struct {
+	Store     *cache.Manager // 计数存储（Redis可用时使用Redis，否则使用内存）
+	Max       int            // 每个窗口允许的最大请求数，<=0 时不限流
+	Window    time.Duration  // 时间窗口，默认1分钟
+	KeyPrefix string         // 计数键前缀，用于区分不同的路由组
+}
+
+// RateLimiter 按客户端IP限流的中间件
+// 使用 Store.IncrWindow 做固定窗口计数（不是令牌桶），窗口边界前后最多可连续放行 2*Max 个请求。
+// 客户端IP取自 c.IP()，部署在反向代理之后时需配置 PROXY_HEADER，否则所有访客共用代理的计数。
+func RateLimiter(opts RateLimiterOptions) fiber.Handler {
+	if opts.Window <= 0 {
+		opts.Window = time.Minute
+	}
+
+	return func(c *fiber.Ctx) error {
+		if opts.Max <= 0 || opts.Store == nil {
+			return c.Next()
+		}
+
+		key := "ratelimit:" + opts.KeyPrefix + ":" + c.IP()
+		count, remaining := opts.Store.IncrWindow(key, opts.Window)
+		if count > int64(opts.Max) {
+			retryAfter := int(math.Ceil(remaining.Seconds()))
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error": "Too many requests",
+			})
+		}
+
+		return c.Next()
+	}
+}
@@ -15,6 +15,49 @@ type UAInfo struct {
     IsWeChat   bool  // 新增：微信检测
     IsQQ       bool  // 新增：QQ检测
     NeedsBlock bool  // 新增：是否需要拦截
+    IsWebView  bool   // App内置浏览器（非独立浏览器），含微信和QQ
+    InAppName  string // 识别出的App名称，通用WebView为空
+}
+
+// inAppMarkers 常见App内置浏览器的UA标识（小写）
+var inAppMarkers = []struct {
+    marker string
+    name   string
+}{
+    {"fban/", "Facebook"},
+    {"fbav/", "Facebook"},
+    {"fb_iab", "Facebook"},
+    {"instagram", "Instagram"},
+    {"line/", "LINE"},
+    {"twitter", "Twitter"},
+    {"bytedancewebview", "TikTok"},
+    {"musical_ly", "TikTok"},
+    {"snapchat", "Snapchat"},
+    {"linkedinapp", "LinkedIn"},
+    {"pinterest", "Pinterest"},
+    {"weibo", "微博"},
+    {"dingtalk", "钉钉"},
+    {"alipayclient", "支付宝"},
+    {"aweme", "抖音"},
+}
+
+// detectWebView 检测App内置浏览器，返回是否为WebView及识别出的App名称
+func detectWebView(userAgent string) (bool, string) {
+    for _, m := range inAppMarkers {
+        if strings.Contains(userAgent, m.marker) {
+            return true, m.name
+        }
+    }
+    // Android 系统WebView 带有 "; wv)" 标识
+    if strings.Contains(userAgent, "; wv)") {
+        return true, ""
+    }
+    // iOS 上的独立浏览器（Safari、Chrome、Firefox等）都带有 Safari/ 标识，WKWebView 没有
+    if (strings.Contains(userAgent, "iphone") || strings.Contains(userAgent, "ipad")) &&
+        strings.Contains(userAgent, "applewebkit") && !strings.Contains(userAgent, "safari/") {
+        return true, ""
+    }
+    return false, ""
 }
 
 func UADetector() fiber.Handler {
@@ -36,6 +79,11 @@ func UADetector() fiber.Handler {
             uaInfo.IsQQ = true
             uaInfo.NeedsBlock = true
         }
+        if uaInfo.IsWeChat || uaInfo.IsQQ {
+            uaInfo.IsWebView = true
+        } else {
+            uaInfo.IsWebView, uaInfo.InAppName = detectWebView(userAgent)
+        }
         
         // 检测设备类型
         if strings.Contains(userAgent, "mobile") || strings.Contains(userAgent, "android") || strings.Contains(userAgent, "iphone") {
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	chromeAndroidUA  = "Mozilla/5.0 (Linux; Android 13; Pixel 7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36"
+	safariIOSUA      = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1"
+	chromeIOSUA      = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/120.0.6099.119 Mobile/15E148 Safari/604.1"
+	androidWebViewUA = "Mozilla/5.0 (Linux; Android 13; Pixel 7; wv) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/120.0.0.0 Mobile Safari/537.36"
+	iosWebViewUA     = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148"
+	instagramUA      = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 Instagram 312.0.0.0"
+	facebookUA       = "Mozilla/5.0 (Linux; Android 13; Pixel 7; wv) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/120.0.0.0 Mobile Safari/537.36 [FB_IAB/FB4A;FBAV/442.0.0.0;]"
+	weChatUA         = "Mozilla/5.0 (iPhone; CPU iPhone OS 16_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 MicroMessenger/8.0.38"
+)
+
+// detect 通过 UADetector 中间件解析 userAgent
+func detect(t *testing.T, userAgent string) *UAInfo {
+	t.Helper()
+	var info *UAInfo
+	app := fiber.New()
+	app.Use(UADetector())
+	app.Get("/", func(c *fiber.Ctx) error {
+		info = c.Locals("uaInfo").(*UAInfo)
+		return nil
+	})
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("User-Agent", userAgent)
+	if _, err := app.Test(req); err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+func TestUADetectorWebView(t *testing.T) {
+	cases := []struct {
+		ua         string
+		webView    bool
+		needsBlock bool
+		app        string
+	}{
+		{chromeAndroidUA, false, false, ""},
+		{safariIOSUA, false, false, ""},
+		{chromeIOSUA, false, false, ""},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36", false, false, ""},
+		{androidWebViewUA, true, false, ""},
+		{iosWebViewUA, true, false, ""},
+		{instagramUA, true, false, "Instagram"},
+		{facebookUA, true, false, "Facebook"},
+		// 微信和QQ始终拦截，与是否开启通用WebView提示无关
+		{weChatUA, true, true, ""},
+	}
+	for _, tc := range cases {
+		info := detect(t, tc.ua)
+		if info.IsWebView != tc.webView || info.NeedsBlock != tc.needsBlock || info.InAppName != tc.app {
+			t.Errorf("%s: IsWebView=%v NeedsBlock=%v InAppName=%q, want %v %v %q",
+				tc.ua, info.IsWebView, info.NeedsBlock, info.InAppName, tc.webView, tc.needsBlock, tc.app)
+		}
+	}
+}
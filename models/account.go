@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// Account 持久化的账户，首次启动时由 ACCOUNTS 环境变量导入
+type Account struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	Username     string    `json:"username" gorm:"not null;uniqueIndex"`
+	PasswordHash string    `json:"-" gorm:"not null"`
+	Role         string    `json:"role" gorm:"not null"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
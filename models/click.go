@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Click 单次点击的明细记录，用于设备、浏览器、操作系统等维度的统计
+type Click struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ShortCode string    `json:"short_code" gorm:"not null;index:idx_click_code_time"`
+	Device    string    `json:"device" gorm:"size:32"`
+	Browser   string    `json:"browser" gorm:"size:32"`
+	OS        string    `json:"os" gorm:"size:32"`
+	ClickedAt time.Time `json:"clicked_at" gorm:"index:idx_click_code_time"`
+
+	// 抽样记录时每条明细代表的点击数（1/抽样率），统计时按权重求和还原总量
+	Weight float64 `json:"weight" gorm:"not null;default:1"`
+}
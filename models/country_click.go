@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// CountryClick 按国家聚合的点击统计
+type CountryClick struct {
+	ID        uint      `json:"-" gorm:"primaryKey"`
+	ShortCode string    `json:"short_code" gorm:"not null;uniqueIndex:idx_country_click"`
+	Country   string    `json:"country" gorm:"not null;size:8;uniqueIndex:idx_country_click"`
+	Count     int64     `json:"count" gorm:"default:0"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
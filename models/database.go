@@ -1,10 +1,14 @@
 package models
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"os"
-	"syscall"
+	"time"
 
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -12,23 +16,81 @@ import (
 
 var DB *gorm.DB
 
-func InitDatabase(dbPath string) error {
-	lockFile := dbPath + ".lock"
+// Now 返回写入数据库或与数据库时间比较时使用的当前时间
+// 所有时间统一以UTC保存：SQLite按文本比较时间，混用不同时区偏移会导致比较结果错误
+func Now() time.Time {
+	return time.Now().UTC()
+}
+
+// UTC 将可选时间转换为UTC，nil 保持不变
+func UTC(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
+
+// 支持的数据库驱动
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+	DriverMySQL    = "mysql"
+)
+
+// InitDatabase 按驱动连接数据库并执行迁移
+// SQLite 时 source 为数据库文件路径，其他驱动为连接字符串（DSN）
+func InitDatabase(driver, source string) error {
+	switch driver {
+	case DriverSQLite:
+		return initSQLite(source)
+	case DriverPostgres:
+		return initServerDB(postgres.Open(source), source)
+	case DriverMySQL:
+		// 未指定长度的字符串字段默认映射为 longtext，无法建立索引
+		return initServerDB(mysql.New(mysql.Config{DSN: source, DefaultStringSize: 191}), source)
+	}
+	return fmt.Errorf("不支持的数据库驱动: %s", driver)
+}
+
+// initServerDB 连接数据库服务器，多实例同时启动时由数据库自身处理并发迁移，不需要文件锁
+func initServerDB(dialector gorm.Dialector, dsn string) error {
+	if dsn == "" {
+		return errors.New("未配置数据库连接字符串 DB_DSN")
+	}
+
+	var err error
+	DB, err = gorm.Open(dialector, &gorm.Config{
+		Logger:  logger.Default.LogMode(logger.Warn),
+		NowFunc: Now,
+	})
+	if err != nil {
+		return err
+	}
+	if err := autoMigrate(); err != nil {
+		return err
+	}
+	log.Printf("Connected to %s database", dialector.Name())
+	return nil
+}
+
+// initSQLite 使用文件锁防止多个进程同时创建和迁移同一个数据库文件
+func initSQLite(dbPath string) error {
+	lockPath := dbPath + ".lock"
 
 	// 创建锁文件
-	file, err := os.OpenFile(lockFile, os.O_CREATE|os.O_WRONLY, 0666)
+	file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_WRONLY, 0666)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
-	defer os.Remove(lockFile)
+	defer os.Remove(lockPath)
 
 	// 尝试获取文件锁
-	err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
-	if err != nil {
+	if err := lockFile(file); err != nil {
 		return err
 	}
-	defer syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+	defer unlockFile(file)
 
 	// 检查数据库是否已存在
 	if _, err := os.Stat(dbPath); err == nil {
@@ -43,11 +105,16 @@ func InitDatabase(dbPath string) error {
 func connectToExistingDB(dbPath string) error {
 	var err error
 	DB, err = gorm.Open(sqlite.Open(dbPath), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Warn),
+		Logger:  logger.Default.LogMode(logger.Warn),
+		NowFunc: Now,
 	})
 	if err != nil {
 		return err
 	}
+	// 已有数据库也需要迁移新增的表和字段
+	if err := autoMigrate(); err != nil {
+		return err
+	}
 	log.Println("Connected to existing database")
 	return nil
 }
@@ -55,14 +122,15 @@ func connectToExistingDB(dbPath string) error {
 func createAndInitDB(dbPath string) error {
 	var err error
 	DB, err = gorm.Open(sqlite.Open(dbPath), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Warn),
+		Logger:  logger.Default.LogMode(logger.Warn),
+		NowFunc: Now,
 	})
 	if err != nil {
 		return err
 	}
 
 	// 执行迁移
-	err = DB.AutoMigrate(&URL{})
+	err = autoMigrate()
 	if err != nil {
 		return err
 	}
@@ -70,3 +138,30 @@ func createAndInitDB(dbPath string) error {
 	log.Println("Database created and migrated successfully")
 	return nil
 }
+
+// autoMigrate 迁移所有模型
+func autoMigrate() error {
+	if err := DB.AutoMigrate(&URL{}, &CountryClick{}, &Account{}, &Click{}); err != nil {
+		return err
+	}
+	return backfillHosts()
+}
+
+// backfillHosts 为新增 host 列之前创建的链接补全主机名（含回收站中的链接）
+// 没有主机名的链接（mailto/tel）每次启动都会被重新检查，数量通常很少
+func backfillHosts() error {
+	var urls []URL
+	return DB.Unscoped().Select("id", "original_url").Where("host = '' OR host IS NULL").
+		FindInBatches(&urls, 500, func(tx *gorm.DB, batch int) error {
+			for _, u := range urls {
+				host := HostOf(u.OriginalURL)
+				if host == "" {
+					continue
+				}
+				if err := DB.Unscoped().Model(&URL{}).Where("id = ?", u.ID).UpdateColumn("host", host).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
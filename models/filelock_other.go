@@ -0,0 +1,14 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package models
+
+import "os"
+
+// lockFile 在不支持 flock 的平台（如 Windows）上不加锁，同时启动多个进程时需自行避免并发初始化
+func lockFile(file *os.File) error {
+	return nil
+}
+
+func unlockFile(file *os.File) error {
+	return nil
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+)
+
+// TestBuildsWithoutFlock 确认没有 syscall.Flock 的平台（Windows）也能编译
+func TestBuildsWithoutFlock(t *testing.T) {
+	if testing.Short() {
+		t.Skip("cross-compiles the module")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+	cmd := exec.Command(goTool, "build", "-o", os.DevNull, ".")
+	cmd.Env = append(os.Environ(), "GOOS=windows", "GOARCH=amd64", "CGO_ENABLED=0")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("GOOS=windows go build: %v\n%s", err, out)
+	}
+}
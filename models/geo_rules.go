@@ -0,0 +1,46 @@
+package models
+
+import "database/sql/driver"
+
+// GeoRules 按访客国家限制访问的规则，国家使用 ISO 3166-1 两位代码（大写）
+// 两个列表都为空表示不限制；同时设置时拒绝列表优先
+type GeoRules struct {
+	Allow []string `json:"allow,omitempty"` // 只允许这些国家访问，为空表示允许所有国家
+	Deny  []string `json:"deny,omitempty"`  // 拒绝这些国家访问
+}
+
+// IsEmpty 是否没有任何限制
+func (r GeoRules) IsEmpty() bool {
+	return len(r.Allow) == 0 && len(r.Deny) == 0
+}
+
+// Allows 检查国家能否访问，设置了允许列表时无法识别的国家（空或 unknown）同样被拒绝
+func (r GeoRules) Allows(country string) bool {
+	if containsCountry(r.Deny, country) {
+		return false
+	}
+	return len(r.Allow) == 0 || containsCountry(r.Allow, country)
+}
+
+func containsCountry(countries []string, country string) bool {
+	for _, c := range countries {
+		if c == country {
+			return true
+		}
+	}
+	return false
+}
+
+// Value 实现 driver.Valuer
+func (r GeoRules) Value() (driver.Value, error) {
+	if r.IsEmpty() {
+		return nil, nil
+	}
+	return jsonValue(r)
+}
+
+// Scan 实现 sql.Scanner
+func (r *GeoRules) Scan(value interface{}) error {
+	*r = GeoRules{}
+	return jsonScan(value, r)
+}
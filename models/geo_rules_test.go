@@ -0,0 +1,47 @@
+package models
+
+import "testing"
+
+func TestGeoRulesAllows(t *testing.T) {
+	cases := []struct {
+		name    string
+		rules   GeoRules
+		country string
+		want    bool
+	}{
+		{"no rules", GeoRules{}, "US", true},
+		{"no rules unknown", GeoRules{}, "unknown", true},
+		{"allowed", GeoRules{Allow: []string{"CN", "HK"}}, "HK", true},
+		{"not allowed", GeoRules{Allow: []string{"CN", "HK"}}, "US", false},
+		{"allow list rejects unknown", GeoRules{Allow: []string{"CN"}}, "unknown", false},
+		{"denied", GeoRules{Deny: []string{"RU"}}, "RU", false},
+		{"not denied", GeoRules{Deny: []string{"RU"}}, "US", true},
+		{"deny list allows unknown", GeoRules{Deny: []string{"RU"}}, "unknown", true},
+		{"deny wins", GeoRules{Allow: []string{"US"}, Deny: []string{"US"}}, "US", false},
+	}
+	for _, tc := range cases {
+		if got := tc.rules.Allows(tc.country); got != tc.want {
+			t.Errorf("%s: Allows(%q) = %v, want %v", tc.name, tc.country, got, tc.want)
+		}
+	}
+}
+
+func TestGeoRulesValueRoundTrip(t *testing.T) {
+	empty, err := GeoRules{}.Value()
+	if err != nil || empty != nil {
+		t.Fatalf("empty rules Value() = %v, %v; want NULL", empty, err)
+	}
+
+	rules := GeoRules{Allow: []string{"CN"}, Deny: []string{"RU"}}
+	value, err := rules.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var scanned GeoRules
+	if err := scanned.Scan(value); err != nil {
+		t.Fatal(err)
+	}
+	if !scanned.Allows("CN") || scanned.Allows("RU") || scanned.Allows("US") {
+		t.Fatalf("scanned rules = %+v, want %+v", scanned, rules)
+	}
+}
@@ -0,0 +1,178 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// 来源规则中的特殊匹配值
+const (
+	RefererMatchAny  = "*"    // 任意带来源的请求
+	RefererMatchNone = "none" // 没有来源的请求（直接访问、App内打开等）
+)
+
+// RefererRule 按来源选择跳转目标的规则
+type RefererRule struct {
+	Match  string `json:"match"`            // 来源域名（同时匹配子域名），或 "*"、"none"
+	Target string `json:"target,omitempty"` // 命中时的跳转目标
+	Block  bool   `json:"block,omitempty"`  // 命中时拒绝访问（用于防盗链），与 Target 二选一
+}
+
+// Matches 检查规则是否匹配来源域名（已转为小写，无来源时为空）
+func (r RefererRule) Matches(host string) bool {
+	switch r.Match {
+	case RefererMatchNone:
+		return host == ""
+	case RefererMatchAny:
+		return host != ""
+	}
+	return host == r.Match || strings.HasSuffix(host, "."+r.Match)
+}
+
+// RefererRules 来源规则列表，以JSON保存在一列中，按顺序匹配
+type RefererRules []RefererRule
+
+// Value 实现 driver.Valuer
+func (r RefererRules) Value() (driver.Value, error) {
+	if len(r) == 0 {
+		return nil, nil
+	}
+	return jsonValue(r)
+}
+
+// Scan 实现 sql.Scanner
+func (r *RefererRules) Scan(value interface{}) error {
+	*r = nil
+	return jsonScan(value, r)
+}
+
+// RefererRuleFor 返回第一条匹配来源的规则，没有匹配时返回nil（使用默认目标）
+func (u *URL) RefererRuleFor(referer string) *RefererRule {
+	if len(u.RefererRules) == 0 {
+		return nil
+	}
+
+	host := ""
+	if referer != "" {
+		if parsed, err := url.Parse(referer); err == nil {
+			host = strings.ToLower(parsed.Hostname())
+		}
+	}
+	for i := range u.RefererRules {
+		if u.RefererRules[i].Matches(host) {
+			return &u.RefererRules[i]
+		}
+	}
+	return nil
+}
+
+// ScheduleRule 按时间段选择跳转目标的规则（如工作时间与非工作时间）
+type ScheduleRule struct {
+	Days   []time.Weekday `json:"days,omitempty"` // 生效的星期（0为周日），为空表示每天
+	Start  string         `json:"start"`          // 开始时间 HH:MM（含）
+	End    string         `json:"end"`            // 结束时间 HH:MM（不含），早于开始时间表示跨越午夜
+	Target string         `json:"target"`
+}
+
+// Matches 检查规则是否覆盖指定时刻，t 应已转换到配置的时区
+// 跨越午夜的时间段按开始当天的星期判断
+func (r ScheduleRule) Matches(t time.Time) bool {
+	start, err := ParseClock(r.Start)
+	if err != nil {
+		return false
+	}
+	end, err := ParseClock(r.End)
+	if err != nil {
+		return false
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	switch {
+	case start == end:
+		return false
+	case start < end:
+		if minute < start || minute >= end {
+			return false
+		}
+	case minute >= start:
+		// 跨越午夜，处于开始当天
+	case minute < end:
+		// 跨越午夜，处于次日，按前一天判断星期
+		day = (day + 6) % 7
+	default:
+		return false
+	}
+	return r.hasDay(day)
+}
+
+func (r ScheduleRule) hasDay(day time.Weekday) bool {
+	if len(r.Days) == 0 {
+		return true
+	}
+	for _, d := range r.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseClock 解析 HH:MM 格式的时间，返回当天的分钟数
+func ParseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("时间格式应为 HH:MM: %s", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// ScheduleRules 时间段规则列表，以JSON保存在一列中，按顺序匹配
+type ScheduleRules []ScheduleRule
+
+// Value 实现 driver.Valuer
+func (r ScheduleRules) Value() (driver.Value, error) {
+	if len(r) == 0 {
+		return nil, nil
+	}
+	return jsonValue(r)
+}
+
+// Scan 实现 sql.Scanner
+func (r *ScheduleRules) Scan(value interface{}) error {
+	*r = nil
+	return jsonScan(value, r)
+}
+
+// ScheduleRuleFor 返回第一条覆盖指定时刻的规则，没有匹配时返回nil（使用默认目标）
+func (u *URL) ScheduleRuleFor(t time.Time) *ScheduleRule {
+	for i := range u.ScheduleRules {
+		if u.ScheduleRules[i].Matches(t) {
+			return &u.ScheduleRules[i]
+		}
+	}
+	return nil
+}
+
+// jsonValue 将规则列表序列化为JSON文本保存
+func jsonValue(v interface{}) (driver.Value, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+// jsonScan 从数据库的JSON文本解析规则列表
+func jsonScan(value interface{}, dest interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), dest)
+	case []byte:
+		return json.Unmarshal(v, dest)
+	}
+	return fmt.Errorf("无法解析跳转规则: %T", value)
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleRuleMatches(t *testing.T) {
+	// 2024-06-03 是周一
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 6, day, hour, minute, 0, 0, time.UTC)
+	}
+	businessHours := ScheduleRule{Days: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}, Start: "09:00", End: "17:30", Target: "https://example.com/office"}
+	overnight := ScheduleRule{Days: []time.Weekday{time.Friday}, Start: "22:00", End: "02:00", Target: "https://example.com/night"}
+
+	cases := []struct {
+		name string
+		rule ScheduleRule
+		at   time.Time
+		want bool
+	}{
+		{"start instant", businessHours, at(3, 9, 0), true},
+		{"inside", businessHours, at(3, 12, 30), true},
+		{"before start", businessHours, at(3, 8, 59), false},
+		{"end instant", businessHours, at(3, 17, 30), false},
+		{"weekend", businessHours, at(8, 12, 0), false},
+		{"overnight start day", overnight, at(7, 23, 0), true},
+		{"overnight next day", overnight, at(8, 1, 59), true},
+		{"overnight end", overnight, at(8, 2, 0), false},
+		{"overnight other day", overnight, at(6, 23, 0), false},
+		{"overnight next day of other start", overnight, at(7, 1, 0), false},
+		{"every day", ScheduleRule{Start: "00:00", End: "06:00"}, at(9, 5, 0), true},
+		{"empty window", ScheduleRule{Start: "10:00", End: "10:00"}, at(3, 10, 0), false},
+		{"invalid clock", ScheduleRule{Start: "9am", End: "17:00"}, at(3, 12, 0), false},
+	}
+	for _, tc := range cases {
+		if got := tc.rule.Matches(tc.at); got != tc.want {
+			t.Errorf("%s: Matches(%s) = %v, want %v", tc.name, tc.at.Format("Mon 15:04"), got, tc.want)
+		}
+	}
+}
+
+func TestScheduleRuleForFallsBack(t *testing.T) {
+	u := &URL{ScheduleRules: ScheduleRules{
+		{Start: "09:00", End: "12:00", Target: "https://example.com/morning"},
+		{Start: "08:00", End: "18:00", Target: "https://example.com/day"},
+	}}
+	cases := map[int]string{10: "https://example.com/morning", 15: "https://example.com/day", 20: ""}
+	for hour, want := range cases {
+		rule := u.ScheduleRuleFor(time.Date(2024, 6, 3, hour, 0, 0, 0, time.UTC))
+		got := ""
+		if rule != nil {
+			got = rule.Target
+		}
+		if got != want {
+			t.Errorf("%02d:00: target = %q, want %q", hour, got, want)
+		}
+	}
+	if (&URL{}).ScheduleRuleFor(time.Now()) != nil {
+		t.Error("link without rules matched a rule")
+	}
+}
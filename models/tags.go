@@ -0,0 +1,51 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+)
+
+// Tags 链接的标签，保存为以逗号包围的文本（如 ",a,b,"），便于按 LIKE '%,a,%' 查询
+// 标签由服务层规范化，不包含逗号
+type Tags []string
+
+// Value 实现 driver.Valuer
+func (t Tags) Value() (driver.Value, error) {
+	if len(t) == 0 {
+		return "", nil
+	}
+	return "," + strings.Join(t, ",") + ",", nil
+}
+
+// Scan 实现 sql.Scanner
+func (t *Tags) Scan(value interface{}) error {
+	var text string
+	switch v := value.(type) {
+	case nil:
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	default:
+		return fmt.Errorf("无法解析标签: %T", value)
+	}
+
+	*t = nil
+	for _, tag := range strings.Split(text, ",") {
+		if tag != "" {
+			*t = append(*t, tag)
+		}
+	}
+	return nil
+}
+
+// Has 检查是否包含指定标签
+func (t Tags) Has(tag string) bool {
+	for _, existing := range t {
+		if existing == tag {
+			return true
+		}
+	}
+	return false
+}
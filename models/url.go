@@ -1,6 +1,8 @@
 package models
 
 import (
+	"net/url"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -17,27 +19,98 @@ type URL struct {
 	CustomDomain string         `json:"custom_domain"`
 	ClickCount   int64          `json:"click_count" gorm:"default:0;index"`
 	IsActive     bool           `json:"is_active" gorm:"default:true;index"`
+	StartsAt     *time.Time     `json:"starts_at" gorm:"index"` // 生效时间，为空表示立即生效
 	ExpiresAt    *time.Time     `json:"expires_at" gorm:"index"`
+	MaxClicks    *int64         `json:"max_clicks"`                       // 最大点击次数，达到后视为过期，为空表示不限制
+	MaxUniqueIPs *int64         `json:"max_unique_ips"`                   // 独立访客IP上限，超出后新访客无法访问，为空表示不限制
+	RedirectType int            `json:"redirect_type" gorm:"default:302"` // 跳转状态码：301/302/307/308
 	CreatedBy    string         `json:"created_by" gorm:"not null;index"`
 	CreatedAt    time.Time      `json:"created_at" gorm:"index"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index;uniqueIndex:idx_short_code_deleted"`
+
+	// 跳转规则，按顺序匹配，均未命中时跳转到 OriginalURL
+	RefererRules  RefererRules  `json:"referer_rules,omitempty" gorm:"type:text"`  // 按来源
+	ScheduleRules ScheduleRules `json:"schedule_rules,omitempty" gorm:"type:text"` // 按时间段，来源规则优先
+
+	Tags Tags `json:"tags,omitempty" gorm:"type:text"` // 规范化后的标签，用于分组管理
+
+	GeoRules GeoRules `json:"geo_rules" gorm:"type:text"` // 按访客国家限制访问，需要配置GeoIP
+
+	Host string `json:"host" gorm:"index"` // 目标地址的主机名（小写，不含端口），mailto/tel 等链接为空
+}
+
+// 链接的实际状态，综合启用状态和过期时间
+const (
+	StatusActive   = "active"   // 启用且未过期
+	StatusInactive = "inactive" // 已禁用
+	StatusExpired  = "expired"  // 已过期（含达到最大点击次数）
+)
+
+// EffectiveStatus 返回链接的实际状态：禁用优先于过期
+func (u *URL) EffectiveStatus() string {
+	return u.EffectiveStatusAt(time.Now())
+}
+
+// EffectiveStatusAt 返回链接在指定时刻的实际状态
+func (u *URL) EffectiveStatusAt(now time.Time) string {
+	if !u.IsActive {
+		return StatusInactive
+	}
+	if u.IsExpiredAt(now) || (u.MaxClicks != nil && u.ClickCount >= *u.MaxClicks) {
+		return StatusExpired
+	}
+	return StatusActive
+}
+
+// IsValidRedirectType 检查跳转状态码是否受支持
+func IsValidRedirectType(code int) bool {
+	switch code {
+	case 301, 302, 307, 308:
+		return true
+	}
+	return false
 }
 
 // IsExpired 检查链接是否过期
 func (u *URL) IsExpired() bool {
+	return u.IsExpiredAt(time.Now())
+}
+
+// IsExpiredAt 检查链接在指定时刻是否已过期
+func (u *URL) IsExpiredAt(now time.Time) bool {
 	if u.ExpiresAt == nil {
 		return false
 	}
-	return time.Now().After(*u.ExpiresAt)
+	return now.After(*u.ExpiresAt)
+}
+
+// IsStarted 检查链接是否已到生效时间（生效时刻本身视为已生效）
+func (u *URL) IsStarted() bool {
+	if u.StartsAt == nil {
+		return true
+	}
+	return !time.Now().Before(*u.StartsAt)
+}
+
+// HostOf 返回目标地址的主机名（小写，不含端口），无法解析或没有主机名时返回空
+func HostOf(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsed.Hostname())
 }
 
-// GetFullURL 获取完整的短链接URL
-func (u *URL) GetFullURL(domain string) string {
+// GetFullURL 获取完整的短链接URL，scheme为空时使用https
+func (u *URL) GetFullURL(scheme, domain string) string {
+	if scheme == "" {
+		scheme = "https"
+	}
 	if u.CustomDomain != "" {
-		return "https://" + u.CustomDomain + "/" + u.ShortCode
+		domain = u.CustomDomain
 	}
-	return "https://" + domain + "/" + u.ShortCode
+	return scheme + "://" + domain + "/" + u.ShortCode
 }
 
 // 移除ClickStat结构体和相关函数
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsExpiredAndEffectiveStatusAt(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Second), now.Add(time.Second)
+	limit := int64(5)
+
+	cases := []struct {
+		name    string
+		url     URL
+		expired bool
+		status  string
+	}{
+		{"no expiry", URL{IsActive: true}, false, StatusActive},
+		{"future expiry", URL{IsActive: true, ExpiresAt: &future}, false, StatusActive},
+		{"expiry instant", URL{IsActive: true, ExpiresAt: &now}, false, StatusActive},
+		{"past expiry", URL{IsActive: true, ExpiresAt: &past}, true, StatusExpired},
+		{"click limit reached", URL{IsActive: true, MaxClicks: &limit, ClickCount: 5}, false, StatusExpired},
+		{"below click limit", URL{IsActive: true, MaxClicks: &limit, ClickCount: 4}, false, StatusActive},
+		{"disabled and expired", URL{IsActive: false, ExpiresAt: &past}, true, StatusInactive},
+	}
+	for _, tc := range cases {
+		if got := tc.url.IsExpiredAt(now); got != tc.expired {
+			t.Errorf("%s: IsExpiredAt = %v, want %v", tc.name, got, tc.expired)
+		}
+		if got := tc.url.EffectiveStatusAt(now); got != tc.status {
+			t.Errorf("%s: EffectiveStatusAt = %q, want %q", tc.name, got, tc.status)
+		}
+	}
+}
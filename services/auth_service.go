@@ -1,16 +1,36 @@
 package services
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/justseemore/surl/cache"
 	"github.com/justseemore/surl/config"
+	"github.com/justseemore/surl/models"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	accessTokenTTL  = 24 * time.Hour     // 访问令牌有效期
+	refreshTokenTTL = 7 * 24 * time.Hour // 刷新令牌有效期
+
+	// TokenTypeRefresh 刷新令牌的 type 声明，访问令牌不携带该声明
+	TokenTypeRefresh = "refresh"
 )
 
 type AuthService struct {
-	config    *config.Config
-	jwtSecret []byte
+	config       *config.Config
+	jwtSecret    []byte
+	cacheManager *cache.Manager
+	db           *gorm.DB
 }
 
 // AuthUser 结构体
@@ -20,26 +40,108 @@ type AuthUser struct {
 }
 
 // NewAuthService 创建认证服务实例
-func NewAuthService(cfg *config.Config) *AuthService {
+func NewAuthService(cfg *config.Config, cacheManager *cache.Manager, db *gorm.DB) *AuthService {
 	return &AuthService{
-		config:    cfg,
-		jwtSecret: []byte(cfg.JWTSecret),
+		config:       cfg,
+		jwtSecret:    []byte(cfg.JWTSecret),
+		cacheManager: cacheManager,
+		db:           db,
 	}
 }
 
-// Login 用户登录验证
-func (s *AuthService) Login(username, password string) (*AuthUser, error) {
-	// 在配置的账户列表中查找匹配的用户
+// SeedAccounts 将 ACCOUNTS 配置的账户导入数据库（已存在的账户不会被覆盖）
+func (s *AuthService) SeedAccounts() error {
 	for _, account := range s.config.Accounts {
-		if account.Username == username && account.Password == password {
-			return &AuthUser{
-				Username: account.Username,
-				Role:     account.Role,
-			}, nil
+		passwordHash := account.Password
+		if !isBcryptHash(passwordHash) {
+			hash, err := HashPassword(account.Password)
+			if err != nil {
+				return fmt.Errorf("生成密码哈希失败 [%s]: %v", account.Username, err)
+			}
+			passwordHash = hash
+		}
+
+		record := models.Account{
+			Username:     account.Username,
+			PasswordHash: passwordHash,
+			Role:         account.Role,
+		}
+		err := s.db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "username"}},
+			DoNothing: true,
+		}).Create(&record).Error
+		if err != nil {
+			return fmt.Errorf("导入账户失败 [%s]: %v", account.Username, err)
 		}
 	}
+	return nil
+}
+
+// findAccount 根据用户名查询账户
+func (s *AuthService) findAccount(username string) (*models.Account, error) {
+	var account models.Account
+	if err := s.db.Where("username = ?", username).First(&account).Error; err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// Login 用户登录验证
+func (s *AuthService) Login(username, password string) (*AuthUser, error) {
+	account, err := s.findAccount(username)
+	if err != nil || !checkPassword(account.PasswordHash, password) {
+		return nil, errors.New("用户名或密码错误")
+	}
+
+	return &AuthUser{
+		Username: account.Username,
+		Role:     account.Role,
+	}, nil
+}
+
+// ChangePassword 校验当前密码后修改密码
+func (s *AuthService) ChangePassword(username, oldPassword, newPassword string) error {
+	account, err := s.findAccount(username)
+	if err != nil {
+		return errors.New("用户不存在")
+	}
+
+	if !checkPassword(account.PasswordHash, oldPassword) {
+		return errors.New("当前密码错误")
+	}
+
+	if len(newPassword) < s.config.MinPasswordLength {
+		return fmt.Errorf("新密码长度不能少于%d个字符", s.config.MinPasswordLength)
+	}
 
-	return nil, errors.New("用户名或密码错误")
+	hash, err := HashPassword(newPassword)
+	if err != nil {
+		return fmt.Errorf("生成密码哈希失败: %v", err)
+	}
+
+	return s.db.Model(account).Update("password_hash", hash).Error
+}
+
+// HashPassword 生成bcrypt密码哈希，可直接用于ACCOUNTS配置
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// isBcryptHash 判断配置的密码是否为bcrypt哈希
+func isBcryptHash(password string) bool {
+	return strings.HasPrefix(password, "$2a$") || strings.HasPrefix(password, "$2b$") || strings.HasPrefix(password, "$2y$")
+}
+
+// checkPassword 校验密码，兼容bcrypt哈希和明文配置（明文使用常量时间比较）
+func checkPassword(stored, password string) bool {
+	if isBcryptHash(stored) {
+		return bcrypt.CompareHashAndPassword([]byte(stored), []byte(password)) == nil
+	}
+	return subtle.ConstantTimeCompare([]byte(stored), []byte(password)) == 1
 }
 
 // GenerateToken 生成JWT令牌
@@ -47,7 +149,28 @@ func (s *AuthService) GenerateToken(user *AuthUser) (string, error) {
 	claims := jwt.MapClaims{
 		"username": user.Username,
 		"role":     user.Role,
-		"exp":      time.Now().Add(time.Hour * 24).Unix(), // 24小时有效期
+		"exp":      time.Now().Add(accessTokenTTL).Unix(), // 24小时有效期
+		"iat":      time.Now().Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(s.jwtSecret)
+}
+
+// GenerateRefreshToken 生成刷新令牌
+// 声明结构：username、role、type（固定为 "refresh"）、jti（随机ID，用于吊销）、exp（7天）、iat
+func (s *AuthService) GenerateRefreshToken(user *AuthUser) (string, error) {
+	jti, err := generateTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	claims := jwt.MapClaims{
+		"username": user.Username,
+		"role":     user.Role,
+		"type":     TokenTypeRefresh,
+		"jti":      jti,
+		"exp":      time.Now().Add(refreshTokenTTL).Unix(),
 		"iat":      time.Now().Unix(),
 	}
 
@@ -55,6 +178,84 @@ func (s *AuthService) GenerateToken(user *AuthUser) (string, error) {
 	return token.SignedString(s.jwtSecret)
 }
 
+// Refresh 使用刷新令牌换取新的访问令牌
+func (s *AuthService) Refresh(refreshToken string) (string, error) {
+	claims, err := s.parseRefreshToken(refreshToken)
+	if err != nil {
+		return "", err
+	}
+
+	username, _ := claims["username"].(string)
+	role, _ := claims["role"].(string)
+	if username == "" || role == "" {
+		return "", errors.New("无效的刷新令牌")
+	}
+
+	// 账户可能已从配置中移除
+	if s.GetAccountInfo(username) == nil {
+		return "", errors.New("用户不存在")
+	}
+
+	return s.GenerateToken(&AuthUser{Username: username, Role: role})
+}
+
+// RevokeRefreshToken 吊销刷新令牌（注销时调用）
+func (s *AuthService) RevokeRefreshToken(refreshToken string) error {
+	claims, err := s.parseRefreshToken(refreshToken)
+	if err != nil {
+		return err
+	}
+
+	jti, _ := claims["jti"].(string)
+	ttl := refreshTokenTTL
+	if exp, ok := claims["exp"].(float64); ok {
+		ttl = time.Until(time.Unix(int64(exp), 0))
+	}
+	s.cacheManager.RevokeToken(jti, ttl)
+	return nil
+}
+
+// parseRefreshToken 解析并校验刷新令牌（签名、有效期、类型和吊销状态）
+func (s *AuthService) parseRefreshToken(refreshToken string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(refreshToken, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("不支持的签名算法")
+		}
+		return s.jwtSecret, nil
+	})
+	if err != nil || !token.Valid {
+		return nil, errors.New("无效的刷新令牌")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("无效的令牌声明")
+	}
+
+	if tokenType, _ := claims["type"].(string); tokenType != TokenTypeRefresh {
+		return nil, errors.New("不是刷新令牌")
+	}
+
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		return nil, errors.New("令牌中缺少jti")
+	}
+	if s.cacheManager.IsTokenRevoked(jti) {
+		return nil, errors.New("刷新令牌已失效")
+	}
+
+	return claims, nil
+}
+
+// generateTokenID 生成随机的令牌ID
+func generateTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
 // ValidateToken 验证JWT令牌
 func (s *AuthService) ValidateToken(tokenString string) (*AuthUser, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
@@ -70,6 +271,11 @@ func (s *AuthService) ValidateToken(tokenString string) (*AuthUser, error) {
 		return nil, errors.New("无效的令牌声明")
 	}
 
+	// 刷新令牌不能当作访问令牌使用
+	if tokenType, _ := claims["type"].(string); tokenType == TokenTypeRefresh {
+		return nil, errors.New("无效的令牌")
+	}
+
 	username, ok := claims["username"].(string)
 	if !ok {
 		return nil, errors.New("令牌中缺少用户名")
@@ -86,6 +292,53 @@ func (s *AuthService) ValidateToken(tokenString string) (*AuthUser, error) {
 	}, nil
 }
 
+// TokenIntrospection 令牌自省结果
+type TokenIntrospection struct {
+	Active    bool   `json:"active"`
+	Username  string `json:"username,omitempty"`
+	Role      string `json:"role,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+}
+
+// IntrospectToken 校验任意令牌并返回其声明，无效或过期的令牌返回 Active=false
+func (s *AuthService) IntrospectToken(tokenString string) *TokenIntrospection {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("不支持的签名算法")
+		}
+		return s.jwtSecret, nil
+	})
+	if err != nil || !token.Valid {
+		return &TokenIntrospection{Active: false}
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return &TokenIntrospection{Active: false}
+	}
+
+	result := &TokenIntrospection{Active: true}
+	result.Username, _ = claims["username"].(string)
+	result.Role, _ = claims["role"].(string)
+	result.TokenType = "access"
+	if tokenType, _ := claims["type"].(string); tokenType == TokenTypeRefresh {
+		// 已吊销的刷新令牌视为无效
+		if jti, _ := claims["jti"].(string); s.cacheManager.IsTokenRevoked(jti) {
+			return &TokenIntrospection{Active: false}
+		}
+		result.TokenType = TokenTypeRefresh
+	}
+	if exp, ok := claims["exp"].(float64); ok {
+		result.Exp = int64(exp)
+	}
+	if iat, ok := claims["iat"].(float64); ok {
+		result.Iat = int64(iat)
+	}
+	return result
+}
+
 // IsAdmin 检查用户是否为管理员
 func (s *AuthService) IsAdmin(user *AuthUser) bool {
 	return user.Role == "admin"
@@ -93,14 +346,13 @@ func (s *AuthService) IsAdmin(user *AuthUser) bool {
 
 // GetAccountInfo 获取账户信息（不返回密码）
 func (s *AuthService) GetAccountInfo(username string) *config.Account {
-	for _, account := range s.config.Accounts {
-		if account.Username == username {
-			return &config.Account{
-				Username: account.Username,
-				Role:     account.Role,
-				Password: "", // 不返回密码
-			}
-		}
+	account, err := s.findAccount(username)
+	if err != nil {
+		return nil
+	}
+	return &config.Account{
+		Username: account.Username,
+		Role:     account.Role,
+		Password: "", // 不返回密码
 	}
-	return nil
 }
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func newTestAuthService(t *testing.T) *AuthService {
+	t.Helper()
+	t.Setenv("ACCOUNTS", "admin:admin123:admin,alice:alicepass:user")
+	s := NewAuthService(newTestConfig(), newTestCache(), newTestDB(t))
+	if err := s.SeedAccounts(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func signClaims(t *testing.T, secret []byte, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestRefreshIssuesAccessToken(t *testing.T) {
+	s := newTestAuthService(t)
+	refresh, err := s.GenerateRefreshToken(&AuthUser{Username: "alice", Role: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 刷新令牌不能直接作为访问令牌使用
+	if _, err := s.ValidateToken(refresh); err == nil {
+		t.Fatal("refresh token accepted as access token")
+	}
+
+	access, err := s.Refresh(refresh)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	user, err := s.ValidateToken(access)
+	if err != nil || user.Username != "alice" || user.Role != "user" {
+		t.Fatalf("refreshed access token = %+v, %v", user, err)
+	}
+	// 访问令牌不能用来刷新
+	if _, err := s.Refresh(access); err == nil {
+		t.Fatal("access token accepted as refresh token")
+	}
+}
+
+func TestRefreshRejectsExpiredToken(t *testing.T) {
+	s := newTestAuthService(t)
+	expired := signClaims(t, s.jwtSecret, jwt.MapClaims{
+		"username": "alice",
+		"role":     "user",
+		"type":     TokenTypeRefresh,
+		"jti":      "expired-jti",
+		"exp":      time.Now().Add(-time.Second).Unix(),
+		"iat":      time.Now().Add(-refreshTokenTTL).Unix(),
+	})
+	if _, err := s.Refresh(expired); err == nil {
+		t.Fatal("expired refresh token accepted")
+	}
+}
+
+func TestRefreshRejectsTamperedToken(t *testing.T) {
+	s := newTestAuthService(t)
+	refresh, err := s.GenerateRefreshToken(&AuthUser{Username: "alice", Role: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 修改载荷提升角色，签名不再匹配
+	parts := strings.Split(refresh, ".")
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts[1] = base64.RawURLEncoding.EncodeToString([]byte(strings.Replace(string(payload), `"role":"user"`, `"role":"admin"`, 1)))
+	if parts[1] == strings.Split(refresh, ".")[1] {
+		t.Fatal("payload not modified")
+	}
+
+	cases := map[string]string{
+		"modified payload": strings.Join(parts, "."),
+		"wrong secret": signClaims(t, []byte("another-secret"), jwt.MapClaims{
+			"username": "alice", "role": "admin", "type": TokenTypeRefresh, "jti": "x",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}),
+		"missing jti": signClaims(t, s.jwtSecret, jwt.MapClaims{
+			"username": "alice", "role": "user", "type": TokenTypeRefresh,
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}),
+		"unknown user": signClaims(t, s.jwtSecret, jwt.MapClaims{
+			"username": "mallory", "role": "admin", "type": TokenTypeRefresh, "jti": "y",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}),
+		"none algorithm": strings.Join([]string{
+			base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`)), parts[1], "",
+		}, "."),
+	}
+	for name, token := range cases {
+		if _, err := s.Refresh(token); err == nil {
+			t.Errorf("%s: refresh token accepted", name)
+		}
+	}
+}
+
+func TestRevokedRefreshToken(t *testing.T) {
+	s := newTestAuthService(t)
+	refresh, err := s.GenerateRefreshToken(&AuthUser{Username: "alice", Role: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RevokeRefreshToken(refresh); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Refresh(refresh); err == nil {
+		t.Fatal("revoked refresh token accepted")
+	}
+	if got := s.IntrospectToken(refresh); got.Active {
+		t.Fatalf("revoked refresh token introspected as active: %+v", got)
+	}
+
+	// 吊销只影响该令牌
+	other, err := s.GenerateRefreshToken(&AuthUser{Username: "alice", Role: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Refresh(other); err != nil {
+		t.Fatalf("unrelated refresh token rejected: %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDerivedTitle(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.MaxTitleLength = 30
+	s, _ := newTestService(t, cfg)
+	cases := map[string]string{
+		"https://www.example.com/reports/Q3%20report.pdf": "example.com - Q3 report.pdf",
+		"https://example.com/":                            "example.com",
+		"https://example.com":                             "example.com",
+		"https://docs.example.com/a/b/":                   "docs.example.com - b",
+		"https://example.com/%3Cscript%3E":                "example.com",
+		"https://example.com/%3Cb%3Ebold":                 "example.com - bold",
+		"https://example.com/" + strings.Repeat("x", 40):  "example.com - " + strings.Repeat("x", 16),
+		"mailto:someone@example.com":                      "",
+	}
+	for raw, want := range cases {
+		if got := s.derivedTitle(raw); got != want {
+			t.Errorf("derivedTitle(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestAutoTitleOnCreate(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		cfg := newTestConfig()
+		cfg.AutoTitle = enabled
+		s, _ := newTestService(t, cfg)
+
+		url := mustCreate(t, s, "https://www.example.com/docs/guide.html", "alice", URLOptions{})
+		want := ""
+		if enabled {
+			want = "example.com - guide.html"
+		}
+		if url.Title != want {
+			t.Errorf("AutoTitle=%v: title = %q, want %q", enabled, url.Title, want)
+		}
+
+		// 填写了标题时不覆盖
+		titled, err := s.CreateShortURL("https://www.example.com/docs/other.html", "My title", "", "", nil, "alice", true, URLOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if titled.Title != "My title" {
+			t.Errorf("AutoTitle=%v: explicit title replaced with %q", enabled, titled.Title)
+		}
+	}
+}
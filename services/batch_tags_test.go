@@ -0,0 +1,86 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/justseemore/surl/models"
+	"gorm.io/gorm"
+)
+
+func tagsOf(t *testing.T, db *gorm.DB, id uint) []string {
+	t.Helper()
+	var url models.URL
+	if err := db.First(&url, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return []string(url.Tags)
+}
+
+func TestBatchTagURLsAddAndRemoveSubset(t *testing.T) {
+	s, db := newTestService(t, newTestConfig())
+	a := mustCreate(t, s, "https://example.com/a", "alice", URLOptions{Tags: []string{"keep"}})
+	b := mustCreate(t, s, "https://example.com/b", "alice", URLOptions{})
+	c := mustCreate(t, s, "https://example.com/c", "alice", URLOptions{})
+
+	n, err := s.BatchTagURLs([]uint{a.ID, b.ID, c.ID}, []string{" Launch ", "launch"}, TagModeAdd, "alice")
+	if err != nil || n != 3 {
+		t.Fatalf("add: n=%d err=%v, want 3 nil", n, err)
+	}
+	// 再次添加同一标签不会重复，也不计入修改数量
+	if n, err := s.BatchTagURLs([]uint{a.ID}, []string{"LAUNCH"}, TagModeAdd, "alice"); err != nil || n != 0 {
+		t.Fatalf("re-add: n=%d err=%v, want 0 nil", n, err)
+	}
+
+	n, err = s.BatchTagURLs([]uint{a.ID, b.ID}, []string{"launch"}, TagModeRemove, "alice")
+	if err != nil || n != 2 {
+		t.Fatalf("remove: n=%d err=%v, want 2 nil", n, err)
+	}
+
+	want := map[uint][]string{a.ID: {"keep"}, b.ID: nil, c.ID: {"launch"}}
+	for id, tags := range want {
+		if got := tagsOf(t, db, id); !reflect.DeepEqual(got, tags) {
+			t.Errorf("url %d tags = %v, want %v", id, got, tags)
+		}
+	}
+}
+
+func TestBatchTagURLsOwnership(t *testing.T) {
+	s, db := newTestService(t, newTestConfig())
+	mine := mustCreate(t, s, "https://example.com/mine", "alice", URLOptions{})
+	theirs := mustCreate(t, s, "https://example.com/theirs", "bob", URLOptions{})
+
+	if _, err := s.BatchTagURLs([]uint{mine.ID, theirs.ID}, []string{"x"}, TagModeAdd, "alice"); err == nil {
+		t.Fatal("tagging another user's link succeeded")
+	}
+	if got := tagsOf(t, db, mine.ID); len(got) != 0 {
+		t.Fatalf("own link tagged despite rejected batch: %v", got)
+	}
+	if _, err := s.BatchTagURLs([]uint{mine.ID, theirs.ID}, []string{"x"}, TagModeAdd, "admin"); err != nil {
+		t.Fatalf("admin batch: %v", err)
+	}
+}
+
+func TestBatchTagURLsRollsBackOnFailure(t *testing.T) {
+	s, db := newTestService(t, newTestConfig())
+	a := mustCreate(t, s, "https://example.com/a", "alice", URLOptions{})
+	b := mustCreate(t, s, "https://example.com/b", "alice", URLOptions{})
+
+	// 第二条更新失败
+	updates := 0
+	db.Callback().Update().Before("gorm:update").Register("test:fail_second", func(tx *gorm.DB) {
+		if updates++; updates == 2 {
+			tx.AddError(errors.New("boom"))
+		}
+	})
+
+	if _, err := s.BatchTagURLs([]uint{a.ID, b.ID}, []string{"x"}, TagModeAdd, "alice"); err == nil {
+		t.Fatal("batch succeeded despite a failed update")
+	}
+	for _, id := range []uint{a.ID, b.ID} {
+		if got := tagsOf(t, db, id); len(got) != 0 {
+			t.Errorf("url %d tags = %v after rollback, want none", id, got)
+		}
+	}
+}
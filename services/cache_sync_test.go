@@ -0,0 +1,113 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/justseemore/surl/models"
+)
+
+func TestUpdateURLCachesCommittedRow(t *testing.T) {
+	s, repo, db := newCountingService(t, newTestConfig())
+	url := mustCreate(t, s, "https://example.com/before", "alice", URLOptions{})
+
+	expires := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	if _, err := s.UpdateURL(url.ID, "https://example.com/after", "after", &expires, nil, "alice", URLOptions{MaxClicks: int64Ptr(5)}); err != nil {
+		t.Fatal(err)
+	}
+	cached, ok := s.cacheManager.GetURL(url.ShortCode)
+	if !ok {
+		t.Fatal("updated link not cached")
+	}
+	var stored models.URL
+	if err := db.First(&stored, url.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if cached.OriginalURL != stored.OriginalURL || cached.Title != stored.Title ||
+		cached.ExpiresAt == nil || !cached.ExpiresAt.Equal(*stored.ExpiresAt) ||
+		cached.MaxClicks == nil || *cached.MaxClicks != 5 {
+		t.Fatalf("cached = %+v, want the committed row %+v", cached, stored)
+	}
+
+	// 跳转直接使用缓存，不再查询数据库
+	before := repo.findByCodeCalls.Load()
+	got, err := s.GetURLByShortCode(url.ShortCode)
+	if err != nil || got.OriginalURL != "https://example.com/after" {
+		t.Fatalf("GetURLByShortCode = %v, %v", got, err)
+	}
+	if repo.findByCodeCalls.Load() != before {
+		t.Fatal("lookup after update queried the database")
+	}
+
+	// 事务失败（无权限）时缓存保持不变
+	if _, err := s.UpdateURL(url.ID, "https://example.com/bob", "", nil, nil, "bob", URLOptions{}); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("UpdateURL by bob err = %v, want ErrForbidden", err)
+	}
+	if cached, _ := s.cacheManager.GetURL(url.ShortCode); cached.OriginalURL != "https://example.com/after" {
+		t.Fatalf("cache changed by a rejected update: %q", cached.OriginalURL)
+	}
+}
+
+func TestToggleURLStatusSyncsCache(t *testing.T) {
+	s, _ := newTestService(t, newTestConfig())
+	url := mustCreate(t, s, "https://example.com/toggle", "alice", URLOptions{})
+
+	if err := s.ToggleURLStatus(url.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.cacheManager.GetURL(url.ShortCode); ok {
+		t.Fatal("disabled link still cached")
+	}
+	if _, err := s.GetURLByShortCode(url.ShortCode); !errors.Is(err, ErrDisabled) {
+		t.Fatalf("lookup after disabling err = %v, want ErrDisabled", err)
+	}
+
+	if err := s.ToggleURLStatus(url.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if cached, ok := s.cacheManager.GetURL(url.ShortCode); !ok || !cached.IsActive {
+		t.Fatalf("re-enabled link cached = %+v, %v", cached, ok)
+	}
+
+	if err := s.ToggleURLStatus(url.ID, "bob"); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("toggle by bob err = %v, want ErrForbidden", err)
+	}
+	if _, ok := s.cacheManager.GetURL(url.ShortCode); !ok {
+		t.Fatal("rejected toggle changed the cache")
+	}
+}
+
+func TestBatchToggleURLsSyncsCache(t *testing.T) {
+	s, db := newTestService(t, newTestConfig())
+	a := mustCreate(t, s, "https://example.com/a", "alice", URLOptions{})
+	b := mustCreate(t, s, "https://example.com/b", "alice", URLOptions{})
+	bobs := mustCreate(t, s, "https://example.com/bob", "bob", URLOptions{})
+
+	// 非管理员的批量操作忽略其他用户的链接
+	if err := s.BatchToggleURLs([]uint{a.ID, b.ID, bobs.ID}, false, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	for _, url := range []*models.URL{a, b} {
+		if _, ok := s.cacheManager.GetURL(url.ShortCode); ok {
+			t.Errorf("%s still cached after disabling", url.ShortCode)
+		}
+	}
+	if cached, ok := s.cacheManager.GetURL(bobs.ShortCode); !ok || !cached.IsActive {
+		t.Fatal("another user's link changed in the cache")
+	}
+
+	if err := s.BatchToggleURLs([]uint{a.ID, b.ID}, true, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	for _, url := range []*models.URL{a, b} {
+		var stored models.URL
+		if err := db.First(&stored, url.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		cached, ok := s.cacheManager.GetURL(url.ShortCode)
+		if !ok || cached.IsActive != stored.IsActive || !stored.IsActive {
+			t.Errorf("%s: cached = %+v, stored is_active = %v", url.ShortCode, cached, stored.IsActive)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCachedURLOmitsDescription(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.MaxDescriptionLength = 0
+	s, repo, _ := newCountingService(t, cfg)
+	description := strings.Repeat("long description ", 1000)
+	url, err := s.CreateShortURL("https://example.com/heavy", "Heavy", description, "", nil, "alice", true, URLOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cached, ok := s.cacheManager.GetURL(url.ShortCode)
+	if !ok {
+		t.Fatal("created link not cached")
+	}
+	data, err := json.Marshal(cached)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "long description") || len(data) > 1024 {
+		t.Fatalf("cached entry carries the description (%d bytes)", len(data))
+	}
+
+	// 跳转只使用缓存，不查询数据库
+	got, err := s.GetURLByShortCode(url.ShortCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.OriginalURL != url.OriginalURL || got.Title != "Heavy" || got.Description != "" {
+		t.Fatalf("redirect record = %q / %q / %d-byte description", got.OriginalURL, got.Title, len(got.Description))
+	}
+	if calls := repo.findByCodeCalls.Load(); calls != 0 {
+		t.Fatalf("redirect lookup queried the database %d times", calls)
+	}
+
+	// 需要元数据时从数据库读取完整记录
+	full, err := s.GetURLDetailsByShortCode(url.ShortCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if full.Description != strings.TrimSpace(description) {
+		t.Fatalf("details description has %d bytes, want %d", len(full.Description), len(strings.TrimSpace(description)))
+	}
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMaxClicksCountsPendingClicks(t *testing.T) {
+	s, _ := newTestService(t, newTestConfig())
+	url := mustCreate(t, s, "https://example.com/once", "alice", URLOptions{MaxClicks: int64Ptr(2)})
+
+	for i := 0; i < 2; i++ {
+		if _, err := s.GetURLByShortCode(url.ShortCode); err != nil {
+			t.Fatalf("click %d: %v", i+1, err)
+		}
+		addClicks(t, s, url.ShortCode, 1)
+	}
+
+	// 两次点击都还在缓冲区中，尚未同步到数据库
+	if _, err := s.GetURLByShortCode(url.ShortCode); !errors.Is(err, ErrExpired) {
+		t.Fatalf("after 2 buffered clicks: err = %v, want ErrExpired", err)
+	}
+
+	// 同步后仍然视为过期
+	s.SyncClickCounts()
+	if _, err := s.GetURLByShortCode(url.ShortCode); !errors.Is(err, ErrExpired) {
+		t.Fatalf("after sync: err = %v, want ErrExpired", err)
+	}
+}
+
+func TestMaxClicksSkipsDatabaseFarFromCap(t *testing.T) {
+	s, repo, _ := newCountingService(t, newTestConfig())
+	url := mustCreate(t, s, "https://example.com/limited", "alice", URLOptions{MaxClicks: int64Ptr(10)})
+
+	// 前8次点击（估算值低于上限的90%）不查询数据库，同步后缓存中的点击数随之更新
+	for i := 0; i < 8; i++ {
+		if _, err := s.GetURLByShortCode(url.ShortCode); err != nil {
+			t.Fatalf("click %d: %v", i+1, err)
+		}
+		addClicks(t, s, url.ShortCode, 1)
+		if i == 4 {
+			s.SyncClickCounts()
+		}
+	}
+	if repo.clickCountCalls.Load() != 0 {
+		t.Fatalf("ClickCount called %d times below the cap, want 0", repo.clickCountCalls.Load())
+	}
+
+	// 估算值达到上限的90%后查询数据库确认
+	addClicks(t, s, url.ShortCode, 1)
+	if _, err := s.GetURLByShortCode(url.ShortCode); err != nil {
+		t.Fatalf("click 10: %v", err)
+	}
+	if repo.clickCountCalls.Load() == 0 {
+		t.Fatal("ClickCount not consulted near the cap")
+	}
+	addClicks(t, s, url.ShortCode, 1)
+	if _, err := s.GetURLByShortCode(url.ShortCode); !errors.Is(err, ErrExpired) {
+		t.Fatalf("after 10 clicks: err = %v, want ErrExpired", err)
+	}
+}
+
+func TestMaxClicksSeesClicksSyncedElsewhere(t *testing.T) {
+	s, db := newTestService(t, newTestConfig())
+	url := mustCreate(t, s, "https://example.com/shared", "alice", URLOptions{MaxClicks: int64Ptr(10)})
+	addClicks(t, s, url.ShortCode, 9)
+	s.SyncClickCounts()
+
+	// 接近上限时，另一个实例同步的点击从数据库读取
+	if err := db.Model(url).Update("click_count", 10).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetURLByShortCode(url.ShortCode); !errors.Is(err, ErrExpired) {
+		t.Fatalf("err = %v, want ErrExpired once the database count is consulted", err)
+	}
+}
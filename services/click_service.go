@@ -0,0 +1,137 @@
+package services
+
+import (
+	"log"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/justseemore/surl/models"
+	"gorm.io/gorm"
+)
+
+const (
+	clickSyncInterval = 10 * time.Second
+	clickFlushSize    = 1000 // 缓冲的点击明细达到该数量时提前写入
+	clickInsertBatch  = 200  // 单条 INSERT 语句写入的记录数
+)
+
+// ClickService 记录点击明细（先缓存在内存，定期批量写入数据库）并提供按维度的统计
+// 配置了抽样率时只记录部分点击，每条明细带有权重，统计结果按权重还原为估算的总点击数
+type ClickService struct {
+	db         *gorm.DB
+	sampleRate float64
+	pending    []models.Click
+	mutex      sync.Mutex
+	flush      chan struct{}
+}
+
+// BreakdownItem 某个维度取值的点击数
+type BreakdownItem struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// UABreakdown 按设备、浏览器、操作系统统计的点击数（抽样时为估算值），各维度按点击数从高到低排序
+type UABreakdown struct {
+	Devices  []BreakdownItem `json:"devices"`
+	Browsers []BreakdownItem `json:"browsers"`
+	OS       []BreakdownItem `json:"os"`
+}
+
+// NewClickService 创建点击明细服务，sampleRate 为 0-1 的抽样率，0 表示不记录明细
+func NewClickService(db *gorm.DB, sampleRate float64) *ClickService {
+	return &ClickService{
+		db:         db,
+		sampleRate: sampleRate,
+		flush:      make(chan struct{}, 1),
+	}
+}
+
+// RecordClick 按抽样率记录一次点击的UA信息
+func (s *ClickService) RecordClick(shortCode, device, browser, os string) {
+	if s.sampleRate <= 0 || (s.sampleRate < 1 && rand.Float64() >= s.sampleRate) {
+		return
+	}
+
+	s.mutex.Lock()
+	s.pending = append(s.pending, models.Click{
+		ShortCode: shortCode,
+		Device:    device,
+		Browser:   browser,
+		OS:        os,
+		ClickedAt: models.Now(),
+		Weight:    1 / s.sampleRate,
+	})
+	full := len(s.pending) >= clickFlushSize
+	s.mutex.Unlock()
+
+	if full {
+		select {
+		case s.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// SyncClicks 将缓冲的点击明细写入数据库
+func (s *ClickService) SyncClicks() {
+	s.mutex.Lock()
+	clicks := s.pending
+	s.pending = nil
+	s.mutex.Unlock()
+
+	if len(clicks) == 0 {
+		return
+	}
+	if err := s.db.CreateInBatches(clicks, clickInsertBatch).Error; err != nil {
+		log.Printf("写入点击明细失败（%d条）: %v", len(clicks), err)
+	}
+}
+
+// StartClickSync 启动点击明细同步
+func (s *ClickService) StartClickSync() {
+	ticker := time.NewTicker(clickSyncInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-s.flush:
+			}
+			s.SyncClicks()
+		}
+	}()
+}
+
+// GetUABreakdown 获取短链接按设备、浏览器、操作系统的点击统计
+func (s *ClickService) GetUABreakdown(shortCode string) (*UABreakdown, error) {
+	breakdown := &UABreakdown{}
+	for column, dest := range map[string]*[]BreakdownItem{
+		"device":  &breakdown.Devices,
+		"browser": &breakdown.Browsers,
+		"os":      &breakdown.OS,
+	} {
+		var rows []struct {
+			Value  string
+			Weight float64
+		}
+		err := s.db.Model(&models.Click{}).
+			Select(column+" AS value, SUM(weight) AS weight").
+			Where("short_code = ?", shortCode).
+			Group(column).
+			Order("weight DESC").
+			Scan(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+
+		items := make([]BreakdownItem, len(rows))
+		for i, row := range rows {
+			items[i] = BreakdownItem{Value: row.Value, Count: int64(math.Round(row.Weight))}
+		}
+		*dest = items
+	}
+	return breakdown, nil
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/justseemore/surl/models"
+)
+
+func TestClickSamplingScalesBreakdown(t *testing.T) {
+	const clicks = 10000
+	db := newTestDB(t)
+	s := NewClickService(db, 0.1)
+	for i := 0; i < clicks; i++ {
+		s.RecordClick("sampled", "Mobile", "Chrome", "Android")
+	}
+	s.SyncClicks()
+
+	// 抽样10%：明细约1000条，误差在 ±3σ（约90条）以内
+	var stored int64
+	if err := db.Model(&models.Click{}).Where("short_code = ?", "sampled").Count(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored < 900 || stored > 1100 {
+		t.Fatalf("stored %d detail records for %d clicks at 10%% sampling", stored, clicks)
+	}
+
+	// 统计结果按权重还原，等于明细条数乘以10
+	breakdown, err := s.GetUABreakdown("sampled")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(breakdown.Devices) != 1 || breakdown.Devices[0].Count != stored*10 {
+		t.Fatalf("Devices = %+v, want one entry counting %d", breakdown.Devices, stored*10)
+	}
+}
+
+func TestClickSamplingOffAndFull(t *testing.T) {
+	db := newTestDB(t)
+	off := NewClickService(db, 0)
+	full := NewClickService(db, 1)
+	for i := 0; i < 50; i++ {
+		off.RecordClick("off", "Desktop", "Firefox", "Linux")
+		full.RecordClick("full", "Desktop", "Firefox", "Linux")
+	}
+	off.SyncClicks()
+	full.SyncClicks()
+
+	for code, want := range map[string]int64{"off": 0, "full": 50} {
+		var stored int64
+		if err := db.Model(&models.Click{}).Where("short_code = ?", code).Count(&stored).Error; err != nil {
+			t.Fatal(err)
+		}
+		if stored != want {
+			t.Errorf("%s: stored %d detail records, want %d", code, stored, want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/justseemore/surl/models"
+	"gorm.io/gorm"
+)
+
+// failClickUpdates 注册gorm回调，failing 为true时让写入 click_count 的UPDATE失败
+func failClickUpdates(t *testing.T, db *gorm.DB) *atomic.Bool {
+	t.Helper()
+	var failing atomic.Bool
+	err := db.Callback().Update().Before("gorm:update").Register("test:fail_click_sync", func(tx *gorm.DB) {
+		if !failing.Load() || tx.Statement.Table != "urls" {
+			return
+		}
+		if updates, ok := tx.Statement.Dest.(map[string]interface{}); ok {
+			if _, ok := updates["click_count"]; !ok {
+				return
+			}
+		}
+		tx.AddError(errors.New("database is locked"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &failing
+}
+
+func storedClicks(t *testing.T, db *gorm.DB, id uint) int64 {
+	t.Helper()
+	var url models.URL
+	if err := db.First(&url, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return url.ClickCount
+}
+
+func TestFailedClickSyncRetriesNextRun(t *testing.T) {
+	s, db := newTestService(t, newTestConfig())
+	failing := failClickUpdates(t, db)
+	url := mustCreate(t, s, "https://example.com/retry", "alice", URLOptions{})
+	addClicks(t, s, url.ShortCode, 3)
+
+	failing.Store(true)
+	s.SyncClickCounts()
+	if got := storedClicks(t, db, url.ID); got != 0 {
+		t.Fatalf("click_count after failed sync = %d, want 0", got)
+	}
+	if got := s.cacheManager.GetPendingClicks(url.ShortCode); got != 3 {
+		t.Fatalf("pending after failed sync = %d, want the 3 clicks re-buffered", got)
+	}
+
+	// 失败期间的新点击与放回的计数一起写入
+	addClicks(t, s, url.ShortCode, 2)
+	failing.Store(false)
+	s.SyncClickCounts()
+	if got := storedClicks(t, db, url.ID); got != 5 {
+		t.Fatalf("click_count after recovery = %d, want 5", got)
+	}
+	if got := s.cacheManager.GetPendingClicks(url.ShortCode); got != 0 {
+		t.Fatalf("pending after recovery = %d, want 0", got)
+	}
+}
+
+func TestFailedClickSyncDroppedWithoutRetry(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.ClickSyncRetry = false
+	s, db := newTestService(t, cfg)
+	failing := failClickUpdates(t, db)
+	url := mustCreate(t, s, "https://example.com/no-retry", "alice", URLOptions{})
+	addClicks(t, s, url.ShortCode, 3)
+
+	failing.Store(true)
+	s.SyncClickCounts()
+	failing.Store(false)
+	s.SyncClickCounts()
+	if got := storedClicks(t, db, url.ID); got != 0 {
+		t.Fatalf("click_count = %d, want the failed counts dropped", got)
+	}
+	if got := s.cacheManager.GetPendingClicks(url.ShortCode); got != 0 {
+		t.Fatalf("pending = %d, want 0", got)
+	}
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSyncClickCountsSkipsOverlappingRun(t *testing.T) {
+	s, repo, db := newCountingService(t, newTestConfig())
+	url := mustCreate(t, s, "https://example.com/slow-sync", "alice", URLOptions{})
+	addClicks(t, s, url.ShortCode, 3)
+
+	// 第一次同步在写入数据库时阻塞，模拟耗时超过同步间隔的同步
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	repo.beforeAddClicks = func() {
+		once.Do(func() { close(entered) })
+		<-release
+	}
+	done := make(chan struct{})
+	go func() {
+		s.SyncClickCounts()
+		close(done)
+	}()
+	<-entered
+
+	// 同步进行期间新的计时触发直接跳过，不会再次读取或写入计数
+	addClicks(t, s, url.ShortCode, 2)
+	skipped := make(chan struct{})
+	go func() {
+		s.SyncClickCounts()
+		close(skipped)
+	}()
+	select {
+	case <-skipped:
+	case <-time.After(time.Second):
+		t.Fatal("overlapping SyncClickCounts blocked instead of skipping")
+	}
+	if got := repo.addClicksCalls.Load(); got != 1 {
+		t.Fatalf("AddClicks called %d times while a sync was running, want 1", got)
+	}
+	if got := s.cacheManager.GetPendingClicks(url.ShortCode); got != 2 {
+		t.Fatalf("pending clicks = %d, want the 2 clicks made during the sync", got)
+	}
+
+	// FlushClickCounts 等待正在进行的同步，而不是跳过
+	flushed := make(chan struct{})
+	go func() {
+		s.FlushClickCounts()
+		close(flushed)
+	}()
+	select {
+	case <-flushed:
+		t.Fatal("FlushClickCounts ran while a sync was in progress")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	<-done
+	<-flushed
+
+	var count int64
+	if err := db.Table("urls").Select("click_count").Where("id = ?", url.ID).Scan(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 5 {
+		t.Fatalf("click_count = %d, want 5", count)
+	}
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRestrictedCustomCodeCharset(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.CustomCodeCharset = "abcdefghijklmnopqrstuvwxyz0123456789."
+	cfg.CustomCodeMinLength = 4
+	cfg.CustomCodeMaxLength = 8
+	s, _ := newTestService(t, cfg)
+
+	cases := map[string]string{
+		"promo":     "",
+		"v1.2":      "",
+		"2024.sale": "长度必须在4到8个字符之间",
+		"abc":       "长度必须在4到8个字符之间",
+		"Promo":     "不允许的字符",
+		"pro-mo":    "不允许的字符",
+		"pro_mo":    "不允许的字符",
+		"café":      "不允许的字符",
+		"login":     "保留路径",
+	}
+	for code, wantErr := range cases {
+		err := s.validateCustomCode(code)
+		switch {
+		case wantErr == "" && err != nil:
+			t.Errorf("validateCustomCode(%q) = %v, want ok", code, err)
+		case wantErr != "" && (err == nil || !strings.Contains(err.Error(), wantErr)):
+			t.Errorf("validateCustomCode(%q) = %v, want %q", code, err, wantErr)
+		}
+	}
+
+	// 创建时使用同样的规则
+	url := mustCreate(t, s, "https://example.com/dotted", "alice", URLOptions{CustomCode: "v1.2"})
+	if url.ShortCode != "v1.2" {
+		t.Fatalf("ShortCode = %q, want v1.2", url.ShortCode)
+	}
+	if _, err := s.CreateShortURL("https://example.com/upper", "", "", "", nil, "alice", true, URLOptions{CustomCode: "UPPER"}); err == nil {
+		t.Fatal("created a code outside the configured charset")
+	}
+}
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/justseemore/surl/models"
+)
+
+func TestDeleteURLIsIdempotent(t *testing.T) {
+	s, db := newTestService(t, newTestConfig())
+	url := mustCreate(t, s, "https://example.com/twice", "alice", URLOptions{})
+
+	for i := 1; i <= 2; i++ {
+		if err := s.DeleteURL(url.ID, "alice"); err != nil {
+			t.Fatalf("delete #%d: %v", i, err)
+		}
+	}
+	// 不存在的ID同样视为已删除
+	if err := s.DeleteURL(url.ID+1000, "alice"); err != nil {
+		t.Fatalf("delete of unknown id: %v", err)
+	}
+
+	var stored models.URL
+	if err := db.Unscoped().First(&stored, url.ID).Error; err != nil {
+		t.Fatalf("soft-deleted row missing: %v", err)
+	}
+	if !stored.DeletedAt.Valid {
+		t.Fatal("row not soft-deleted")
+	}
+	if _, err := s.GetURLByShortCode(url.ShortCode); err == nil {
+		t.Fatal("deleted link still resolves")
+	}
+}
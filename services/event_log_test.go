@@ -0,0 +1,63 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/justseemore/surl/events"
+)
+
+func TestCreateShortURLWritesEventLog(t *testing.T) {
+	s, _ := newTestService(t, newTestConfig())
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	eventLog, err := events.NewEventLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetEventLog(eventLog)
+
+	url := mustCreate(t, s, "https://example.com/indexed", "alice", URLOptions{Tags: []string{"docs"}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := eventLog.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.Split(bytes.TrimSpace(raw), []byte("\n"))
+	if len(lines) != 1 {
+		t.Fatalf("got %d lines, want 1: %s", len(lines), raw)
+	}
+
+	var event struct {
+		Type      string    `json:"event"`
+		Timestamp time.Time `json:"timestamp"`
+		Data      struct {
+			ID          uint     `json:"id"`
+			ShortCode   string   `json:"short_code"`
+			OriginalURL string   `json:"original_url"`
+			CreatedBy   string   `json:"created_by"`
+			Tags        []string `json:"tags"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(lines[0], &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != events.LinkCreated || event.Timestamp.IsZero() {
+		t.Errorf("event = %q at %v", event.Type, event.Timestamp)
+	}
+	if event.Data.ID != url.ID || event.Data.ShortCode != url.ShortCode ||
+		event.Data.OriginalURL != "https://example.com/indexed" || event.Data.CreatedBy != "alice" ||
+		len(event.Data.Tags) != 1 || event.Data.Tags[0] != "docs" {
+		t.Errorf("data = %+v", event.Data)
+	}
+}
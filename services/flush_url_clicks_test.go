@@ -0,0 +1,55 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFlushURLClicksOnlyTouchesOneCode(t *testing.T) {
+	s, db := newTestService(t, newTestConfig())
+	hot := mustCreate(t, s, "https://example.com/hot", "alice", URLOptions{})
+	other := mustCreate(t, s, "https://example.com/other", "alice", URLOptions{})
+	addClicks(t, s, hot.ShortCode, 4)
+	addClicks(t, s, other.ShortCode, 2)
+
+	total, err := s.FlushURLClicks(hot.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 4 || storedClicks(t, db, hot.ID) != 4 {
+		t.Fatalf("FlushURLClicks = %d, stored %d; want 4", total, storedClicks(t, db, hot.ID))
+	}
+	if got := s.cacheManager.GetPendingClicks(hot.ShortCode); got != 0 {
+		t.Fatalf("hot pending = %d, want 0", got)
+	}
+	// 其他链接的缓冲计数保持不变，等待定时同步
+	if got := s.cacheManager.GetPendingClicks(other.ShortCode); got != 2 {
+		t.Fatalf("other pending = %d, want 2", got)
+	}
+	if got := storedClicks(t, db, other.ID); got != 0 {
+		t.Fatalf("other click_count = %d, want 0", got)
+	}
+
+	// 没有缓冲计数时返回数据库中的总数
+	if total, err := s.FlushURLClicks(hot.ID); err != nil || total != 4 {
+		t.Fatalf("second FlushURLClicks = %d, %v; want 4", total, err)
+	}
+	if _, err := s.FlushURLClicks(99999); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("FlushURLClicks(unknown) err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFlushURLClicksRestoresOnFailure(t *testing.T) {
+	s, db := newTestService(t, newTestConfig())
+	failing := failClickUpdates(t, db)
+	url := mustCreate(t, s, "https://example.com/flaky", "alice", URLOptions{})
+	addClicks(t, s, url.ShortCode, 3)
+
+	failing.Store(true)
+	if _, err := s.FlushURLClicks(url.ID); err == nil {
+		t.Fatal("FlushURLClicks succeeded with a failing UPDATE")
+	}
+	if got := s.cacheManager.GetPendingClicks(url.ShortCode); got != 3 {
+		t.Fatalf("pending after failed flush = %d, want 3", got)
+	}
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/justseemore/surl/models"
+	"github.com/oschwald/geoip2-golang"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// unknownCountry 无法解析IP时使用的国家代码
+const unknownCountry = "unknown"
+
+type GeoService struct {
+	reader *geoip2.Reader
+	lookup func(ip net.IP) string // 将IP解析为国家代码，无法识别时返回空
+	db     *gorm.DB
+	counts map[countryKey]int64
+	mutex  sync.Mutex
+}
+
+type countryKey struct {
+	shortCode string
+	country   string
+}
+
+// NewGeoService 创建GeoIP服务实例，dbPath为空时返回nil（功能关闭）
+func NewGeoService(dbPath string, db *gorm.DB) (*GeoService, error) {
+	if dbPath == "" {
+		return nil, nil
+	}
+
+	reader, err := geoip2.Open(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("打开GeoIP数据库失败: %v", err)
+	}
+
+	return &GeoService{
+		reader: reader,
+		lookup: func(ip net.IP) string {
+			record, err := reader.Country(ip)
+			if err != nil {
+				return ""
+			}
+			return record.Country.IsoCode
+		},
+		db:     db,
+		counts: make(map[countryKey]int64),
+	}, nil
+}
+
+// NewGeoServiceWithLookup 创建使用指定解析函数的GeoIP服务，不需要GeoIP数据库
+func NewGeoServiceWithLookup(lookup func(ip net.IP) string, db *gorm.DB) *GeoService {
+	return &GeoService{
+		lookup: lookup,
+		db:     db,
+		counts: make(map[countryKey]int64),
+	}
+}
+
+// LookupCountry 将IP解析为国家代码
+func (s *GeoService) LookupCountry(ip string) string {
+	parsedIP := net.ParseIP(ip)
+	if parsedIP == nil {
+		return unknownCountry
+	}
+
+	country := s.lookup(parsedIP)
+	if country == "" {
+		return unknownCountry
+	}
+	return country
+}
+
+// RecordClick 记录一次点击的国家（先缓存在内存，定期写入数据库）
+func (s *GeoService) RecordClick(shortCode, ip string) {
+	country := s.LookupCountry(ip)
+
+	s.mutex.Lock()
+	s.counts[countryKey{shortCode: shortCode, country: country}]++
+	s.mutex.Unlock()
+}
+
+// SyncCountryClicks 将内存中的国家点击计数写入数据库
+func (s *GeoService) SyncCountryClicks() {
+	s.mutex.Lock()
+	counts := s.counts
+	s.counts = make(map[countryKey]int64)
+	s.mutex.Unlock()
+
+	for key, count := range counts {
+		record := models.CountryClick{
+			ShortCode: key.shortCode,
+			Country:   key.country,
+			Count:     count,
+			UpdatedAt: models.Now(),
+		}
+		err := s.db.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "short_code"}, {Name: "country"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"count":      gorm.Expr("count + ?", count),
+				"updated_at": record.UpdatedAt,
+			}),
+		}).Create(&record).Error
+		if err != nil {
+			log.Printf("同步国家点击计数失败 [%s/%s]: %v", key.shortCode, key.country, err)
+		}
+	}
+}
+
+// StartCountryClickSync 启动国家点击计数同步
+func (s *GeoService) StartCountryClickSync() {
+	ticker := time.NewTicker(10 * time.Second)
+	go func() {
+		defer ticker.Stop()
+		for range ticker.C {
+			s.SyncCountryClicks()
+		}
+	}()
+}
+
+// GetCountryClicks 获取短链接按国家的点击统计
+func (s *GeoService) GetCountryClicks(shortCode string) ([]models.CountryClick, error) {
+	var clicks []models.CountryClick
+	err := s.db.Where("short_code = ?", shortCode).Order("count DESC").Find(&clicks).Error
+	return clicks, err
+}
+
+// Close 关闭GeoIP数据库
+func (s *GeoService) Close() error {
+	if s.reader == nil {
+		return nil
+	}
+	return s.reader.Close()
+}
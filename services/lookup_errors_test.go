@@ -0,0 +1,46 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGetURLByShortCodeTypedErrors(t *testing.T) {
+	s, _ := newTestService(t, newTestConfig())
+	active := mustCreate(t, s, "https://example.com/active", "alice", URLOptions{})
+	disabled := mustCreate(t, s, "https://example.com/disabled", "alice", URLOptions{})
+	if err := s.ToggleURLStatus(disabled.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Hour)
+	expired := mustCreate(t, s, "https://example.com/expired", "alice", URLOptions{})
+	if _, err := s.UpdateURL(expired.ID, "", "", &past, nil, "alice", URLOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	limited := mustCreate(t, s, "https://example.com/limited", "alice", URLOptions{MaxClicks: int64Ptr(2)})
+	addClicks(t, s, limited.ShortCode, 2)
+	s.SyncClickCounts()
+	deleted := mustCreate(t, s, "https://example.com/deleted", "alice", URLOptions{})
+	if err := s.DeleteURL(deleted.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]error{
+		active.ShortCode:   nil,
+		disabled.ShortCode: ErrDisabled,
+		expired.ShortCode:  ErrExpired,
+		limited.ShortCode:  ErrExpired,
+		deleted.ShortCode:  ErrNotFound,
+		"nosuchcode":       ErrNotFound,
+	}
+	// 第二轮走缓存（包括负缓存），结果应与第一轮相同
+	for round := 1; round <= 2; round++ {
+		for code, want := range cases {
+			_, err := s.GetURLByShortCode(code)
+			if want == nil && err != nil || want != nil && !errors.Is(err, want) {
+				t.Errorf("round %d: GetURLByShortCode(%s) err = %v, want %v", round, code, err, want)
+			}
+		}
+	}
+}
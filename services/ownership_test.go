@@ -0,0 +1,42 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/justseemore/surl/models"
+)
+
+func TestSingleURLOwnership(t *testing.T) {
+	s, db := newTestService(t, newTestConfig())
+	url := mustCreate(t, s, "https://example.com/owned", "alice", URLOptions{})
+
+	if _, err := s.UpdateURL(url.ID, "https://evil.example/", "", nil, nil, "bob", URLOptions{}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("UpdateURL by non-owner: err = %v, want ErrForbidden", err)
+	}
+	if err := s.ToggleURLStatus(url.ID, "bob"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("ToggleURLStatus by non-owner: err = %v, want ErrForbidden", err)
+	}
+	if err := s.DeleteURL(url.ID, "bob"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("DeleteURL by non-owner: err = %v, want ErrForbidden", err)
+	}
+
+	var stored models.URL
+	if err := db.First(&stored, url.ID).Error; err != nil {
+		t.Fatalf("link gone after rejected delete: %v", err)
+	}
+	if stored.OriginalURL != "https://example.com/owned" || !stored.IsActive {
+		t.Fatalf("link modified by non-owner: %+v", stored)
+	}
+
+	// 创建者和admin可以修改和删除
+	if _, err := s.UpdateURL(url.ID, "", "mine", nil, nil, "alice", URLOptions{}); err != nil {
+		t.Errorf("UpdateURL by owner: %v", err)
+	}
+	if err := s.ToggleURLStatus(url.ID, "admin"); err != nil {
+		t.Errorf("ToggleURLStatus by admin: %v", err)
+	}
+	if err := s.DeleteURL(url.ID, "admin"); err != nil {
+		t.Errorf("DeleteURL by admin: %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/justseemore/surl/cache"
+	"github.com/justseemore/surl/models"
+	"gorm.io/gorm"
+)
+
+// newRedisWorkers 模拟 Prefork 的子进程：各自的缓存管理器连接同一个进程内Redis（miniredis），共用一个数据库
+func newRedisWorkers(t *testing.T, db *gorm.DB, n int) ([]*URLService, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	workers := make([]*URLService, n)
+	for i := range workers {
+		m := cache.NewCacheManager(mr.Addr(), "", 0, 60, 1000, "surltest")
+		if !m.RedisEnabled() {
+			t.Fatal("miniredis not reachable")
+		}
+		t.Cleanup(func() { m.Close() })
+		workers[i] = NewURLService(m, db, newTestConfig(), nil)
+	}
+	return workers, mr
+}
+
+func TestWorkersShareRedisClickCounts(t *testing.T) {
+	db := newTestDB(t)
+	workers, mr := newRedisWorkers(t, db, 2)
+	url := mustCreate(t, workers[0], "https://example.com/prefork", "alice", URLOptions{})
+
+	const perWorker = 300
+	var wg sync.WaitGroup
+	for _, w := range workers {
+		wg.Add(1)
+		go func(w *URLService) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				w.IncrementClickCount(url.ShortCode)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	// 点击计入共用Redis中的同一个哈希字段（HINCRBY），而不是各进程的内存
+	deadline := time.Now().Add(2 * time.Second)
+	for mr.HGet("surltest:clicks", url.ShortCode) != strconv.Itoa(2*perWorker) {
+		if time.Now().After(deadline) {
+			t.Fatalf("clicks hash field = %q, want %d", mr.HGet("surltest:clicks", url.ShortCode), 2*perWorker)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// 任意一个进程都能看到全部未同步的点击
+	for workers[1].cacheManager.GetPendingClicks(url.ShortCode) < 2*perWorker {
+		if time.Now().After(deadline) {
+			t.Fatalf("pending clicks = %d, want %d", workers[1].cacheManager.GetPendingClicks(url.ShortCode), 2*perWorker)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// 两个进程同时同步，每个点击只写入一次
+	for _, w := range workers {
+		wg.Add(1)
+		go func(w *URLService) {
+			defer wg.Done()
+			w.SyncClickCounts()
+		}(w)
+	}
+	wg.Wait()
+
+	var stored models.URL
+	if err := db.First(&stored, url.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.ClickCount != 2*perWorker {
+		t.Fatalf("click_count = %d, want %d", stored.ClickCount, 2*perWorker)
+	}
+	if pending := workers[0].cacheManager.GetPendingClicks(url.ShortCode); pending != 0 {
+		t.Fatalf("pending after sync = %d, want 0", pending)
+	}
+	// 同步时 HGETALL 和 DEL 在同一个事务中执行，哈希整体被取走
+	if mr.Exists("surltest:clicks") {
+		t.Fatal("clicks hash still present after sync")
+	}
+}
+
+func TestFailedSyncRestoresClicksToRedis(t *testing.T) {
+	db := newTestDB(t)
+	workers, mr := newRedisWorkers(t, db, 2)
+	url := mustCreate(t, workers[0], "https://example.com/restore", "alice", URLOptions{})
+	failing := failClickUpdates(t, db)
+
+	for i := 0; i < 3; i++ {
+		workers[0].IncrementClickCount(url.ShortCode)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for workers[1].cacheManager.GetPendingClicks(url.ShortCode) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("pending clicks = %d, want 3", workers[1].cacheManager.GetPendingClicks(url.ShortCode))
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// 写入数据库失败的计数放回共用Redis，而不是同步进程自己的内存
+	failing.Store(true)
+	workers[0].SyncClickCounts()
+	if got := mr.HGet("surltest:clicks", url.ShortCode); got != "3" {
+		t.Fatalf("clicks hash field after failed sync = %q, want 3", got)
+	}
+	if got := storedClicks(t, db, url.ID); got != 0 {
+		t.Fatalf("click_count after failed sync = %d, want 0", got)
+	}
+
+	// 另一个进程的下一次同步写入放回的计数
+	failing.Store(false)
+	workers[1].SyncClickCounts()
+	if got := storedClicks(t, db, url.ID); got != 3 {
+		t.Fatalf("click_count after retry = %d, want 3", got)
+	}
+	if mr.Exists("surltest:clicks") {
+		t.Fatal("clicks hash still present after retry")
+	}
+}
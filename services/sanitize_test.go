@@ -0,0 +1,72 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeText(t *testing.T) {
+	cases := map[string]string{
+		"  plain title  ":                   "plain title",
+		"<script>alert(1)</script>Hi":       "alert(1)Hi",
+		`<img src=x onerror="alert(1)">`:    "",
+		"<b>bold</b> & <i>it</i>":           "bold & it",
+		"<img src=x onerror=alert(1)":       "img src=x onerror=alert(1)",
+		"a < b > c":                         "a  c",
+		"line1\nline2\r\x00\x1b[31m":        "line1\nline2[31m",
+		"中文标题<a href='javascript:x'>链接</a>": "中文标题链接",
+	}
+	for in, want := range cases {
+		got, err := sanitizeText(in, 0, "标题")
+		if err != nil || got != want {
+			t.Errorf("sanitizeText(%q) = %q, %v; want %q", in, got, err, want)
+		}
+		if strings.ContainsAny(got, "<>") {
+			t.Errorf("sanitizeText(%q) = %q still contains markup", in, got)
+		}
+	}
+
+	// 长度按字符计算，并在清理之后检查
+	if _, err := sanitizeText(strings.Repeat("字", 5), 5, "描述"); err != nil {
+		t.Errorf("5 runes rejected at limit 5: %v", err)
+	}
+	if _, err := sanitizeText("<b>"+strings.Repeat("a", 5)+"</b>", 5, "描述"); err != nil {
+		t.Errorf("markup counted towards the limit: %v", err)
+	}
+	if _, err := sanitizeText(strings.Repeat("a", 6), 5, "描述"); err == nil || !strings.Contains(err.Error(), "描述长度不能超过5个字符") {
+		t.Errorf("over-long value: err = %v", err)
+	}
+}
+
+func TestCreateShortURLLimitsAndSanitizesText(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.MaxTitleLength = 20
+	cfg.MaxDescriptionLength = 50
+	s, _ := newTestService(t, cfg)
+
+	_, err := s.CreateShortURL("https://example.com/long", "", strings.Repeat("d", 51), "", nil, "alice", true, URLOptions{})
+	if err == nil || !strings.Contains(err.Error(), "描述长度不能超过50个字符") {
+		t.Fatalf("over-long description: err = %v", err)
+	}
+	_, err = s.CreateShortURL("https://example.com/long", strings.Repeat("t", 21), "", "", nil, "alice", true, URLOptions{})
+	if err == nil || !strings.Contains(err.Error(), "标题长度不能超过20个字符") {
+		t.Fatalf("over-long title: err = %v", err)
+	}
+
+	url, err := s.CreateShortURL("https://example.com/xss", "<script>alert(1)</script>Sale", "<b>50%</b> off", "", nil, "alice", true, URLOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url.Title != "alert(1)Sale" || url.Description != "50% off" {
+		t.Fatalf("stored title/description = %q / %q", url.Title, url.Description)
+	}
+
+	// 更新标题同样清理
+	updated, err := s.UpdateURL(url.ID, "", "<svg onload=alert(1)>New", nil, nil, "alice", URLOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Title != "New" {
+		t.Fatalf("updated title = %q, want New", updated.Title)
+	}
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestShutdownPersistsPendingClicks(t *testing.T) {
+	s, db := newTestService(t, newTestConfig())
+	a := mustCreate(t, s, "https://example.com/a", "alice", URLOptions{})
+	b := mustCreate(t, s, "https://example.com/b", "alice", URLOptions{})
+	s.StartClickCountSync()
+	addClicks(t, s, a.ShortCode, 3)
+	addClicks(t, s, b.ShortCode, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	for code, want := range map[string]int64{a.ShortCode: 3, b.ShortCode: 1} {
+		var count int64
+		if err := db.Table("urls").Select("click_count").Where("short_code = ?", code).Scan(&count).Error; err != nil {
+			t.Fatal(err)
+		}
+		if count != want {
+			t.Errorf("click_count for %s = %d, want %d", code, count, want)
+		}
+		if pending := s.cacheManager.GetPendingClicks(code); pending != 0 {
+			t.Errorf("pending clicks for %s after Shutdown = %d", code, pending)
+		}
+	}
+	// 重复关闭不会panic，也不会重复计入
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatalf("second Shutdown: %v", err)
+	}
+	var count int64
+	db.Table("urls").Select("click_count").Where("id = ?", a.ID).Scan(&count)
+	if count != 3 {
+		t.Fatalf("click_count after second Shutdown = %d, want 3", count)
+	}
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestColdLookupsShareOneQuery(t *testing.T) {
+	s, repo, _ := newCountingService(t, newTestConfig())
+	url := mustCreate(t, s, "https://example.com/popular", "alice", URLOptions{})
+	s.cacheManager.DeleteURL(url.ShortCode) // 模拟重启或被淘汰后的冷缓存
+
+	// 第一个查询开始后保持未完成，让其余请求都在等待同一次加载
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	repo.beforeFind = func() {
+		once.Do(func() { close(entered) })
+		<-release
+	}
+
+	const concurrent = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, concurrent)
+	for i := 0; i < concurrent; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := s.GetURLByShortCode(url.ShortCode)
+			if err == nil && got.OriginalURL != url.OriginalURL {
+				t.Errorf("OriginalURL = %q, want %q", got.OriginalURL, url.OriginalURL)
+			}
+			errs <- err
+		}()
+	}
+	<-entered
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("GetURLByShortCode: %v", err)
+		}
+	}
+	if got := repo.findByCodeCalls.Load(); got != 1 {
+		t.Fatalf("FindByShortCode called %d times for %d concurrent cold lookups, want 1", got, concurrent)
+	}
+	// 加载后写入缓存，之后的请求不再查询数据库
+	if _, err := s.GetURLByShortCode(url.ShortCode); err != nil {
+		t.Fatal(err)
+	}
+	if got := repo.findByCodeCalls.Load(); got != 1 {
+		t.Fatalf("FindByShortCode called %d times after the cache was filled, want 1", got)
+	}
+}
+
+func TestColdLookupResultsAreIndependent(t *testing.T) {
+	s, _ := newTestService(t, newTestConfig())
+	url := mustCreate(t, s, "https://example.com/copy", "alice", URLOptions{})
+	s.cacheManager.DeleteURL(url.ShortCode)
+
+	first, err := s.GetURLByShortCode(url.ShortCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.OriginalURL = "https://changed.example/"
+
+	s.cacheManager.DeleteURL(url.ShortCode)
+	second, err := s.GetURLByShortCode(url.ShortCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.OriginalURL != url.OriginalURL {
+		t.Fatalf("second lookup sees caller's modification: %q", second.OriginalURL)
+	}
+}
+
+// BenchmarkGetURLByShortCode 跳转热路径：cached 为缓存命中，cold 每次查询前清除缓存
+// cold 报告的 db-queries/op 小于1说明并发的冷查询被合并
+func BenchmarkGetURLByShortCode(b *testing.B) {
+	b.Run("cached", func(b *testing.B) {
+		s, repo, _ := newCountingService(b, newTestConfig())
+		url := mustCreate(b, s, "https://example.com/hot", "alice", URLOptions{})
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if _, err := s.GetURLByShortCode(url.ShortCode); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		})
+		b.ReportMetric(float64(repo.findByCodeCalls.Load())/float64(b.N), "db-queries/op")
+	})
+
+	b.Run("cold", func(b *testing.B) {
+		s, repo, _ := newCountingService(b, newTestConfig())
+		url := mustCreate(b, s, "https://example.com/cold", "alice", URLOptions{})
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				s.cacheManager.DeleteURL(url.ShortCode)
+				if _, err := s.GetURLByShortCode(url.ShortCode); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		})
+		b.ReportMetric(float64(repo.findByCodeCalls.Load())/float64(b.N), "db-queries/op")
+	})
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStartsAtBoundary(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.NegativeCacheTTL = 30 // 负缓存远长于等待时间，生效后仍然必须可以跳转
+	s, _ := newTestService(t, cfg)
+	startsAt := time.Now().Add(100 * time.Millisecond)
+	url := mustCreate(t, s, "https://example.com/campaign", "alice", URLOptions{StartsAt: &startsAt})
+
+	for i := 0; i < 2; i++ {
+		if _, err := s.GetURLByShortCode(url.ShortCode); !errors.Is(err, ErrNotStarted) {
+			t.Fatalf("lookup #%d before StartsAt: err = %v, want ErrNotStarted", i+1, err)
+		}
+	}
+
+	time.Sleep(time.Until(startsAt))
+	if _, err := s.GetURLByShortCode(url.ShortCode); err != nil {
+		t.Fatalf("lookup at StartsAt: %v", err)
+	}
+	// 冷缓存时以数据库记录为准
+	s.cacheManager.DeleteURL(url.ShortCode)
+	if _, err := s.GetURLByShortCode(url.ShortCode); err != nil {
+		t.Fatalf("cold lookup after StartsAt: %v", err)
+	}
+}
+
+func TestUpdateStartsAt(t *testing.T) {
+	s, _ := newTestService(t, newTestConfig())
+	url := mustCreate(t, s, "https://example.com/later", "alice", URLOptions{})
+
+	future := time.Now().Add(time.Hour)
+	if _, err := s.UpdateURL(url.ID, "", "", nil, nil, "alice", URLOptions{StartsAt: &future}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetURLByShortCode(url.ShortCode); !errors.Is(err, ErrNotStarted) {
+		t.Fatalf("after setting a future StartsAt: err = %v, want ErrNotStarted", err)
+	}
+
+	// 零值取消生效时间
+	var zero time.Time
+	updated, err := s.UpdateURL(url.ID, "", "", nil, nil, "alice", URLOptions{StartsAt: &zero})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.StartsAt != nil {
+		t.Fatalf("StartsAt = %v after clearing", updated.StartsAt)
+	}
+	if _, err := s.GetURLByShortCode(url.ShortCode); err != nil {
+		t.Fatalf("after clearing StartsAt: %v", err)
+	}
+}
+
+func TestWarmupCachesScheduledLinks(t *testing.T) {
+	s, _ := newTestService(t, newTestConfig())
+	startsAt := time.Now().Add(time.Hour)
+	url := mustCreate(t, s, "https://example.com/scheduled", "alice", URLOptions{StartsAt: &startsAt})
+	s.cacheManager.DeleteURL(url.ShortCode)
+
+	s.WarmupCache()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, ok := s.cacheManager.GetURL(url.ShortCode); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("WarmupCache did not cache the scheduled link")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// 预热的记录同样检查生效时间
+	if _, err := s.GetURLByShortCode(url.ShortCode); !errors.Is(err, ErrNotStarted) {
+		t.Fatalf("err = %v, want ErrNotStarted", err)
+	}
+}
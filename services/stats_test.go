@@ -0,0 +1,65 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestURLStatsAdminAndPerUser(t *testing.T) {
+	s, _ := newTestService(t, newTestConfig())
+	active := mustCreate(t, s, "https://example.com/alice-1", "alice", URLOptions{})
+	disabled := mustCreate(t, s, "https://example.com/alice-2", "alice", URLOptions{})
+	expiring := mustCreate(t, s, "https://example.com/alice-3", "alice", URLOptions{})
+	bob1 := mustCreate(t, s, "https://example.com/bob-1", "bob", URLOptions{})
+	bob2 := mustCreate(t, s, "https://example.com/bob-2", "bob", URLOptions{})
+
+	if err := s.ToggleURLStatus(disabled.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Hour)
+	if _, err := s.UpdateURL(expiring.ID, "", "", &past, nil, "alice", URLOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	addClicks(t, s, active.ShortCode, 3)
+	addClicks(t, s, disabled.ShortCode, 1)
+	addClicks(t, s, bob1.ShortCode, 5)
+	addClicks(t, s, bob2.ShortCode, 2)
+	s.SyncClickCounts()
+
+	cases := map[string]URLStats{
+		"alice":  {TotalURLs: 3, ActiveURLs: 1, TotalClicks: 4},
+		"bob":    {TotalURLs: 2, ActiveURLs: 2, TotalClicks: 7},
+		"nobody": {},
+		"":       {TotalURLs: 5, ActiveURLs: 3, TotalClicks: 11}, // 管理员统计所有用户
+	}
+	for user, want := range cases {
+		got, err := s.GetURLStats(user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(*got, want) {
+			t.Errorf("GetURLStats(%q) = %+v, want %+v", user, *got, want)
+		}
+	}
+
+	// 全站统计的汇总等于各用户之和
+	global, err := s.GetGlobalStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*global.Totals, cases[""]) {
+		t.Fatalf("global totals = %+v, want %+v", *global.Totals, cases[""])
+	}
+	var urls, clicks int64
+	for _, u := range global.Users {
+		urls += u.URLs
+		clicks += u.Clicks
+	}
+	if urls != 5 || clicks != 11 {
+		t.Fatalf("per-user stats sum to %d urls / %d clicks, want 5 / 11", urls, clicks)
+	}
+	if len(global.Users) != 2 || global.Users[0].Username != "bob" {
+		t.Fatalf("Users = %+v, want bob first by clicks", global.Users)
+	}
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTagService(t *testing.T) *URLService {
+	t.Helper()
+	cfg := newTestConfig()
+	cfg.MaxTagsPerLink = 3
+	cfg.MaxTagLength = 8
+	s, _ := newTestService(t, cfg)
+	return s
+}
+
+func TestNormalizeTagsDedupes(t *testing.T) {
+	s := newTagService(t)
+	got, err := s.normalizeTags([]string{" Go ", "go", "GO", "", "  ", "Web", "web ", "api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"go", "web", "api"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("normalizeTags = %v, want %v", got, want)
+	}
+
+	// 去重后数量在限制内则允许
+	if _, err := s.normalizeTags([]string{"a", "A", "b", "B", "c", "C"}); err != nil {
+		t.Fatalf("duplicates counted towards the limit: %v", err)
+	}
+	if got, err := s.normalizeTags(nil); err != nil || len(got) != 0 {
+		t.Fatalf("normalizeTags(nil) = %v, %v", got, err)
+	}
+}
+
+func TestNormalizeTagsLimits(t *testing.T) {
+	s := newTagService(t)
+	cases := map[string][]string{
+		"每个链接最多3个标签":   {"a", "b", "c", "d"},
+		"标签长度不能超过8个字符": {"toolongtag"},
+		"标签不能包含逗号":     {"a,b"},
+	}
+	for wantErr, tags := range cases {
+		if _, err := s.normalizeTags(tags); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("normalizeTags(%q) error = %v, want %q", tags, err, wantErr)
+		}
+	}
+	// 长度按字符计算
+	if _, err := s.normalizeTags([]string{"八个字符的标签名"}); err != nil {
+		t.Errorf("8-rune tag rejected: %v", err)
+	}
+}
+
+func TestTagLimitsOnCreateAndUpdate(t *testing.T) {
+	s := newTagService(t)
+	if _, err := s.CreateShortURL("https://example.com/tags", "", "", "", nil, "alice", true, URLOptions{Tags: []string{"a", "b", "c", "d"}}); err == nil {
+		t.Fatal("create accepted too many tags")
+	}
+
+	url := mustCreate(t, s, "https://example.com/tags", "alice", URLOptions{Tags: []string{"News", "news", "Tech"}})
+	if want := []string{"news", "tech"}; !reflect.DeepEqual([]string(url.Tags), want) {
+		t.Fatalf("created tags = %v, want %v", url.Tags, want)
+	}
+	if _, err := s.UpdateURL(url.ID, "", "", nil, nil, "alice", URLOptions{Tags: []string{"a", "b", "c", "d"}}); err == nil {
+		t.Fatal("update accepted too many tags")
+	}
+	updated, err := s.UpdateURL(url.ID, "", "", nil, nil, "alice", URLOptions{Tags: []string{"X", "x"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"x"}; !reflect.DeepEqual([]string(updated.Tags), want) {
+		t.Fatalf("updated tags = %v, want %v", updated.Tags, want)
+	}
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/justseemore/surl/cache"
+	"github.com/justseemore/surl/config"
+	"github.com/justseemore/surl/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB 创建迁移好的内存SQLite数据库
+// 只使用一个连接：每个 :memory: 连接都是独立的数据库
+func newTestDB(t testing.TB) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger:  logger.Default.LogMode(logger.Silent),
+		NowFunc: models.Now,
+	})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("sqlite handle: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(&models.URL{}, &models.CountryClick{}, &models.Account{}, &models.Click{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+// newTestConfig 返回默认配置；测试不访问网络，关闭需要DNS解析的内网地址检查
+func newTestConfig() *config.Config {
+	cfg := config.Load()
+	cfg.BlockPrivateHosts = false
+	return cfg
+}
+
+// newTestCache 创建只使用内存的缓存管理器
+func newTestCache() *cache.Manager {
+	return cache.NewCacheManager("", "", 0, 60, 1000, "")
+}
+
+// newCountingService 创建记录查询次数的服务
+func newCountingService(t testing.TB, cfg *config.Config) (*URLService, *countingRepo, *gorm.DB) {
+	t.Helper()
+	db := newTestDB(t)
+	repo := &countingRepo{URLRepository: NewGormURLRepository(db)}
+	return NewURLServiceWithRepository(newTestCache(), repo, cfg, nil), repo, db
+}
+
+// newTestService 创建使用内存数据库和内存缓存的服务
+func newTestService(t testing.TB, cfg *config.Config) (*URLService, *gorm.DB) {
+	t.Helper()
+	db := newTestDB(t)
+	return NewURLService(newTestCache(), db, cfg, nil), db
+}
+
+// mustCreate 创建短链接，失败时结束测试
+func mustCreate(t testing.TB, s *URLService, originalURL, createdBy string, opts URLOptions) *models.URL {
+	t.Helper()
+	url, err := s.CreateShortURL(originalURL, "", "", "", nil, createdBy, true, opts)
+	if err != nil {
+		t.Fatalf("create %s: %v", originalURL, err)
+	}
+	return url
+}
+
+// addClicks 增加点击计数并等待异步计数完成
+func addClicks(t testing.TB, s *URLService, shortCode string, n int) {
+	t.Helper()
+	want := s.cacheManager.GetPendingClicks(shortCode) + int64(n)
+	for i := 0; i < n; i++ {
+		s.IncrementClickCount(shortCode)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for s.cacheManager.GetPendingClicks(shortCode) < want {
+		if time.Now().After(deadline) {
+			t.Fatalf("pending clicks for %s did not reach %d", shortCode, want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// countingRepo 记录热路径上的查询次数，用于确认是否访问数据库
+type countingRepo struct {
+	URLRepository
+	clickCountCalls atomic.Int64
+	findByCodeCalls atomic.Int64
+	addClicksCalls  atomic.Int64
+
+	beforeFind      func() // 不为nil时在每次 FindByShortCode 查询前调用，用于模拟慢查询
+	beforeAddClicks func() // 不为nil时在每次 AddClicks 写入前调用，用于模拟慢同步
+}
+
+func (r *countingRepo) FindByShortCode(shortCode string) (*models.URL, error) {
+	r.findByCodeCalls.Add(1)
+	if r.beforeFind != nil {
+		r.beforeFind()
+	}
+	return r.URLRepository.FindByShortCode(shortCode)
+}
+
+func (r *countingRepo) AddClicks(shortCode string, count int64) error {
+	r.addClicksCalls.Add(1)
+	if r.beforeAddClicks != nil {
+		r.beforeAddClicks()
+	}
+	return r.URLRepository.AddClicks(shortCode, count)
+}
+
+func (r *countingRepo) ClickCount(id uint) (int64, error) {
+	r.clickCountCalls.Add(1)
+	return r.URLRepository.ClickCount(id)
+}
+
+func int64Ptr(v int64) *int64 { return &v }
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/justseemore/surl/models"
+)
+
+func TestTodayClicksUseConfiguredTimezone(t *testing.T) {
+	// 东京午夜与UTC午夜相差9小时，两个边界之间的点击只有按配置时区统计时才能得到1
+	t.Setenv("TIMEZONE", "Asia/Tokyo")
+	cfg := newTestConfig()
+	s, db := newTestService(t, cfg)
+	url := mustCreate(t, s, "https://example.com/tz", "alice", URLOptions{})
+
+	now := time.Now().In(cfg.Location())
+	todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	clicks := []models.Click{
+		{ShortCode: url.ShortCode, ClickedAt: todayStart.Add(-time.Second).UTC(), Weight: 1},
+		{ShortCode: url.ShortCode, ClickedAt: todayStart.UTC(), Weight: 1},
+	}
+	if err := db.Create(&clicks).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := s.GetURLStats("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.TodayClicks != 1 {
+		t.Fatalf("TodayClicks = %d, want 1", stats.TodayClicks)
+	}
+}
+
+func TestTimestampsStoredInUTC(t *testing.T) {
+	s, db := newTestService(t, newTestConfig())
+	tokyo := time.FixedZone("JST", 9*60*60)
+	startsAt := time.Date(2030, 1, 2, 9, 0, 0, 0, tokyo)
+	expiresAt := time.Date(2030, 2, 3, 9, 0, 0, 0, tokyo)
+
+	url := mustCreate(t, s, "https://example.com/utc", "alice", URLOptions{StartsAt: &startsAt})
+	var stored models.URL
+	if err := db.First(&stored, url.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.StartsAt == nil || stored.StartsAt.Location() != time.UTC || !stored.StartsAt.Equal(startsAt) {
+		t.Fatalf("created starts_at = %v, want %v in UTC", stored.StartsAt, startsAt)
+	}
+
+	later := startsAt.Add(time.Hour)
+	if _, err := s.UpdateURL(url.ID, "", "", &expiresAt, nil, "alice", URLOptions{StartsAt: &later}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.First(&stored, url.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.StartsAt == nil || stored.StartsAt.Location() != time.UTC || !stored.StartsAt.Equal(later) {
+		t.Errorf("updated starts_at = %v, want %v in UTC", stored.StartsAt, later)
+	}
+	if stored.ExpiresAt == nil || stored.ExpiresAt.Location() != time.UTC || !stored.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("updated expires_at = %v, want %v in UTC", stored.ExpiresAt, expiresAt)
+	}
+}
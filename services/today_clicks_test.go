@@ -0,0 +1,48 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/justseemore/surl/models"
+)
+
+func TestTodayClicksUsesConfiguredDay(t *testing.T) {
+	t.Setenv("TIMEZONE", "Asia/Tokyo")
+	s, db := newTestService(t, newTestConfig())
+	own := mustCreate(t, s, "https://example.com/today", "alice", URLOptions{})
+	other := mustCreate(t, s, "https://example.com/others", "bob", URLOptions{})
+	deleted := mustCreate(t, s, "https://example.com/deleted", "alice", URLOptions{})
+
+	now := time.Now().In(s.config.Location())
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	clicks := []models.Click{
+		{ShortCode: own.ShortCode, ClickedAt: now, Weight: 1},
+		{ShortCode: own.ShortCode, ClickedAt: midnight, Weight: 1},
+		{ShortCode: own.ShortCode, ClickedAt: midnight.Add(30 * time.Minute), Weight: 2.5}, // 抽样记录按权重计入
+		{ShortCode: own.ShortCode, ClickedAt: midnight.Add(-time.Minute), Weight: 1},       // 配置时区的昨天
+		{ShortCode: other.ShortCode, ClickedAt: now, Weight: 1},
+		{ShortCode: deleted.ShortCode, ClickedAt: now, Weight: 1},
+	}
+	// 与 RecordClick 一致，明细时间以UTC保存
+	for i := range clicks {
+		clicks[i].ClickedAt = clicks[i].ClickedAt.UTC()
+	}
+	if err := db.Create(&clicks).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteURL(deleted.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]int64{"alice": 5, "bob": 1, "": 6}
+	for user, want := range cases {
+		stats, err := s.GetURLStats(user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.TodayClicks != want {
+			t.Errorf("GetURLStats(%q).TodayClicks = %d, want %d", user, stats.TodayClicks, want)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/justseemore/surl/models"
+	"gorm.io/gorm"
+)
+
+// createAt 创建短链接并把创建时间改为指定时刻
+func createAt(t *testing.T, s *URLService, db *gorm.DB, originalURL, createdBy string, createdAt time.Time) *models.URL {
+	t.Helper()
+	url := mustCreate(t, s, originalURL, createdBy, URLOptions{})
+	if err := db.Model(&models.URL{}).Where("id = ?", url.ID).Update("created_at", createdAt.UTC()).Error; err != nil {
+		t.Fatal(err)
+	}
+	return url
+}
+
+func timePtr(v time.Time) *time.Time { return &v }
+
+func TestListCreatedDateRange(t *testing.T) {
+	s, db := newTestService(t, newTestConfig())
+	weekStart := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	createAt(t, s, db, "https://example.com/before", "alice", weekStart.Add(-time.Second))
+	createAt(t, s, db, "https://example.com/start", "alice", weekStart)
+	createAt(t, s, db, "https://example.com/middle", "alice", weekStart.Add(3*24*time.Hour))
+	createAt(t, s, db, "https://example.com/end", "alice", weekStart.Add(7*24*time.Hour))
+	createAt(t, s, db, "https://example.com/other-owner", "bob", weekStart.Add(time.Hour))
+
+	weekEnd := weekStart.Add(7 * 24 * time.Hour)
+	cases := []struct {
+		name   string
+		filter URLListFilter
+		want   []string
+	}{
+		{"window", URLListFilter{CreatedFrom: &weekStart, CreatedTo: &weekEnd},
+			[]string{"https://example.com/middle", "https://example.com/other-owner", "https://example.com/start"}},
+		{"from only", URLListFilter{CreatedFrom: timePtr(weekStart.Add(24 * time.Hour))},
+			[]string{"https://example.com/end", "https://example.com/middle"}},
+		{"to only", URLListFilter{CreatedTo: &weekStart},
+			[]string{"https://example.com/before"}},
+		{"combined with owner and search", URLListFilter{CreatedFrom: &weekStart, CreatedTo: &weekEnd, CreatedBy: "alice", Search: "mid"},
+			[]string{"https://example.com/middle"}},
+		{"empty window", URLListFilter{CreatedFrom: &weekEnd, CreatedTo: &weekStart}, []string{}},
+	}
+	for _, tc := range cases {
+		if got := searchTargets(t, s, tc.filter); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestRequireSearch(t *testing.T) {
+	s, _ := newTestService(t, newTestConfig())
+	mustCreate(t, s, "https://example.com/a", "alice", URLOptions{Tags: []string{"promo"}})
+	mustCreate(t, s, "https://other.org/b", "alice", URLOptions{})
+
+	cases := []struct {
+		filter URLListFilter
+		want   int
+	}{
+		{URLListFilter{}, 2},
+		{URLListFilter{RequireSearch: true}, 0},
+		{URLListFilter{RequireSearch: true, Search: "   "}, 0},
+		{URLListFilter{RequireSearch: true, Search: "other"}, 1},
+		{URLListFilter{RequireSearch: true, Host: "example.com"}, 1},
+		{URLListFilter{RequireSearch: true, Tag: " PROMO "}, 1},
+	}
+	for _, tc := range cases {
+		if got := searchTargets(t, s, tc.filter); len(got) != tc.want {
+			t.Errorf("%+v: got %v, want %d urls", tc.filter, got, tc.want)
+		}
+	}
+}
+
+func TestListMultiStatusAndOwner(t *testing.T) {
+	s, db := newTestService(t, newTestConfig())
+	mustCreate(t, s, "https://example.com/alice-active", "alice", URLOptions{})
+	disabled := mustCreate(t, s, "https://example.com/alice-disabled", "alice", URLOptions{})
+	if err := s.ToggleURLStatus(disabled.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	expired := mustCreate(t, s, "https://example.com/bob-expired", "bob", URLOptions{})
+	if err := db.Model(&models.URL{}).Where("id = ?", expired.ID).Update("expires_at", time.Now().Add(-time.Hour).UTC()).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 达到点击上限同样视为过期
+	limited := mustCreate(t, s, "https://example.com/carol-limited", "carol", URLOptions{MaxClicks: int64Ptr(1)})
+	if err := db.Model(&models.URL{}).Where("id = ?", limited.ID).Update("click_count", 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 禁用优先于过期
+	both := mustCreate(t, s, "https://example.com/bob-disabled-expired", "bob", URLOptions{})
+	if err := db.Model(&models.URL{}).Where("id = ?", both.ID).Updates(map[string]interface{}{"is_active": false, "expires_at": time.Now().Add(-time.Hour).UTC()}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		filter URLListFilter
+		want   []string
+	}{
+		{"active", URLListFilter{Statuses: []string{URLStatusActive}},
+			[]string{"https://example.com/alice-active"}},
+		{"active or expired", URLListFilter{Statuses: []string{URLStatusActive, URLStatusExpired}},
+			[]string{"https://example.com/alice-active", "https://example.com/bob-expired", "https://example.com/carol-limited"}},
+		{"inactive", URLListFilter{Statuses: []string{URLStatusInactive}},
+			[]string{"https://example.com/alice-disabled", "https://example.com/bob-disabled-expired"}},
+		{"two owners", URLListFilter{Owners: []string{"alice", "carol"}},
+			[]string{"https://example.com/alice-active", "https://example.com/alice-disabled", "https://example.com/carol-limited"}},
+		{"owners and statuses", URLListFilter{Owners: []string{"alice", "bob"}, Statuses: []string{URLStatusExpired, URLStatusInactive}},
+			[]string{"https://example.com/alice-disabled", "https://example.com/bob-disabled-expired", "https://example.com/bob-expired"}},
+		{"owners intersect CreatedBy", URLListFilter{Owners: []string{"alice", "bob"}, CreatedBy: "bob"},
+			[]string{"https://example.com/bob-disabled-expired", "https://example.com/bob-expired"}},
+		{"owner with quote", URLListFilter{Owners: []string{"alice' OR '1'='1"}}, []string{}},
+	}
+	for _, tc := range cases {
+		if got := searchTargets(t, s, tc.filter); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestListHostFilter(t *testing.T) {
+	s, _ := newTestService(t, newTestConfig())
+	mustCreate(t, s, "https://example.com/root", "alice", URLOptions{})
+	mustCreate(t, s, "https://www.example.com/www", "alice", URLOptions{Tags: []string{"promo"}})
+	mustCreate(t, s, "https://shop.EU.example.com:8443/deep", "alice", URLOptions{})
+	mustCreate(t, s, "https://notexample.com/lookalike", "alice", URLOptions{})
+	mustCreate(t, s, "https://example.com.evil.org/suffix", "alice", URLOptions{})
+	mustCreate(t, s, "https://example.com/bob", "bob", URLOptions{})
+
+	cases := []struct {
+		name   string
+		filter URLListFilter
+		want   []string
+	}{
+		{"domain and subdomains", URLListFilter{Host: "example.com"}, []string{
+			"https://example.com/bob", "https://example.com/root", "https://shop.eu.example.com:8443/deep", "https://www.example.com/www"}},
+		{"exact subdomain", URLListFilter{Host: "www.example.com"}, []string{"https://www.example.com/www"}},
+		{"nested subdomain", URLListFilter{Host: "eu.example.com"}, []string{"https://shop.eu.example.com:8443/deep"}},
+		{"with owner", URLListFilter{Host: "example.com", CreatedBy: "bob"}, []string{"https://example.com/bob"}},
+		{"with tag", URLListFilter{Host: "example.com", Tag: "promo"}, []string{"https://www.example.com/www"}},
+		{"with search", URLListFilter{Host: "example.com", Search: "root"}, []string{"https://example.com/root"}},
+		{"no match", URLListFilter{Host: "example.net"}, []string{}},
+	}
+	for _, tc := range cases {
+		if got := searchTargets(t, s, tc.filter); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestListExpiresBeforeWithStatusAndCreated(t *testing.T) {
+	s, db := newTestService(t, newTestConfig())
+	now := time.Now().UTC().Truncate(time.Second)
+	expiresAt := func(url *models.URL, at time.Time) {
+		t.Helper()
+		if err := db.Model(&models.URL{}).Where("id = ?", url.ID).Update("expires_at", at).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	lastWeek := now.Add(-7 * 24 * time.Hour)
+
+	soon := createAt(t, s, db, "https://example.com/soon", "alice", now)
+	expiresAt(soon, now.Add(2*24*time.Hour))
+	later := createAt(t, s, db, "https://example.com/later", "alice", now)
+	expiresAt(later, now.Add(30*24*time.Hour))
+	gone := createAt(t, s, db, "https://example.com/gone", "alice", lastWeek)
+	expiresAt(gone, now.Add(-time.Hour))
+	disabledSoon := createAt(t, s, db, "https://example.com/disabled-soon", "alice", lastWeek)
+	expiresAt(disabledSoon, now.Add(24*time.Hour))
+	if err := s.ToggleURLStatus(disabledSoon.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	never := createAt(t, s, db, "https://example.com/never", "alice", now)
+	if err := db.Model(&models.URL{}).Where("id = ?", never.ID).Update("expires_at", nil).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	week := now.Add(7 * 24 * time.Hour)
+	yesterday := now.Add(-24 * time.Hour)
+	cases := []struct {
+		name   string
+		filter URLListFilter
+		want   []string
+	}{
+		// 不包含未设置过期时间的链接
+		{"expiring this week", URLListFilter{ExpiresBefore: &week},
+			[]string{"https://example.com/disabled-soon", "https://example.com/gone", "https://example.com/soon"}},
+		{"active and expiring this week", URLListFilter{ExpiresBefore: &week, Statuses: []string{URLStatusActive}},
+			[]string{"https://example.com/soon"}},
+		// status=expired 只匹配已过期且仍启用的链接
+		{"expired", URLListFilter{Statuses: []string{URLStatusExpired}},
+			[]string{"https://example.com/gone"}},
+		{"inactive and expiring", URLListFilter{ExpiresBefore: &week, Statuses: []string{URLStatusInactive}},
+			[]string{"https://example.com/disabled-soon"}},
+		{"created recently and expiring", URLListFilter{ExpiresBefore: &week, CreatedFrom: &yesterday},
+			[]string{"https://example.com/soon"}},
+		{"created before and expired", URLListFilter{CreatedTo: &yesterday, Statuses: []string{URLStatusExpired}},
+			[]string{"https://example.com/gone"}},
+		{"all filters, no match", URLListFilter{ExpiresBefore: &week, CreatedFrom: &yesterday, Statuses: []string{URLStatusExpired}},
+			[]string{}},
+	}
+	for _, tc := range cases {
+		if got := searchTargets(t, s, tc.filter); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestListFilterKeepsPaginationAndOrder(t *testing.T) {
+	s, db := newTestService(t, newTestConfig())
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		createAt(t, s, db, fmt.Sprintf("https://example.com/match-%d", i), "alice", base.Add(time.Duration(i)*time.Hour))
+	}
+	disabled := createAt(t, s, db, "https://example.com/disabled", "alice", base.Add(10*time.Hour))
+	if err := s.ToggleURLStatus(disabled.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	filter := URLListFilter{Statuses: []string{URLStatusActive}, CreatedFrom: &base}
+	var pages [][]string
+	for page := 1; page <= 3; page++ {
+		urls, total, err := s.GetURLList(page, 2, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != 5 {
+			t.Fatalf("page %d: total = %d, want 5", page, total)
+		}
+		targets := make([]string, len(urls))
+		for i, url := range urls {
+			targets[i] = url.OriginalURL
+		}
+		pages = append(pages, targets)
+	}
+	// 按创建时间倒序分页
+	want := [][]string{
+		{"https://example.com/match-4", "https://example.com/match-3"},
+		{"https://example.com/match-2", "https://example.com/match-1"},
+		{"https://example.com/match-0"},
+	}
+	if !reflect.DeepEqual(pages, want) {
+		t.Fatalf("pages = %v, want %v", pages, want)
+	}
+}
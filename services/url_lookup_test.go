@@ -0,0 +1,113 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/justseemore/surl/cache"
+	"github.com/justseemore/surl/models"
+)
+
+// cacheExpired 让缓存中的记录已过期，模拟缓存写入后链接过期
+func cacheExpired(t *testing.T, s *URLService, url *models.URL) {
+	t.Helper()
+	past := models.Now().Add(-time.Hour)
+	expired := *url
+	expired.ExpiresAt = &past
+	s.cacheManager.SetURL(url.ShortCode, cache.NewCachedURL(&expired))
+}
+
+func TestGetURLByShortCodeRefreshesExtendedExpiry(t *testing.T) {
+	s, db := newTestService(t, newTestConfig())
+	url := mustCreate(t, s, "https://example.com/extend", "alice", URLOptions{})
+	cacheExpired(t, s, url)
+
+	// 另一个实例延长了有效期，本实例缓存中的记录仍是过期的
+	future := models.Now().Add(24 * time.Hour)
+	if err := db.Model(url).Update("expires_at", future).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.GetURLByShortCode(url.ShortCode)
+	if err != nil {
+		t.Fatalf("GetURLByShortCode after extending expiry: %v", err)
+	}
+	if got.ExpiresAt == nil || !got.ExpiresAt.Equal(future) {
+		t.Fatalf("ExpiresAt = %v, want %v", got.ExpiresAt, future)
+	}
+	// 缓存已按数据库记录刷新
+	cached, found := s.cacheManager.GetURL(url.ShortCode)
+	if !found || cached.ExpiresAt == nil || !cached.ExpiresAt.Equal(future) {
+		t.Fatalf("cache not refreshed: %+v", cached)
+	}
+}
+
+func TestGetURLByShortCodeCachesUnavailable(t *testing.T) {
+	s, repo, db := newCountingService(t, newTestConfig())
+	url := mustCreate(t, s, "https://example.com/expired", "alice", URLOptions{})
+	past := models.Now().Add(-time.Hour)
+	if err := db.Model(url).Update("expires_at", past).Error; err != nil {
+		t.Fatal(err)
+	}
+	cacheExpired(t, s, url)
+
+	for i := 0; i < 5; i++ {
+		if _, err := s.GetURLByShortCode(url.ShortCode); !errors.Is(err, ErrExpired) {
+			t.Fatalf("lookup %d: err = %v, want ErrExpired", i+1, err)
+		}
+	}
+	if repo.findByCodeCalls.Load() != 1 {
+		t.Fatalf("FindByShortCode called %d times, want 1", repo.findByCodeCalls.Load())
+	}
+
+	// 通过服务延长有效期后立即生效，不等待标记过期
+	future := models.Now().Add(24 * time.Hour)
+	if _, err := s.UpdateURL(url.ID, "", "", &future, nil, "alice", URLOptions{}); err != nil {
+		t.Fatalf("UpdateURL: %v", err)
+	}
+	if _, err := s.GetURLByShortCode(url.ShortCode); err != nil {
+		t.Fatalf("lookup after extending expiry: %v", err)
+	}
+}
+
+func TestGetURLByShortCodeUnavailableCacheDisabled(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.NegativeCacheTTL = 0
+	s, repo, db := newCountingService(t, cfg)
+	url := mustCreate(t, s, "https://example.com/disabled", "alice", URLOptions{})
+	if err := db.Model(url).Update("is_active", false).Error; err != nil {
+		t.Fatal(err)
+	}
+	disabled := *url
+	disabled.IsActive = false
+	s.cacheManager.SetURL(url.ShortCode, cache.NewCachedURL(&disabled))
+
+	for i := 0; i < 3; i++ {
+		if _, err := s.GetURLByShortCode(url.ShortCode); !errors.Is(err, ErrDisabled) {
+			t.Fatalf("lookup %d: err = %v, want ErrDisabled", i+1, err)
+		}
+	}
+	if repo.findByCodeCalls.Load() != 3 {
+		t.Fatalf("FindByShortCode called %d times, want 3 with NEGATIVE_CACHE_TTL=0", repo.findByCodeCalls.Load())
+	}
+}
+
+func TestNegativeCacheThenCreate(t *testing.T) {
+	s, repo, _ := newCountingService(t, newTestConfig())
+
+	for i := 0; i < 3; i++ {
+		if _, err := s.GetURLByShortCode("later1"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("lookup %d: err = %v, want ErrNotFound", i+1, err)
+		}
+	}
+	if repo.findByCodeCalls.Load() != 1 {
+		t.Fatalf("FindByShortCode called %d times, want 1", repo.findByCodeCalls.Load())
+	}
+
+	// 创建后立即可以访问，不等待负缓存过期
+	mustCreate(t, s, "https://example.com/later", "alice", URLOptions{CustomCode: "later1"})
+	if _, err := s.GetURLByShortCode("later1"); err != nil {
+		t.Fatalf("lookup after create: %v", err)
+	}
+}
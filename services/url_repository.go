@@ -0,0 +1,376 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/justseemore/surl/models"
+	"gorm.io/gorm"
+)
+
+// ErrRecordNotFound 仓储中没有符合条件的记录，各实现需将自身的"未找到"错误转换为该错误
+var ErrRecordNotFound = errors.New("记录不存在")
+
+// URLRepository 短链接的持久化接口，URLService 只通过该接口访问存储
+// 除特别说明外，查询不包含已软删除的记录；createdBy 为空表示不按创建者过滤
+type URLRepository interface {
+	Create(url *models.URL) error
+	FindByID(id uint) (*models.URL, error)
+	FindByShortCode(shortCode string) (*models.URL, error)
+	// FindByOriginalURL 返回目标地址相同的最新一条记录
+	FindByOriginalURL(originalURL, createdBy string) (*models.URL, error)
+	FindByIDs(ids []uint, createdBy string) ([]models.URL, error)
+	FindByShortCodes(shortCodes []string, createdBy string) ([]models.URL, error)
+	// ShortCodeExists 检查短代码是否已被其他记录使用，excludeID 为0时不排除任何记录
+	ShortCodeExists(shortCode string, excludeID uint) (bool, error)
+	// ClickCount 返回已写入存储的点击数
+	ClickCount(id uint) (int64, error)
+	// List 按过滤条件分页查询，按创建时间倒序，同时返回总数
+	List(offset, limit int, filter URLListFilter) ([]models.URL, int64, error)
+	Stats(createdBy string) (*URLStats, error)
+	// ClicksSince 按权重汇总 since 之后的点击明细（四舍五入），只统计未删除的链接
+	ClicksSince(createdBy string, since time.Time) (int64, error)
+	// UserStats 按创建者汇总链接数和点击数，按点击数倒序
+	UserStats() ([]UserStats, error)
+	// TopURLs 按 by（TopByClicks 或 TopByCreated）倒序返回前 limit 条记录
+	TopURLs(limit int, by string, createdBy string) ([]models.URL, error)
+
+	Update(id uint, updates map[string]interface{}) error
+	// UpdateRecord 更新记录并将修改写回 url，调用方无需重新查询
+	UpdateRecord(url *models.URL, updates map[string]interface{}) error
+	UpdateByIDs(ids []uint, createdBy string, updates map[string]interface{}) error
+	// AddClicks 将点击数累加到指定短代码
+	AddClicks(shortCode string, count int64) error
+
+	// Delete 和 DeleteByIDs 为软删除，记录进入回收站
+	Delete(id uint) error
+	DeleteByIDs(ids []uint) error
+	// ListDeleted 分页查询回收站，按删除时间倒序
+	ListDeleted(offset, limit int, createdBy string) ([]models.URL, int64, error)
+	FindDeleted(id uint, createdBy string) (*models.URL, error)
+	Restore(id uint) error
+	// PurgeDeleted 永久删除删除时间早于 before 的记录，返回删除的数量
+	PurgeDeleted(before time.Time) (int64, error)
+
+	// FindActive 返回启用且在 now 时未过期的记录
+	FindActive(now time.Time) ([]models.URL, error)
+	// FindExpiredActive 返回仍处于启用状态但在 now 时已过期的记录
+	FindExpiredActive(now time.Time) ([]models.URL, error)
+	// DeactivateExpired 停用在 now 时已过期的记录
+	DeactivateExpired(now time.Time) error
+
+	// Transaction 在同一个事务中执行 fn，fn 返回错误时回滚；fn 内只能使用传入的 repo
+	Transaction(fn func(repo URLRepository) error) error
+}
+
+// gormURLRepository 基于GORM的默认实现
+type gormURLRepository struct {
+	db *gorm.DB
+}
+
+// NewGormURLRepository 创建基于GORM的短链接仓储
+func NewGormURLRepository(db *gorm.DB) URLRepository {
+	return &gormURLRepository{db: db}
+}
+
+// notFound 将GORM的未找到错误转换为 ErrRecordNotFound
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrRecordNotFound
+	}
+	return err
+}
+
+// byCreator 按创建者过滤，createdBy 为空时不过滤
+func byCreator(query *gorm.DB, createdBy string) *gorm.DB {
+	if createdBy != "" {
+		return query.Where("created_by = ?", createdBy)
+	}
+	return query
+}
+
+func (r *gormURLRepository) Create(url *models.URL) error {
+	return r.db.Create(url).Error
+}
+
+func (r *gormURLRepository) FindByID(id uint) (*models.URL, error) {
+	var url models.URL
+	if err := r.db.First(&url, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &url, nil
+}
+
+func (r *gormURLRepository) FindByShortCode(shortCode string) (*models.URL, error) {
+	var url models.URL
+	if err := r.db.Where("short_code = ?", shortCode).First(&url).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &url, nil
+}
+
+func (r *gormURLRepository) FindByOriginalURL(originalURL, createdBy string) (*models.URL, error) {
+	var url models.URL
+	query := byCreator(r.db.Where("original_url = ?", originalURL), createdBy)
+	if err := query.Order("created_at DESC").First(&url).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &url, nil
+}
+
+func (r *gormURLRepository) FindByIDs(ids []uint, createdBy string) ([]models.URL, error) {
+	var urls []models.URL
+	err := byCreator(r.db.Where("id IN ?", ids), createdBy).Find(&urls).Error
+	return urls, err
+}
+
+func (r *gormURLRepository) FindByShortCodes(shortCodes []string, createdBy string) ([]models.URL, error) {
+	var urls []models.URL
+	err := byCreator(r.db.Where("short_code IN ?", shortCodes), createdBy).Find(&urls).Error
+	return urls, err
+}
+
+func (r *gormURLRepository) ShortCodeExists(shortCode string, excludeID uint) (bool, error) {
+	query := r.db.Model(&models.URL{}).Where("short_code = ?", shortCode)
+	if excludeID != 0 {
+		query = query.Where("id <> ?", excludeID)
+	}
+	var count int64
+	err := query.Count(&count).Error
+	return count > 0, err
+}
+
+func (r *gormURLRepository) ClickCount(id uint) (int64, error) {
+	var clickCount int64
+	err := r.db.Model(&models.URL{}).Select("click_count").Where("id = ?", id).Scan(&clickCount).Error
+	return clickCount, err
+}
+
+func (r *gormURLRepository) List(offset, limit int, filter URLListFilter) ([]models.URL, int64, error) {
+	var urls []models.URL
+	var total int64
+
+	query := byCreator(r.db.Model(&models.URL{}), filter.CreatedBy)
+
+	if len(filter.Owners) > 0 {
+		query = query.Where("created_by IN ?", filter.Owners)
+	}
+
+	// 状态过滤
+	if cond, args := statusCondition(filter.Statuses); cond != "" {
+		query = query.Where(cond, args...)
+	}
+
+	// 搜索过滤（不区分大小写），转义通配符，搜索词中的 % 和 _ 按字面匹配
+	if search := filter.Search; search != "" {
+		query = query.Where(r.searchCondition(), sql.Named("pattern", "%"+likeEscaper.Replace(search)+"%"))
+	}
+
+	// 目标主机过滤（精确匹配或子域名）
+	if host := filter.Host; host != "" {
+		query = query.Where("(host = ? OR host LIKE ?)", host, "%."+host)
+	}
+
+	// 标签过滤，标签保存为 ",a,b," 形式；转义通配符，保证含 _ 或 % 的标签精确匹配
+	if tag := filter.Tag; tag != "" {
+		query = query.Where("tags LIKE ? ESCAPE '!'", "%,"+likeEscaper.Replace(tag)+",%")
+	}
+
+	// 创建时间范围过滤
+	if filter.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where("created_at < ?", *filter.CreatedTo)
+	}
+
+	// 过期时间上限，不包含未设置过期时间的URL
+	if filter.ExpiresBefore != nil {
+		query = query.Where("expires_at IS NOT NULL AND expires_at < ?", *filter.ExpiresBefore)
+	}
+
+	// 获取总数
+	query.Count(&total)
+
+	// 分页查询
+	err := query.Offset(offset).Limit(limit).Order("created_at DESC").Find(&urls).Error
+	return urls, total, err
+}
+
+// likeEscaper 转义 LIKE 模式中的通配符，配合 ESCAPE '!' 使用（避免反斜杠在各数据库中的转义差异）
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// searchCondition 返回在目标URL、标题、描述和短代码中不区分大小写搜索的条件，参数为单个 LIKE 模式
+// ILIKE 只有 PostgreSQL 支持，其他数据库统一转小写后使用 LIKE
+func (r *gormURLRepository) searchCondition() string {
+	columns := []string{"original_url", "title", "description", "short_code"}
+	parts := make([]string, len(columns))
+	for i, column := range columns {
+		if r.db.Dialector.Name() == models.DriverPostgres {
+			parts[i] = column + " ILIKE @pattern ESCAPE '!'"
+		} else {
+			parts[i] = "LOWER(" + column + ") LIKE LOWER(@pattern) ESCAPE '!'"
+		}
+	}
+	return "(" + strings.Join(parts, " OR ") + ")"
+}
+
+// statusCondition 将多个状态组合为 OR 条件，返回条件语句及参数
+func statusCondition(statuses []string) (string, []interface{}) {
+	now := models.Now()
+	var parts []string
+	var args []interface{}
+	for _, status := range statuses {
+		switch status {
+		case URLStatusActive:
+			parts = append(parts, "(is_active = ? AND (expires_at IS NULL OR expires_at > ?) AND (max_clicks IS NULL OR click_count < max_clicks))")
+			args = append(args, true, now)
+		case URLStatusInactive:
+			parts = append(parts, "is_active = ?")
+			args = append(args, false)
+		case URLStatusExpired:
+			parts = append(parts, "(is_active = ? AND ((expires_at IS NOT NULL AND expires_at <= ?) OR (max_clicks IS NOT NULL AND click_count >= max_clicks)))")
+			args = append(args, true, now)
+		}
+	}
+	if len(parts) == 0 {
+		return "", nil
+	}
+	return "(" + strings.Join(parts, " OR ") + ")", args
+}
+
+func (r *gormURLRepository) Stats(createdBy string) (*URLStats, error) {
+	stats := &URLStats{}
+
+	// 每项统计使用独立的查询，避免条件在链式调用之间累积
+	urls := func() *gorm.DB {
+		return byCreator(r.db.Model(&models.URL{}), createdBy)
+	}
+	// 总URL数
+	if err := urls().Count(&stats.TotalURLs).Error; err != nil {
+		return nil, err
+	}
+	// 活跃URL数
+	if err := urls().Where("is_active = ? AND (expires_at IS NULL OR expires_at > ?)", true, models.Now()).Count(&stats.ActiveURLs).Error; err != nil {
+		return nil, err
+	}
+	// 总点击数
+	if err := urls().Select("COALESCE(SUM(click_count), 0)").Scan(&stats.TotalClicks).Error; err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func (r *gormURLRepository) UserStats() ([]UserStats, error) {
+	var stats []UserStats
+	err := r.db.Model(&models.URL{}).
+		Select("created_by AS username, COUNT(*) AS urls, COALESCE(SUM(click_count), 0) AS clicks").
+		Group("created_by").
+		Order("clicks DESC, username").
+		Scan(&stats).Error
+	return stats, err
+}
+
+func (r *gormURLRepository) TopURLs(limit int, by string, createdBy string) ([]models.URL, error) {
+	order := "click_count DESC, id"
+	if by == TopByCreated {
+		order = "created_at DESC, id DESC"
+	}
+	var urls []models.URL
+	err := byCreator(r.db, createdBy).Order(order).Limit(limit).Find(&urls).Error
+	return urls, err
+}
+
+func (r *gormURLRepository) ClicksSince(createdBy string, since time.Time) (int64, error) {
+	query := r.db.Model(&models.Click{}).
+		Joins("JOIN urls ON urls.short_code = clicks.short_code AND urls.deleted_at IS NULL").
+		Where("clicks.clicked_at >= ?", since)
+	if createdBy != "" {
+		query = query.Where("urls.created_by = ?", createdBy)
+	}
+
+	var total float64
+	err := query.Select("COALESCE(SUM(clicks.weight), 0)").Scan(&total).Error
+	return int64(math.Round(total)), err
+}
+
+func (r *gormURLRepository) Update(id uint, updates map[string]interface{}) error {
+	return r.db.Model(&models.URL{}).Where("id = ?", id).Updates(updates).Error
+}
+
+func (r *gormURLRepository) UpdateRecord(url *models.URL, updates map[string]interface{}) error {
+	return r.db.Model(url).Updates(updates).Error
+}
+
+func (r *gormURLRepository) UpdateByIDs(ids []uint, createdBy string, updates map[string]interface{}) error {
+	return byCreator(r.db.Model(&models.URL{}).Where("id IN ?", ids), createdBy).Updates(updates).Error
+}
+
+func (r *gormURLRepository) AddClicks(shortCode string, count int64) error {
+	return r.db.Model(&models.URL{}).Where("short_code = ?", shortCode).Update("click_count", gorm.Expr("click_count + ?", count)).Error
+}
+
+func (r *gormURLRepository) Delete(id uint) error {
+	return r.db.Delete(&models.URL{}, id).Error
+}
+
+func (r *gormURLRepository) DeleteByIDs(ids []uint) error {
+	return r.db.Where("id IN ?", ids).Delete(&models.URL{}).Error
+}
+
+func (r *gormURLRepository) ListDeleted(offset, limit int, createdBy string) ([]models.URL, int64, error) {
+	var urls []models.URL
+	var total int64
+
+	query := byCreator(r.db.Unscoped().Model(&models.URL{}).Where("deleted_at IS NOT NULL"), createdBy)
+
+	// 获取总数
+	query.Count(&total)
+
+	// 分页查询
+	err := query.Offset(offset).Limit(limit).Order("deleted_at DESC").Find(&urls).Error
+	return urls, total, err
+}
+
+func (r *gormURLRepository) FindDeleted(id uint, createdBy string) (*models.URL, error) {
+	var url models.URL
+	query := byCreator(r.db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id), createdBy)
+	if err := query.First(&url).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &url, nil
+}
+
+func (r *gormURLRepository) Restore(id uint) error {
+	return r.db.Unscoped().Model(&models.URL{}).Where("id = ?", id).Update("deleted_at", nil).Error
+}
+
+func (r *gormURLRepository) PurgeDeleted(before time.Time) (int64, error) {
+	result := r.db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", before).Delete(&models.URL{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *gormURLRepository) FindActive(now time.Time) ([]models.URL, error) {
+	var urls []models.URL
+	err := r.db.Where("is_active = ? AND (expires_at IS NULL OR expires_at > ?)", true, now).Find(&urls).Error
+	return urls, err
+}
+
+func (r *gormURLRepository) FindExpiredActive(now time.Time) ([]models.URL, error) {
+	var urls []models.URL
+	err := r.db.Where("expires_at IS NOT NULL AND expires_at < ? AND is_active = ?", now, true).Find(&urls).Error
+	return urls, err
+}
+
+func (r *gormURLRepository) DeactivateExpired(now time.Time) error {
+	return r.db.Model(&models.URL{}).Where("expires_at IS NOT NULL AND expires_at < ?", now).Update("is_active", false).Error
+}
+
+func (r *gormURLRepository) Transaction(fn func(repo URLRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&gormURLRepository{db: tx})
+	})
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"sort"
+	"testing"
+)
+
+func searchTargets(t *testing.T, s *URLService, filter URLListFilter) []string {
+	t.Helper()
+	urls, total, err := s.GetURLList(1, 100, filter)
+	if err != nil {
+		t.Fatalf("GetURLList(%+v): %v", filter, err)
+	}
+	if int(total) != len(urls) {
+		t.Fatalf("total = %d, got %d urls", total, len(urls))
+	}
+	targets := make([]string, len(urls))
+	for i, url := range urls {
+		targets[i] = url.OriginalURL
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+func createTitled(t *testing.T, s *URLService, originalURL, title, description string) {
+	t.Helper()
+	if _, err := s.CreateShortURL(originalURL, title, description, "", nil, "alice", true, URLOptions{}); err != nil {
+		t.Fatalf("create %s: %v", originalURL, err)
+	}
+}
+
+func TestSearchIsCaseInsensitiveOnSQLite(t *testing.T) {
+	s, _ := newTestService(t, newTestConfig())
+	createTitled(t, s, "https://example.com/Docs", "Release NOTES", "")
+	createTitled(t, s, "https://other.org/blog", "", "Weekly notes")
+	createTitled(t, s, "https://other.org/none", "", "")
+
+	for _, search := range []string{"notes", "NOTES", "NoTeS"} {
+		got := searchTargets(t, s, URLListFilter{Search: search})
+		if len(got) != 2 || got[0] != "https://example.com/Docs" || got[1] != "https://other.org/blog" {
+			t.Errorf("search %q = %v", search, got)
+		}
+	}
+	if got := searchTargets(t, s, URLListFilter{Search: "EXAMPLE.COM/DOCS"}); len(got) != 1 {
+		t.Errorf("url search = %v, want 1 match", got)
+	}
+}
+
+func TestSearchTreatsWildcardsLiterally(t *testing.T) {
+	s, _ := newTestService(t, newTestConfig())
+	createTitled(t, s, "https://example.com/a?off=50%25", "50% off", "")
+	createTitled(t, s, "https://example.com/b", "500 offers", "")
+	createTitled(t, s, "https://example.com/c", "snake_case", "")
+	createTitled(t, s, "https://example.com/d", "snakeXcase", "")
+	createTitled(t, s, "https://example.com/e", "wow!", "")
+
+	cases := map[string][]string{
+		"0% o":   {"https://example.com/a?off=50%25"},
+		"e_c":    {"https://example.com/c"},
+		"w!":     {"https://example.com/e"},
+		"%":      {"https://example.com/a?off=50%25"},
+		"_":      {"https://example.com/c"},
+		"snake%": nil,
+	}
+	for search, want := range cases {
+		got := searchTargets(t, s, URLListFilter{Search: search})
+		if len(got) != len(want) || (len(want) > 0 && got[0] != want[0]) {
+			t.Errorf("search %q = %v, want %v", search, got, want)
+		}
+	}
+}
@@ -1,26 +1,45 @@
 package services
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"log"
 	"math/big"
+	"net"
 	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/justseemore/surl/cache"
 	"github.com/justseemore/surl/config"
+	"github.com/justseemore/surl/events"
 	"github.com/justseemore/surl/models"
+	"golang.org/x/sync/singleflight"
 	"gorm.io/gorm"
 )
 
 type URLService struct {
 	cacheManager *cache.Manager
-	db           *gorm.DB
+	repo         URLRepository
 	config       *config.Config
+	syncMutex    sync.Mutex         // 防止点击计数同步并发执行
+	loadGroup    singleflight.Group // 合并同一短代码的并发数据库加载
+	stop         chan struct{}      // 关闭时通知后台任务退出
+	stopOnce     sync.Once
+	syncDone     chan struct{} // 点击计数同步任务退出后关闭，未启动时为nil
+
+	events   *events.Dispatcher // 链接事件的webhook通知，未配置时为nil
+	eventLog *events.EventLog   // 链接事件的 JSON Lines 日志，未配置时为nil
 }
 
 type URLStats struct {
@@ -30,11 +49,102 @@ type URLStats struct {
 	TodayClicks int64 `json:"today_clicks"`
 }
 
-func NewURLService(cacheManager *cache.Manager, db *gorm.DB, cfg *config.Config) *URLService {
+// UserStats 单个用户的链接数和点击数
+type UserStats struct {
+	Username string `json:"username"`
+	URLs     int64  `json:"urls"`
+	Clicks   int64  `json:"clicks"`
+}
+
+// GlobalStats 全站统计（仅限管理员）
+type GlobalStats struct {
+	Totals  *URLStats    // 所有用户的汇总
+	Users   []UserStats  // 按点击数倒序
+	TopURLs []models.URL // 点击数最多的链接
+}
+
+// globalTopURLs 全站统计中展示的热门链接数
+const globalTopURLs = 10
+
+// URLOptions 创建/更新短链接时的可选设置
+// 更新时字段为nil表示保持不变
+type URLOptions struct {
+	MaxClicks    *int64     // 最大点击次数，更新时传0表示取消限制
+	MaxUniqueIPs *int64     // 独立访客IP上限，更新时传0表示取消限制
+	StartsAt     *time.Time // 生效时间，更新时传零值表示取消
+	RedirectType *int       // 跳转状态码，创建时为空使用 DEFAULT_REDIRECT_CODE
+	CustomCode   string     // 自定义短代码（仅创建时使用），为空时自动生成
+
+	RefererRules  []models.RefererRule  // 按来源跳转的规则，更新时为nil表示不修改，空列表表示清除
+	ScheduleRules []models.ScheduleRule // 按时间段跳转的规则，更新时同上
+	GeoRules      *models.GeoRules      // 按国家限制访问，更新时为nil表示不修改，两个列表都为空表示清除
+	Tags          []string              // 标签，更新时为nil表示不修改，空列表表示清除
+}
+
+// UpdatePatch 批量更新时要修改的字段，为nil表示保持不变
+type UpdatePatch struct {
+	Title     *string
+	ExpiresAt *time.Time // 零值表示取消过期时间
+	IsActive  *bool
+}
+
+// maxRedirectRules 每个链接最多的跳转规则数
+const maxRedirectRules = 20
+
+// reservedCodes 与站点路由冲突、不能作为自定义短代码的路径
+var reservedCodes = map[string]bool{
+	"api":        true,
+	"login":      true,
+	"admin.html": true,
+}
+
+// GetURLByShortCode 返回的错误，调用方可用 errors.Is 区分
+var (
+	ErrNotFound   = errors.New("短链接不存在")
+	ErrDisabled   = errors.New("短链接已禁用")
+	ErrExpired    = errors.New("短链接已过期")
+	ErrNotStarted = errors.New("链接尚未生效")
+)
+
+// ErrForbidden 非admin用户操作他人创建的URL
+var ErrForbidden = errors.New("无权限操作该URL")
+
+// ErrInvalidInput 创建或修改URL的参数未通过校验（目标URL、短代码、标题、规则等），调用方可用 errors.Is 区分数据库错误
+var ErrInvalidInput = errors.New("参数无效")
+
+// validationError 校验失败的具体原因，Error 返回原因本身，errors.Is(err, ErrInvalidInput) 为 true
+type validationError struct {
+	err error
+}
+
+func (e validationError) Error() string        { return e.err.Error() }
+func (e validationError) Unwrap() error        { return e.err }
+func (e validationError) Is(target error) bool { return target == ErrInvalidInput }
+
+// invalidInput 将校验错误标记为 ErrInvalidInput
+func invalidInput(err error) error {
+	return validationError{err: err}
+}
+
+// DeletedURL 回收站中的URL，附带删除时间
+type DeletedURL struct {
+	models.URL
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// NewURLService 创建使用GORM存储的短链接服务
+func NewURLService(cacheManager *cache.Manager, db *gorm.DB, cfg *config.Config, dispatcher *events.Dispatcher) *URLService {
+	return NewURLServiceWithRepository(cacheManager, NewGormURLRepository(db), cfg, dispatcher)
+}
+
+// NewURLServiceWithRepository 创建使用指定存储的短链接服务
+func NewURLServiceWithRepository(cacheManager *cache.Manager, repo URLRepository, cfg *config.Config, dispatcher *events.Dispatcher) *URLService {
 	return &URLService{
 		cacheManager: cacheManager,
-		db:           db,
+		repo:         repo,
 		config:       cfg,
+		stop:         make(chan struct{}),
+		events:       dispatcher,
 	}
 }
 
@@ -62,47 +172,492 @@ func (s *URLService) validateURL(rawURL string) (string, error) {
 		return "", fmt.Errorf("URL长度不能超过%d个字符", s.config.MaxURLLength)
 	}
 
+	// 禁止控制字符（包括空字节），避免截断和头部注入
+	for _, r := range rawURL {
+		if r < 0x20 || r == 0x7f {
+			return "", errors.New("URL不能包含控制字符")
+		}
+	}
+
 	// 验证URL格式
 	parsedURL, err := url.Parse(rawURL)
 	if err != nil {
 		return "", fmt.Errorf("无效的URL格式: %v", err)
 	}
 
+	// 只允许配置的协议，防止 javascript:、data:、file: 等协议被用于跳转
+	scheme := strings.ToLower(parsedURL.Scheme)
+	if scheme == "" {
+		return "", errors.New("URL必须包含协议（如 https://）")
+	}
+	if !s.isSchemeAllowed(scheme) {
+		return "", fmt.Errorf("不支持的URL协议: %s", scheme)
+	}
+	parsedURL.Scheme = scheme
+
+	// mailto/tel 没有主机名，只需要有内容
+	if scheme == "mailto" || scheme == "tel" {
+		if parsedURL.Opaque == "" {
+			return "", fmt.Errorf("无效的%s链接", scheme)
+		}
+		return parsedURL.String(), nil
+	}
+
 	// 检查主机名
-	if parsedURL.Host == "" {
+	if parsedURL.Host == "" || parsedURL.Hostname() == "" {
 		return "", errors.New("URL必须包含有效的主机名")
 	}
+	if err := validateHostname(parsedURL.Hostname()); err != nil {
+		return "", err
+	}
+
+	// 禁止本地、内网和链路本地地址（可配置）
+	if s.config.BlockPrivateHosts {
+		if err := checkPublicHost(parsedURL.Hostname()); err != nil {
+			return "", err
+		}
+	}
+
+	// 规范化：主机名统一小写（协议已在上面转换）
+	parsedURL.Host = strings.ToLower(parsedURL.Host)
+
+	return parsedURL.String(), nil
+}
+
+// isSchemeAllowed 检查协议是否在允许列表中
+func (s *URLService) isSchemeAllowed(scheme string) bool {
+	for _, allowed := range s.config.AllowedSchemes {
+		if scheme == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// validateCustomCode 按配置的字符集和长度校验自定义短代码
+func (s *URLService) validateCustomCode(code string) error {
+	length := utf8.RuneCountInString(code)
+	if length < s.config.CustomCodeMinLength || length > s.config.CustomCodeMaxLength {
+		return fmt.Errorf("自定义短代码长度必须在%d到%d个字符之间", s.config.CustomCodeMinLength, s.config.CustomCodeMaxLength)
+	}
+	for _, r := range code {
+		if !strings.ContainsRune(s.config.CustomCodeCharset, r) {
+			return fmt.Errorf("自定义短代码包含不允许的字符: %q", r)
+		}
+	}
+	if reservedCodes[strings.ToLower(code)] {
+		return fmt.Errorf("自定义短代码为保留路径: %s", code)
+	}
+	return nil
+}
+
+// resolveDomain 校验并返回短链接域名，为空时使用默认域名
+func (s *URLService) resolveDomain(domain string) (string, error) {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	if domain == "" || domain == strings.ToLower(s.config.CustomDomain) {
+		return s.config.CustomDomain, nil
+	}
+	for _, allowed := range s.config.AllowedDomains {
+		if domain == allowed {
+			return domain, nil
+		}
+	}
+	return "", fmt.Errorf("不支持的短链接域名: %s", domain)
+}
+
+// validateHostname 校验主机名长度和字符，拒绝非ASCII（同形异义）主机名
+func validateHostname(host string) error {
+	if len(host) > 253 {
+		return errors.New("主机名过长")
+	}
+	for _, r := range host {
+		if r > unicode.MaxASCII {
+			return errors.New("主机名不能包含非ASCII字符，请使用punycode编码")
+		}
+	}
+	// IP字面量不需要检查标签
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+	for _, label := range strings.Split(host, ".") {
+		if len(label) > 63 {
+			return errors.New("主机名中的标签过长")
+		}
+	}
+	return nil
+}
+
+// lookupIPAddr 解析主机名，测试中替换以避免真实的DNS查询
+var lookupIPAddr = net.DefaultResolver.LookupIPAddr
+
+// checkPublicHost 解析主机名并拒绝指向本地、内网或链路本地地址的主机
+func checkPublicHost(host string) error {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errors.New("不允许使用本地地址")
+	}
+	// IPv6 字面量可能带有区域（如 fe80::1%eth0），比较地址范围时去掉
+	if i := strings.IndexByte(host, '%'); i >= 0 && strings.Contains(host, ":") {
+		host = host[:i]
+	}
+
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else if ip := parseLegacyIPv4(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		// 解析失败时无法判断地址范围，不阻止创建（跳转由客户端完成，服务端不会访问该地址）
+		addrs, _ := lookupIPAddr(ctx, host)
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+
+	for _, ip := range ips {
+		if isPrivateIP(ip) {
+			return errors.New("不允许使用本地或内网地址")
+		}
+	}
+	return nil
+}
+
+// parseLegacyIPv4 解析浏览器仍然接受的简写IPv4形式（如 2130706433、0x7f.1、0177.0.0.1），不是这类形式时返回nil
+// 每段可以是十进制、0x 开头的十六进制或 0 开头的八进制，最后一段占据剩余的字节
+func parseLegacyIPv4(host string) net.IP {
+	parts := strings.Split(host, ".")
+	if len(parts) > 4 {
+		return nil
+	}
+	var value uint64
+	for i, part := range parts {
+		base := 10
+		switch {
+		case len(part) > 1 && (part[:2] == "0x" || part[:2] == "0X"):
+			base, part = 16, part[2:]
+			if part == "" {
+				part = "0"
+			}
+		case len(part) > 1 && part[0] == '0':
+			base, part = 8, part[1:]
+		}
+		n, err := strconv.ParseUint(part, base, 32)
+		if err != nil {
+			return nil
+		}
+		if i < len(parts)-1 {
+			if n > 255 {
+				return nil
+			}
+			value = value<<8 | n
+			continue
+		}
+		remaining := uint(4 - i) // 最后一段占据的字节数
+		if n >= 1<<(8*remaining) {
+			return nil
+		}
+		value = value<<(8*remaining) | n
+	}
+	return net.IPv4(byte(value>>24), byte(value>>16), byte(value>>8), byte(value))
+}
+
+// isPrivateIP 判断IP是否为回环、内网、链路本地、未指定或组播地址
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified()
+}
+
+// htmlTagPattern 匹配HTML标签
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
 
-	// 禁止本地地址（可选）
-	if strings.Contains(parsedURL.Host, "localhost") || strings.Contains(parsedURL.Host, "127.0.0.1") {
-		return "", errors.New("不允许使用本地地址")
+// sanitizeText 清理标题/描述：去除HTML标签和控制字符，并校验长度
+// 这些字段会在管理页面和提示页中展示，不能保存可执行的标记
+func sanitizeText(value string, maxLength int, field string) (string, error) {
+	value = htmlTagPattern.ReplaceAllString(value, "")
+	value = strings.Map(func(r rune) rune {
+		if r == '<' || r == '>' {
+			return -1
+		}
+		if unicode.IsControl(r) && r != '\n' {
+			return -1
+		}
+		return r
+	}, value)
+	value = strings.TrimSpace(value)
+
+	if maxLength > 0 && utf8.RuneCountInString(value) > maxLength {
+		return "", fmt.Errorf("%s长度不能超过%d个字符", field, maxLength)
 	}
+	return value, nil
+}
 
-	return rawURL, nil
+// derivedTitle 根据目标URL生成默认标题：域名 + 最后一段路径，如 "example.com - report.pdf"
+// 只解析URL本身，不请求目标页面；无法得到域名时返回空字符串
+func (s *URLService) derivedTitle(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Hostname() == "" {
+		return ""
+	}
+
+	title := strings.TrimPrefix(parsed.Hostname(), "www.")
+	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	last := segments[len(segments)-1]
+	if unescaped, err := url.PathUnescape(last); err == nil {
+		last = unescaped
+	}
+	// 路径段与用户输入的标题走同样的清理，清理后为空时只使用域名
+	if last, _ = sanitizeText(last, 0, "标题"); last != "" {
+		title += " - " + last
+	}
+
+	// 超长时截断而不是报错
+	if max := s.config.MaxTitleLength; max > 0 && utf8.RuneCountInString(title) > max {
+		title = string([]rune(title)[:max])
+	}
+	return title
 }
 
-// CreateShortURL 创建短链接
-func (s *URLService) CreateShortURL(originalURL, title, description, domain string, expiresAt *time.Time, createdBy string) (*models.URL, error) {
+// validateRefererRules 规范化并校验来源规则，规则目标与原始链接使用同样的校验
+func (s *URLService) validateRefererRules(rules []models.RefererRule) (models.RefererRules, error) {
+	if len(rules) > maxRedirectRules {
+		return nil, fmt.Errorf("每个链接最多%d条来源规则", maxRedirectRules)
+	}
+
+	validated := make(models.RefererRules, 0, len(rules))
+	for _, rule := range rules {
+		rule.Match = strings.ToLower(strings.TrimSpace(rule.Match))
+		if rule.Match == "" {
+			return nil, errors.New("来源规则的匹配域名不能为空")
+		}
+		if rule.Match != models.RefererMatchAny && rule.Match != models.RefererMatchNone && strings.ContainsAny(rule.Match, "/:*?# ") {
+			return nil, fmt.Errorf("来源规则只能匹配域名: %s", rule.Match)
+		}
+		if rule.Block == (rule.Target != "") {
+			return nil, fmt.Errorf("来源规则 %s 必须且只能指定跳转目标或拒绝访问之一", rule.Match)
+		}
+		if rule.Target != "" {
+			target, err := s.validateURL(rule.Target)
+			if err != nil {
+				return nil, fmt.Errorf("来源规则 %s 的跳转目标无效: %v", rule.Match, err)
+			}
+			rule.Target = target
+		}
+		validated = append(validated, rule)
+	}
+	return validated, nil
+}
+
+// validateScheduleRules 校验时间段规则
+func (s *URLService) validateScheduleRules(rules []models.ScheduleRule) (models.ScheduleRules, error) {
+	if len(rules) > maxRedirectRules {
+		return nil, fmt.Errorf("每个链接最多%d条时间段规则", maxRedirectRules)
+	}
+
+	validated := make(models.ScheduleRules, 0, len(rules))
+	for _, rule := range rules {
+		start, err := models.ParseClock(rule.Start)
+		if err != nil {
+			return nil, err
+		}
+		end, err := models.ParseClock(rule.End)
+		if err != nil {
+			return nil, err
+		}
+		if start == end {
+			return nil, fmt.Errorf("时间段规则的开始和结束时间不能相同: %s", rule.Start)
+		}
+		for _, day := range rule.Days {
+			if day < time.Sunday || day > time.Saturday {
+				return nil, fmt.Errorf("无效的星期: %d（0为周日，6为周六）", day)
+			}
+		}
+		target, err := s.validateURL(rule.Target)
+		if err != nil {
+			return nil, fmt.Errorf("时间段规则 %s-%s 的跳转目标无效: %v", rule.Start, rule.End, err)
+		}
+		rule.Target = target
+		validated = append(validated, rule)
+	}
+	return validated, nil
+}
+
+// validateGeoRules 规范化国家代码（去空格、转大写、去重）并校验格式
+func validateGeoRules(rules *models.GeoRules) (models.GeoRules, error) {
+	if rules == nil {
+		return models.GeoRules{}, nil
+	}
+	allow, err := normalizeCountries(rules.Allow)
+	if err != nil {
+		return models.GeoRules{}, err
+	}
+	deny, err := normalizeCountries(rules.Deny)
+	if err != nil {
+		return models.GeoRules{}, err
+	}
+	return models.GeoRules{Allow: allow, Deny: deny}, nil
+}
+
+func normalizeCountries(countries []string) ([]string, error) {
+	seen := make(map[string]bool, len(countries))
+	var normalized []string
+	for _, country := range countries {
+		country = strings.ToUpper(strings.TrimSpace(country))
+		if len(country) != 2 || country[0] < 'A' || country[0] > 'Z' || country[1] < 'A' || country[1] > 'Z' {
+			return nil, fmt.Errorf("国家代码应为两位字母（ISO 3166-1）: %s", country)
+		}
+		if !seen[country] {
+			seen[country] = true
+			normalized = append(normalized, country)
+		}
+	}
+	return normalized, nil
+}
+
+// normalizeTags 规范化标签（去空格、转小写、去重）并校验数量和长度
+func (s *URLService) normalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if s.config.MaxTagLength > 0 && utf8.RuneCountInString(tag) > s.config.MaxTagLength {
+			return nil, fmt.Errorf("标签长度不能超过%d个字符: %s", s.config.MaxTagLength, tag)
+		}
+		if strings.Contains(tag, ",") {
+			return nil, fmt.Errorf("标签不能包含逗号: %s", tag)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	if s.config.MaxTagsPerLink > 0 && len(normalized) > s.config.MaxTagsPerLink {
+		return nil, fmt.Errorf("每个链接最多%d个标签", s.config.MaxTagsPerLink)
+	}
+	return normalized, nil
+}
+
+// CreateShortURL 创建短链接，domain 为空时使用默认域名，allowDuplicate 为 true 时允许同一目标URL创建多个短代码
+func (s *URLService) CreateShortURL(originalURL, title, description, domain string, expiresAt *time.Time, createdBy string, allowDuplicate bool, opts URLOptions) (*models.URL, error) {
 	// 验证URL
 	validatedURL, err := s.validateURL(originalURL)
 	if err != nil {
-		return nil, err
+		return nil, invalidInput(err)
+	}
+
+	// 校验短链接域名
+	if domain, err = s.resolveDomain(domain); err != nil {
+		return nil, invalidInput(err)
+	}
+
+	// 清理标题和描述
+	if title, err = sanitizeText(title, s.config.MaxTitleLength, "标题"); err != nil {
+		return nil, invalidInput(err)
+	}
+	if description, err = sanitizeText(description, s.config.MaxDescriptionLength, "描述"); err != nil {
+		return nil, invalidInput(err)
+	}
+	if title == "" && s.config.AutoTitle {
+		title = s.derivedTitle(validatedURL)
+	}
+
+	// 验证最大点击次数（0表示不限制）
+	var maxClicks *int64
+	if opts.MaxClicks != nil {
+		if *opts.MaxClicks < 0 {
+			return nil, invalidInput(errors.New("最大点击次数不能为负数"))
+		}
+		if *opts.MaxClicks > 0 {
+			maxClicks = opts.MaxClicks
+		}
+	}
+
+	// 验证独立访客上限（0表示不限制）
+	var maxUniqueIPs *int64
+	if opts.MaxUniqueIPs != nil {
+		if *opts.MaxUniqueIPs < 0 {
+			return nil, invalidInput(errors.New("独立访客上限不能为负数"))
+		}
+		if *opts.MaxUniqueIPs > 0 {
+			maxUniqueIPs = opts.MaxUniqueIPs
+		}
+	}
+
+	// 生效时间的零值等同于未设置
+	var startsAt *time.Time
+	if opts.StartsAt != nil && !opts.StartsAt.IsZero() {
+		startsAt = models.UTC(opts.StartsAt)
+	}
+
+	// 跳转状态码
+	redirectType := s.config.DefaultRedirectCode
+	if opts.RedirectType != nil {
+		if !models.IsValidRedirectType(*opts.RedirectType) {
+			return nil, invalidInput(fmt.Errorf("不支持的跳转状态码: %d", *opts.RedirectType))
+		}
+		redirectType = *opts.RedirectType
+	}
+
+	// 来源规则
+	refererRules, err := s.validateRefererRules(opts.RefererRules)
+	if err != nil {
+		return nil, invalidInput(err)
+	}
+	scheduleRules, err := s.validateScheduleRules(opts.ScheduleRules)
+	if err != nil {
+		return nil, invalidInput(err)
+	}
+	geoRules, err := validateGeoRules(opts.GeoRules)
+	if err != nil {
+		return nil, invalidInput(err)
+	}
+	tags, err := s.normalizeTags(opts.Tags)
+	if err != nil {
+		return nil, invalidInput(err)
 	}
 
 	// 检查URL是否已存在
-	var existingURL models.URL
-	if err := s.db.Where("original_url = ? AND deleted_at IS NULL", validatedURL).First(&existingURL).Error; err == nil {
-		return nil, errors.New("URL已存在")
+	if !allowDuplicate {
+		if _, err := s.repo.FindByOriginalURL(validatedURL, ""); err == nil {
+			return nil, invalidInput(errors.New("URL已存在"))
+		}
 	}
 
 	// 生成唯一短代码
-	shortCode := s.generateShortCodeFromURL(validatedURL)
+	var shortCode string
+	if opts.CustomCode != "" {
+		if err := s.validateCustomCode(opts.CustomCode); err != nil {
+			return nil, invalidInput(err)
+		}
+		exists, err := s.repo.ShortCodeExists(opts.CustomCode, 0)
+		if err != nil {
+			return nil, fmt.Errorf("检查短代码失败: %v", err)
+		}
+		if exists {
+			return nil, invalidInput(errors.New("短代码已被使用"))
+		}
+		shortCode = opts.CustomCode
+	} else {
+		if shortCode, err = s.uniqueShortCode(validatedURL); err != nil {
+			return nil, err
+		}
+	}
 	// 设置默认过期时间
 	if expiresAt == nil {
-		defaultExpiry := time.Now().Add(time.Duration(s.config.DefaultExpiry) * time.Hour)
+		defaultExpiry := models.Now().Add(time.Duration(s.config.DefaultExpiry) * time.Hour)
 		expiresAt = &defaultExpiry
 	}
 
+	expiresAt = models.UTC(expiresAt)
+
 	url := &models.URL{
 		ShortCode:    shortCode,
 		OriginalURL:  validatedURL,
@@ -110,34 +665,220 @@ func (s *URLService) CreateShortURL(originalURL, title, description, domain stri
 		Description:  description,
 		CustomDomain: domain,
 		IsActive:     true,
+		StartsAt:     startsAt,
 		ExpiresAt:    expiresAt,
+		MaxClicks:    maxClicks,
+		MaxUniqueIPs: maxUniqueIPs,
+		RedirectType: redirectType,
+		RefererRules: refererRules,
 		CreatedBy:    createdBy,
+
+		ScheduleRules: scheduleRules,
+		GeoRules:      geoRules,
+		Host:          models.HostOf(validatedURL),
+		Tags:          tags,
 	}
 
-	if err := s.db.Create(url).Error; err != nil {
+	if err := s.repo.Create(url); err != nil {
 		return nil, fmt.Errorf("创建短链接失败: %v", err)
 	}
 
 	// 创建成功后，立即将新创建的URL加载到缓存中
-	s.cacheManager.SetURL(shortCode, url)
+	s.cacheManager.SetURL(shortCode, cache.NewCachedURL(url))
+	s.emit(events.LinkCreated, url)
 
 	return url, nil
 }
 
-// GetURLByShortCode 根据短代码获取URL
+// GetURLByShortCode 根据短代码获取可跳转的URL
+// 缓存命中时只填充跳转相关字段（见 cache.CachedURL），需要描述等元数据时使用 GetURLDetailsByShortCode
 func (s *URLService) GetURLByShortCode(shortCode string) (*models.URL, error) {
-	// 首先尝试从缓存获取
-	if url, found := s.cacheManager.GetURL(shortCode); found {
+	// 首先尝试从缓存获取（缓存中只有跳转相关字段）
+	cached, found := s.cacheManager.GetURL(shortCode)
+	if found {
 		// 检查缓存中的URL是否有效且未过期
-		if url.IsActive && (url.ExpiresAt == nil || url.ExpiresAt.After(time.Now())) {
+		url := cached.URL()
+		err := s.checkAvailable(url)
+		if err == nil {
 			return url, nil
 		}
+		// 刚从数据库确认过不可用，直接返回；重新启用或延长有效期时 syncCachedURL 会更新缓存并清除该标记
+		if s.cacheManager.IsUnavailable(shortCode) {
+			return nil, err
+		}
 	}
-	return nil, errors.New("链接已失效")
+
+	// 最近已确认不存在的短代码不再查询数据库
+	if !found && s.cacheManager.IsNotFound(shortCode) {
+		return nil, ErrNotFound
+	}
+
+	// 缓存未命中（重启或被淘汰），或缓存中的记录可能已过时（例如重新启用或延长了有效期），以数据库为准
+	url, err := s.reloadURL(shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkAvailable(url); err != nil {
+		if s.config.NegativeCacheTTL > 0 {
+			s.cacheManager.SetUnavailable(shortCode)
+		}
+		return nil, err
+	}
+	return url, nil
+}
+
+// GetURLDetailsByShortCode 获取可用短链接的完整记录
+// 缓存中只保存跳转相关字段，需要展示描述等元数据时使用本方法，可用性检查仍走缓存
+func (s *URLService) GetURLDetailsByShortCode(shortCode string) (*models.URL, error) {
+	if _, err := s.GetURLByShortCode(shortCode); err != nil {
+		return nil, err
+	}
+
+	full, err := s.repo.FindByShortCode(shortCode)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("查询URL失败: %v", err)
+	}
+	return full, nil
+}
+
+// checkAvailable 检查链接当前是否可以跳转
+func (s *URLService) checkAvailable(url *models.URL) error {
+	if !url.IsActive {
+		return ErrDisabled
+	}
+	if url.ExpiresAt != nil && !url.ExpiresAt.After(time.Now()) {
+		return ErrExpired
+	}
+	// 达到最大点击次数视为过期
+	if s.isClickLimitReached(url) {
+		return ErrExpired
+	}
+	// 预先创建的链接在生效时间之前不跳转
+	if !url.IsStarted() {
+		return ErrNotStarted
+	}
+	return nil
+}
+
+// reloadURL 从数据库重新加载短链接并刷新缓存
+// 同一短代码的并发请求只查询一次数据库，其余请求等待并共享结果
+func (s *URLService) reloadURL(shortCode string) (*models.URL, error) {
+	result, err, _ := s.loadGroup.Do(shortCode, func() (interface{}, error) {
+		return s.loadURL(shortCode)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// 返回副本，避免调用方之间共享同一对象
+	url := *result.(*models.URL)
+	return &url, nil
+}
+
+// loadURL 从数据库加载短链接并写入缓存
+func (s *URLService) loadURL(shortCode string) (*models.URL, error) {
+	url, err := s.repo.FindByShortCode(shortCode)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			s.cacheManager.DeleteURL(shortCode)
+			if s.config.NegativeCacheTTL > 0 {
+				s.cacheManager.SetNotFound(shortCode)
+			}
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("查询URL失败: %v", err)
+	}
+
+	s.cacheManager.SetURL(shortCode, cache.NewCachedURL(url))
+	return url, nil
+}
+
+// clickLimitCheckPercent 估算的点击数达到上限的该百分比后，才查询数据库确认
+const clickLimitCheckPercent = 90
+
+// isClickLimitReached 检查是否已达到最大点击次数
+// 先用缓存中的点击数加上尚未同步的点击数估算，远低于上限时不查询数据库；
+// 接近上限时从数据库读取已同步的点击数（包含其他实例同步的点击）再确认。并发访问时异步计数可能导致少量超出
+func (s *URLService) isClickLimitReached(url *models.URL) bool {
+	if url.MaxClicks == nil {
+		return false
+	}
+
+	pending := s.cacheManager.GetPendingClicks(url.ShortCode)
+	estimate := url.ClickCount + pending
+	if estimate >= *url.MaxClicks {
+		return true
+	}
+	if estimate*100 < *url.MaxClicks*clickLimitCheckPercent {
+		return false
+	}
+
+	clickCount, err := s.repo.ClickCount(url.ID)
+	if err != nil {
+		log.Printf("查询点击数失败 [%s]: %v", url.ShortCode, err)
+		return false
+	}
+	return clickCount+pending >= *url.MaxClicks
+}
+
+// addCachedClicks 将已同步的点击数计入缓存记录，使最大点击次数的估算不依赖数据库
+// 只更新设置了最大点击次数的链接，其他链接的跳转不使用缓存中的点击数
+func (s *URLService) addCachedClicks(shortCode string, count int64) {
+	cached, found := s.cacheManager.GetURL(shortCode)
+	if !found || cached.MaxClicks == nil {
+		return
+	}
+	updated := *cached
+	updated.ClickCount += count
+	s.cacheManager.SetURL(shortCode, &updated)
+}
+
+// AdmitVisitor 检查访客IP能否访问限制了独立访客数的短链接，未设置上限时始终放行
+// 访客IP在 UNIQUE_IP_WINDOW 时间内有效，已访问过的IP不受上限影响
+func (s *URLService) AdmitVisitor(url *models.URL, ip string) bool {
+	if url.MaxUniqueIPs == nil {
+		return true
+	}
+	window := time.Duration(s.config.UniqueIPWindow) * time.Hour
+	return s.cacheManager.AdmitUniqueIP(url.ShortCode, ip, *url.MaxUniqueIPs, window)
+}
+
+// 列表可按以下状态过滤
+const (
+	URLStatusActive   = models.StatusActive
+	URLStatusInactive = models.StatusInactive
+	URLStatusExpired  = models.StatusExpired
+)
+
+// IsValidURLStatus 检查状态过滤值是否受支持
+func IsValidURLStatus(status string) bool {
+	switch status {
+	case URLStatusActive, URLStatusInactive, URLStatusExpired:
+		return true
+	}
+	return false
+}
+
+// URLListFilter URL列表的过滤条件，零值字段表示不过滤
+type URLListFilter struct {
+	Search      string
+	CreatedBy   string
+	Owners      []string   // 多个创建者（仅管理员使用），与 CreatedBy 同时存在时取交集
+	Statuses    []string   // 多个状态之间为"或"关系，与 EffectiveStatus 一致：禁用优先于过期
+	CreatedFrom *time.Time // 创建时间下限（包含）
+	CreatedTo   *time.Time // 创建时间上限（不包含）
+	Host        string     // 目标主机名，同时匹配其子域名
+	Tag         string     // 包含该标签（精确匹配）
+
+	ExpiresBefore *time.Time // 过期时间上限（不包含），不含永不过期的URL
+
+	RequireSearch bool // 搜索词、Host 和 Tag 均为空时返回空列表
 }
 
 // GetURLList 获取URL列表
-func (s *URLService) GetURLList(page, pageSize int, search, createdBy string) ([]models.URL, int64, error) {
+func (s *URLService) GetURLList(page, pageSize int, filter URLListFilter) ([]models.URL, int64, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -145,47 +886,136 @@ func (s *URLService) GetURLList(page, pageSize int, search, createdBy string) ([
 		pageSize = 20
 	}
 
-	var urls []models.URL
-	var total int64
+	filter.Tag = strings.ToLower(strings.TrimSpace(filter.Tag))
+	if filter.RequireSearch && strings.TrimSpace(filter.Search) == "" && filter.Host == "" && filter.Tag == "" {
+		return []models.URL{}, 0, nil
+	}
 
-	query := s.db.Model(&models.URL{})
+	offset := (page - 1) * pageSize
+	return s.repo.List(offset, pageSize, filter)
+}
 
-	// 按创建者过滤
-	if createdBy != "" {
-		query = query.Where("created_by = ?", createdBy)
+// ListByTag 获取包含指定标签的URL列表，非admin用户只包含自己创建的URL
+func (s *URLService) ListByTag(tag string, page, pageSize int, createdBy string) ([]models.URL, int64, error) {
+	if createdBy == "admin" {
+		createdBy = ""
 	}
-
-	// 搜索过滤
-	if search != "" {
-		query = query.Where("original_url ILIKE ? OR title ILIKE ? OR description ILIKE ? OR short_code ILIKE ?",
-			"%"+search+"%", "%"+search+"%", "%"+search+"%", "%"+search+"%")
+	if strings.TrimSpace(tag) == "" {
+		return nil, 0, errors.New("标签不能为空")
 	}
+	return s.GetURLList(page, pageSize, URLListFilter{Tag: tag, CreatedBy: createdBy})
+}
 
-	// 获取总数
-	query.Count(&total)
+// GetDeletedURLs 获取已软删除的URL列表（回收站）
+func (s *URLService) GetDeletedURLs(page, pageSize int, createdBy string) ([]DeletedURL, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
 
-	// 分页查询
 	offset := (page - 1) * pageSize
-	err := query.Offset(offset).Limit(pageSize).Order("created_at DESC").Find(&urls).Error
+	urls, total, err := s.repo.ListDeleted(offset, pageSize, createdBy)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	deleted := make([]DeletedURL, len(urls))
+	for i, u := range urls {
+		deleted[i] = DeletedURL{URL: u, DeletedAt: u.DeletedAt.Time}
+	}
+	return deleted, total, nil
+}
+
+// GetURLStats 获取URL统计信息
+// 今日点击数按配置时区的自然日从点击明细统计，尚未写入数据库的明细不计入
+func (s *URLService) GetURLStats(createdBy string) (*URLStats, error) {
+	stats, err := s.repo.Stats(createdBy)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().In(s.config.Location())
+	todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if stats.TodayClicks, err = s.repo.ClicksSince(createdBy, todayStart.UTC()); err != nil {
+		return nil, fmt.Errorf("统计今日点击数失败: %v", err)
+	}
+	return stats, nil
+}
+
+// GetGlobalStats 获取所有用户的汇总统计、按用户的统计和点击数最多的链接
+func (s *URLService) GetGlobalStats() (*GlobalStats, error) {
+	totals, err := s.GetURLStats("")
+	if err != nil {
+		return nil, err
+	}
+	users, err := s.repo.UserStats()
+	if err != nil {
+		return nil, fmt.Errorf("统计用户数据失败: %v", err)
+	}
+	topURLs, err := s.repo.TopURLs(globalTopURLs, TopByClicks, "")
+	if err != nil {
+		return nil, fmt.Errorf("查询热门链接失败: %v", err)
+	}
+	return &GlobalStats{Totals: totals, Users: users, TopURLs: topURLs}, nil
+}
+
+// 排行榜的排序方式
+const (
+	TopByClicks  = "clicks"  // 按点击数
+	TopByCreated = "created" // 按创建时间（最新）
+)
+
+// GetTopURLs 获取排行榜，limit 限制在1到100之间，非admin用户只包含自己创建的URL
+// includePending 为 true 时把尚未同步的点击数计入 ClickCount 并重新排序：
+// 只对数据库返回的前 limit 条生效（刚好排在之外但缓冲点击很多的链接不会进入榜单），且每条记录多一次缓存查询
+func (s *URLService) GetTopURLs(limit int, by string, createdBy string, includePending bool) ([]models.URL, error) {
+	if limit < 1 {
+		limit = 1
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if by != TopByClicks && by != TopByCreated {
+		return nil, fmt.Errorf("不支持的排序方式: %s", by)
+	}
+	if createdBy == "admin" {
+		createdBy = ""
+	}
+
+	urls, err := s.repo.TopURLs(limit, by, createdBy)
+	if err != nil {
+		return nil, fmt.Errorf("查询排行榜失败: %v", err)
+	}
+
+	if includePending {
+		for i := range urls {
+			urls[i].ClickCount += s.cacheManager.GetPendingClicks(urls[i].ShortCode)
+		}
+		if by == TopByClicks {
+			sort.SliceStable(urls, func(i, j int) bool {
+				return urls[i].ClickCount > urls[j].ClickCount
+			})
+		}
+	}
+	return urls, nil
+}
+
+// SetEventLog 设置链接事件的 JSON Lines 日志，nil 表示关闭
+func (s *URLService) SetEventLog(eventLog *events.EventLog) {
+	s.eventLog = eventLog
+}
 
-	return urls, total, err
+// emit 将链接事件同时发送到webhook和事件日志
+func (s *URLService) emit(eventType string, url interface{}) {
+	s.events.Emit(eventType, url)
+	s.eventLog.Emit(eventType, url)
 }
 
-// GetURLStats 获取URL统计信息
-func (s *URLService) GetURLStats(createdBy string) (*URLStats, error) {
-	stats := &URLStats{}
-
-	query := s.db.Model(&models.URL{})
-	if createdBy != "" {
-		query = query.Where("created_by = ?", createdBy)
-	}
-	// 总URL数
-	query.Count(&stats.TotalURLs)
-	// 活跃URL数
-	query.Where("is_active = ? AND (expires_at IS NULL OR expires_at > ?)", true, time.Now()).Count(&stats.ActiveURLs)
-	// 总点击数
-	s.db.Model(&models.URL{}).Select("COALESCE(SUM(click_count), 0)").Where("created_by = ? OR ? = ''", createdBy, createdBy).Scan(&stats.TotalClicks)
-	return stats, nil
+// CacheStats 获取URL缓存统计
+func (s *URLService) CacheStats() cache.Stats {
+	return s.cacheManager.Stats()
 }
 
 // IncrementClickCount 增加点击计数
@@ -203,31 +1033,32 @@ func (s *URLService) IncrementClickCount(shortCode string) {
 //     ...
 // }
 
-// UpdateURL 更新URL
-func (s *URLService) UpdateURL(id uint, originalURL, title string, expiresAt *time.Time, active bool, updatedBy string) error {
-	var url models.URL
-	if err := s.db.First(&url, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("URL不存在")
-		}
-		return fmt.Errorf("查询URL失败: %v", err)
-	}
-
+// UpdateURL 更新URL并返回更新后的记录，active 为nil时保持启用状态不变
+func (s *URLService) UpdateURL(id uint, originalURL, title string, expiresAt *time.Time, active *bool, updatedBy string, opts URLOptions) (*models.URL, error) {
 	// 验证新的URL（如果提供）
 	if originalURL != "" {
 		validatedURL, err := s.validateURL(originalURL)
 		if err != nil {
-			return err
+			return nil, invalidInput(err)
 		}
 		originalURL = validatedURL
 	}
 
+	if title != "" {
+		sanitized, err := sanitizeText(title, s.config.MaxTitleLength, "标题")
+		if err != nil {
+			return nil, invalidInput(err)
+		}
+		title = sanitized
+	}
+
 	updates := map[string]interface{}{
-		"updated_at": time.Now(),
+		"updated_at": models.Now(),
 	}
 
 	if originalURL != "" {
 		updates["original_url"] = originalURL
+		updates["host"] = models.HostOf(originalURL)
 	}
 
 	if title != "" {
@@ -235,54 +1066,183 @@ func (s *URLService) UpdateURL(id uint, originalURL, title string, expiresAt *ti
 	}
 
 	if expiresAt != nil {
-		updates["expires_at"] = expiresAt
+		updates["expires_at"] = models.UTC(expiresAt)
 	}
 
-	if active != url.IsActive {
-		updates["is_active"] = active
+	if active != nil {
+		updates["is_active"] = *active
 	}
 
-	err := s.db.Model(&url).Updates(updates).Error
-	if err != nil {
-		return err
+	if opts.RedirectType != nil {
+		if !models.IsValidRedirectType(*opts.RedirectType) {
+			return nil, invalidInput(fmt.Errorf("不支持的跳转状态码: %d", *opts.RedirectType))
+		}
+		updates["redirect_type"] = *opts.RedirectType
 	}
 
-	// 更新成功后，同步更新缓存
-	// 如果URL变为非活跃状态，从缓存中删除
-	if !url.IsActive {
-		s.cacheManager.DeleteURL(url.ShortCode)
-	} else {
-		// 重新查询更新后的数据并更新缓存
-		var updatedURL models.URL
-		if err := s.db.First(&updatedURL, id).Error; err == nil {
-			s.cacheManager.SetURL(updatedURL.ShortCode, &updatedURL)
+	if opts.StartsAt != nil {
+		if opts.StartsAt.IsZero() {
+			updates["starts_at"] = nil
+		} else {
+			updates["starts_at"] = models.UTC(opts.StartsAt)
 		}
 	}
 
-	return nil
+	if opts.MaxClicks != nil {
+		if *opts.MaxClicks < 0 {
+			return nil, invalidInput(errors.New("最大点击次数不能为负数"))
+		}
+		if *opts.MaxClicks == 0 {
+			updates["max_clicks"] = nil
+		} else {
+			updates["max_clicks"] = *opts.MaxClicks
+		}
+	}
+
+	if opts.MaxUniqueIPs != nil {
+		if *opts.MaxUniqueIPs < 0 {
+			return nil, invalidInput(errors.New("独立访客上限不能为负数"))
+		}
+		if *opts.MaxUniqueIPs == 0 {
+			updates["max_unique_ips"] = nil
+		} else {
+			updates["max_unique_ips"] = *opts.MaxUniqueIPs
+		}
+	}
+
+	if opts.RefererRules != nil {
+		rules, err := s.validateRefererRules(opts.RefererRules)
+		if err != nil {
+			return nil, invalidInput(err)
+		}
+		updates["referer_rules"] = rules
+	}
+
+	if opts.ScheduleRules != nil {
+		rules, err := s.validateScheduleRules(opts.ScheduleRules)
+		if err != nil {
+			return nil, invalidInput(err)
+		}
+		updates["schedule_rules"] = rules
+	}
+
+	if opts.GeoRules != nil {
+		rules, err := validateGeoRules(opts.GeoRules)
+		if err != nil {
+			return nil, invalidInput(err)
+		}
+		updates["geo_rules"] = rules
+	}
+
+	if opts.Tags != nil {
+		tags, err := s.normalizeTags(opts.Tags)
+		if err != nil {
+			return nil, invalidInput(err)
+		}
+		updates["tags"] = models.Tags(tags)
+	}
+
+	// 读取、权限检查和更新在同一个事务中完成，更新后的记录直接用于同步缓存
+	var url *models.URL
+	err := s.repo.Transaction(func(repo URLRepository) error {
+		found, err := repo.FindByID(id)
+		if err != nil {
+			if errors.Is(err, ErrRecordNotFound) {
+				return errors.New("URL不存在")
+			}
+			return fmt.Errorf("查询URL失败: %v", err)
+		}
+		// 非admin用户只能修改自己创建的URL
+		if updatedBy != "admin" && found.CreatedBy != updatedBy {
+			return ErrForbidden
+		}
+		if err := repo.UpdateRecord(found, updates); err != nil {
+			return err
+		}
+		url = found
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.syncCachedURL(url)
+	s.emit(events.LinkUpdated, url)
+	return url, nil
+}
+
+// syncCachedURL 按已提交的记录同步缓存：启用的链接写入缓存，非活跃的链接从缓存中删除
+func (s *URLService) syncCachedURL(url *models.URL) {
+	if url.IsActive {
+		s.cacheManager.SetURL(url.ShortCode, cache.NewCachedURL(url))
+	} else {
+		s.cacheManager.DeleteURL(url.ShortCode)
+	}
 }
 
-// DeleteURL 删除URL
+// DeleteURL 删除URL（幂等：已删除或不存在的URL视为删除成功）
 func (s *URLService) DeleteURL(id uint, deletedBy string) error {
-	var url models.URL
-	if err := s.db.First(&url, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("URL不存在")
+	url, err := s.repo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return nil
 		}
 		return fmt.Errorf("查询URL失败: %v", err)
 	}
+	// 非admin用户只能删除自己创建的URL
+	if deletedBy != "admin" && url.CreatedBy != deletedBy {
+		return ErrForbidden
+	}
 
 	// 软删除
-	err := s.db.Delete(&url).Error
-	if err != nil {
+	if err := s.repo.Delete(url.ID); err != nil {
 		return err
 	}
 
 	// 删除成功后，从缓存中移除
 	s.cacheManager.DeleteURL(url.ShortCode)
+	s.emit(events.LinkDeleted, url)
 	return nil
 }
 
+// RestoreURL 恢复已软删除的URL
+func (s *URLService) RestoreURL(id uint, restoredBy string) (*models.URL, error) {
+	// 非admin用户只能恢复自己创建的URL
+	owner := restoredBy
+	if restoredBy == "admin" {
+		owner = ""
+	}
+	url, err := s.repo.FindDeleted(id, owner)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return nil, errors.New("URL不存在或无权限恢复")
+		}
+		return nil, fmt.Errorf("查询URL失败: %v", err)
+	}
+
+	// 短代码可能在删除后被其他链接重新使用
+	exists, err := s.repo.ShortCodeExists(url.ShortCode, url.ID)
+	if err != nil {
+		return nil, fmt.Errorf("检查短代码失败: %v", err)
+	}
+	if exists {
+		return nil, errors.New("短代码已被其他链接使用，无法恢复")
+	}
+
+	if err := s.repo.Restore(url.ID); err != nil {
+		return nil, err
+	}
+	url.DeletedAt = gorm.DeletedAt{}
+
+	// 恢复成功后，有效且未过期的URL重新加载到缓存
+	if url.IsActive && !url.IsExpired() {
+		s.cacheManager.SetURL(url.ShortCode, cache.NewCachedURL(url))
+	}
+	s.emit(events.LinkRestored, url)
+
+	return url, nil
+}
+
 // BatchDeleteURLs 批量删除URL
 func (s *URLService) BatchDeleteURLs(ids []uint, deletedBy string) error {
 	if len(ids) == 0 {
@@ -291,12 +1251,11 @@ func (s *URLService) BatchDeleteURLs(ids []uint, deletedBy string) error {
 
 	// 先查询要删除的URL的短代码，用于清理缓存
 	// 添加权限验证：非admin用户只能删除自己创建的URL
-	var urls []models.URL
-	query := s.db.Where("id IN ?", ids)
-	if deletedBy != "admin" {
-		query = query.Where("created_by = ?", deletedBy)
+	owner := deletedBy
+	if deletedBy == "admin" {
+		owner = ""
 	}
-	err := query.Find(&urls).Error
+	urls, err := s.repo.FindByIDs(ids, owner)
 	if err != nil {
 		return fmt.Errorf("查询URL失败: %v", err)
 	}
@@ -313,7 +1272,7 @@ func (s *URLService) BatchDeleteURLs(ids []uint, deletedBy string) error {
 	}
 
 	// 批量删除
-	err = s.db.Where("id IN ?", urlIds).Delete(&models.URL{}).Error
+	err = s.repo.DeleteByIDs(urlIds)
 	if err != nil {
 		return err
 	}
@@ -321,6 +1280,7 @@ func (s *URLService) BatchDeleteURLs(ids []uint, deletedBy string) error {
 	// 删除成功后，从缓存中移除所有相关URL
 	for _, url := range urls {
 		s.cacheManager.DeleteURL(url.ShortCode)
+		s.emit(events.LinkDeleted, url)
 	}
 
 	return nil
@@ -328,31 +1288,33 @@ func (s *URLService) BatchDeleteURLs(ids []uint, deletedBy string) error {
 
 // ToggleURLStatus 切换URL状态
 func (s *URLService) ToggleURLStatus(id uint, updatedBy string) error {
-	var url models.URL
-	if err := s.db.First(&url, id).Error; err != nil {
-		return err
-	}
-
-	err := s.db.Model(&url).Updates(map[string]interface{}{
-		"is_active":  !url.IsActive,
-		"updated_at": time.Now(),
-	}).Error
+	var url *models.URL
+	err := s.repo.Transaction(func(repo URLRepository) error {
+		found, err := repo.FindByID(id)
+		if err != nil {
+			return err
+		}
+		// 非admin用户只能修改自己创建的URL
+		if updatedBy != "admin" && found.CreatedBy != updatedBy {
+			return ErrForbidden
+		}
+		err = repo.UpdateRecord(found, map[string]interface{}{
+			"is_active":  !found.IsActive,
+			"updated_at": models.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		url = found
+		return nil
+	})
 	if err != nil {
 		return err
 	}
 
 	// 状态切换成功后，同步更新缓存
-	if url.IsActive {
-		// 原来是活跃的，现在变为非活跃，从缓存中删除
-		s.cacheManager.DeleteURL(url.ShortCode)
-	} else {
-		// 原来是非活跃的，现在变为活跃，重新加载到缓存
-		var updatedURL models.URL
-		if err := s.db.First(&updatedURL, id).Error; err == nil {
-			s.cacheManager.SetURL(updatedURL.ShortCode, &updatedURL)
-		}
-	}
-
+	s.syncCachedURL(url)
+	s.emit(events.LinkUpdated, url)
 	return nil
 }
 
@@ -362,115 +1324,448 @@ func (s *URLService) BatchToggleURLs(ids []uint, active bool, username string) e
 		return errors.New("没有要操作的URL")
 	}
 
-	// 先查询要操作的URL，用于缓存同步
+	owner := username
+	if username == "admin" {
+		owner = ""
+	}
+
+	// 查询和更新在同一个事务中完成，缓存按查询到的记录同步，无需再次查询
+	now := models.Now()
 	var urls []models.URL
-	query := s.db.Where("id IN ?", ids)
-	if username != "admin" {
-		query = query.Where("created_by = ?", username)
+	err := s.repo.Transaction(func(repo URLRepository) error {
+		found, err := repo.FindByIDs(ids, owner)
+		if err != nil {
+			return fmt.Errorf("查询URL失败: %v", err)
+		}
+		// 检查权限：非管理员只能操作自己的URL
+		err = repo.UpdateByIDs(ids, owner, map[string]interface{}{
+			"is_active":  active,
+			"updated_at": now,
+		})
+		if err != nil {
+			return err
+		}
+		urls = found
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// 批量操作成功后，同步更新缓存
+	for i := range urls {
+		urls[i].IsActive = active
+		urls[i].UpdatedAt = now
+		s.syncCachedURL(&urls[i])
+		s.emit(events.LinkUpdated, &urls[i])
+	}
+
+	return nil
+}
+
+// BatchUpdate 将多个URL的标题、过期时间或启用状态修改为相同的值，返回更新的URL数量
+// 非admin用户只能修改自己创建的URL，其他ID被忽略；所有URL在同一条语句中更新
+func (s *URLService) BatchUpdate(ids []uint, fields UpdatePatch, updatedBy string) (int, error) {
+	if len(ids) == 0 {
+		return 0, errors.New("没有要操作的URL")
+	}
+	if fields.Title == nil && fields.ExpiresAt == nil && fields.IsActive == nil {
+		return 0, errors.New("至少需要指定一个要修改的字段")
+	}
+
+	now := models.Now()
+	updates := map[string]interface{}{
+		"updated_at": now,
+	}
+	var title string
+	if fields.Title != nil {
+		sanitized, err := sanitizeText(*fields.Title, s.config.MaxTitleLength, "标题")
+		if err != nil {
+			return 0, err
+		}
+		title = sanitized
+		updates["title"] = title
 	}
-	err := query.Find(&urls).Error
+	var expiresAt *time.Time
+	if fields.ExpiresAt != nil {
+		if fields.ExpiresAt.IsZero() {
+			updates["expires_at"] = nil
+		} else {
+			expiresAt = models.UTC(fields.ExpiresAt)
+			updates["expires_at"] = expiresAt
+		}
+	}
+	if fields.IsActive != nil {
+		updates["is_active"] = *fields.IsActive
+	}
+
+	owner := updatedBy
+	if updatedBy == "admin" {
+		owner = ""
+	}
+
+	// 查询和更新在同一个事务中完成，缓存按查询到的记录同步，无需再次查询
+	var urls []models.URL
+	err := s.repo.Transaction(func(repo URLRepository) error {
+		found, err := repo.FindByIDs(ids, owner)
+		if err != nil {
+			return fmt.Errorf("查询URL失败: %v", err)
+		}
+		if len(found) == 0 {
+			return nil
+		}
+		matched := make([]uint, len(found))
+		for i, url := range found {
+			matched[i] = url.ID
+		}
+		if err := repo.UpdateByIDs(matched, owner, updates); err != nil {
+			return err
+		}
+		urls = found
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("查询URL失败: %v", err)
+		return 0, err
 	}
 
-	// 检查权限：非管理员只能操作自己的URL
-	updateQuery := s.db.Model(&models.URL{}).Where("id IN ?", ids)
-	if username != "admin" {
-		updateQuery = updateQuery.Where("created_by = ?", username)
+	// 更新成功后，将修改应用到查询到的记录并同步缓存
+	for i := range urls {
+		url := &urls[i]
+		url.UpdatedAt = now
+		if fields.Title != nil {
+			url.Title = title
+		}
+		if fields.ExpiresAt != nil {
+			url.ExpiresAt = expiresAt
+		}
+		if fields.IsActive != nil {
+			url.IsActive = *fields.IsActive
+		}
+		s.syncCachedURL(url)
+		s.emit(events.LinkUpdated, url)
 	}
+	return len(urls), nil
+}
+
+// 批量修改标签的方式
+const (
+	TagModeAdd    = "add"
+	TagModeRemove = "remove"
+)
 
-	err = updateQuery.Updates(map[string]interface{}{
-		"is_active":  active,
-		"updated_at": time.Now(),
-	}).Error
+// BatchTagURLs 为多个URL批量添加或移除标签，返回标签实际发生变化的URL数量
+// 非admin用户只能修改自己创建的URL；添加后超过每个链接的标签上限时整批不修改
+func (s *URLService) BatchTagURLs(ids []uint, tags []string, mode string, username string) (int, error) {
+	if len(ids) == 0 {
+		return 0, errors.New("没有要操作的URL")
+	}
+	if mode != TagModeAdd && mode != TagModeRemove {
+		return 0, fmt.Errorf("不支持的标签操作: %s", mode)
+	}
+	tags, err := s.normalizeTags(tags)
 	if err != nil {
-		return err
+		return 0, err
+	}
+	if len(tags) == 0 {
+		return 0, errors.New("标签不能为空")
 	}
 
-	// 批量操作成功后，同步更新缓存
-	for _, url := range urls {
-		if !active {
-			// 设置为非活跃，从缓存中删除
-			s.cacheManager.DeleteURL(url.ShortCode)
-		} else {
-			// 设置为活跃，重新加载到缓存
-			var updatedURL models.URL
-			if err := s.db.First(&updatedURL, url.ID).Error; err == nil {
-				s.cacheManager.SetURL(updatedURL.ShortCode, &updatedURL)
+	owner := username
+	if username == "admin" {
+		owner = ""
+	}
+
+	// 查询、校验和更新在同一个事务中完成，任何一条失败时整批回滚
+	now := models.Now()
+	var urls []models.URL
+	changed := make(map[uint]models.Tags)
+	err = s.repo.Transaction(func(repo URLRepository) error {
+		found, err := repo.FindByIDs(ids, owner)
+		if err != nil {
+			return fmt.Errorf("查询URL失败: %v", err)
+		}
+		if len(found) != len(ids) {
+			return errors.New("部分URL不存在或无权限操作")
+		}
+
+		// 先计算所有URL的新标签并校验，避免部分URL修改后才发现超出上限
+		for _, url := range found {
+			var next models.Tags
+			if mode == TagModeAdd {
+				merged, err := s.normalizeTags(append(append([]string{}, url.Tags...), tags...))
+				if err != nil {
+					return fmt.Errorf("链接 %s: %v", url.ShortCode, err)
+				}
+				next = merged
+			} else {
+				for _, tag := range url.Tags {
+					if !models.Tags(tags).Has(tag) {
+						next = append(next, tag)
+					}
+				}
+			}
+			if len(next) != len(url.Tags) {
+				changed[url.ID] = next
+			}
+		}
+
+		for id, next := range changed {
+			err := repo.Update(id, map[string]interface{}{
+				"tags":       next,
+				"updated_at": now,
+			})
+			if err != nil {
+				return err
 			}
 		}
+		urls = found
+		return nil
+	})
+	if err != nil {
+		return 0, err
 	}
 
-	return nil
+	// 提交成功后，将修改应用到查询到的记录并同步缓存和事件
+	for i := range urls {
+		url := &urls[i]
+		next, ok := changed[url.ID]
+		if !ok {
+			continue
+		}
+		url.Tags = next
+		url.UpdatedAt = now
+		s.syncCachedURL(url)
+		s.emit(events.LinkUpdated, url)
+	}
+	return len(changed), nil
 }
 
 // CleanupExpiredURLs 清理过期的URL
 func (s *URLService) CleanupExpiredURLs() error {
 	// 先查询要清理的URL，用于缓存同步
-	var expiredURLs []models.URL
-	err := s.db.Where("expires_at IS NOT NULL AND expires_at < ? AND is_active = ?", time.Now(), true).Find(&expiredURLs).Error
+	now := models.Now()
+	expiredURLs, err := s.repo.FindExpiredActive(now)
 	if err != nil {
 		return fmt.Errorf("查询过期URL失败: %v", err)
 	}
 
 	// 更新数据库
-	err = s.db.Model(&models.URL{}).Where("expires_at IS NOT NULL AND expires_at < ?", time.Now()).Update("is_active", false).Error
+	err = s.repo.DeactivateExpired(now)
 	if err != nil {
 		return err
 	}
 
-	// 清理成功后，从缓存中移除过期的URL
+	// 清理成功后，从缓存中移除过期的URL；只通知本次由启用变为停用的链接
 	for _, url := range expiredURLs {
 		s.cacheManager.DeleteURL(url.ShortCode)
+		url.IsActive = false
+		s.emit(events.LinkExpired, url)
 	}
 
 	return nil
 }
 
-// SyncClickCounts 同步点击计数
+// PurgeDeleted 永久删除软删除时间早于 olderThan 的URL，返回删除的数量
+func (s *URLService) PurgeDeleted(olderThan time.Duration) (int64, error) {
+	cutoff := models.Now().Add(-olderThan)
+	count, err := s.repo.PurgeDeleted(cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("清理回收站失败: %v", err)
+	}
+	return count, nil
+}
+
+// StartTrashPurge 启动回收站定期清理，保留天数小于等于0时不启动
+func (s *URLService) StartTrashPurge() {
+	if s.config.TrashRetentionDays <= 0 {
+		return
+	}
+	retention := time.Duration(s.config.TrashRetentionDays) * 24 * time.Hour
+
+	ticker := time.NewTicker(time.Hour)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if count, err := s.PurgeDeleted(retention); err != nil {
+					log.Println(err)
+				} else if count > 0 {
+					log.Printf("已永久删除%d条过期的回收站记录", count)
+				}
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// SyncClickCounts 同步点击计数，上一次同步尚未完成时直接跳过
 func (s *URLService) SyncClickCounts() {
-	clickCounts := s.cacheManager.GetAllClickCounts()
+	if !s.syncMutex.TryLock() {
+		log.Println("点击计数同步仍在进行，跳过本次同步")
+		return
+	}
+	defer s.syncMutex.Unlock()
+
+	s.syncClickCounts()
+}
+
+// FlushClickCounts 等待正在进行的同步完成后再同步一次，用于关闭前写入剩余的点击计数
+func (s *URLService) FlushClickCounts() {
+	s.syncMutex.Lock()
+	defer s.syncMutex.Unlock()
+
+	s.syncClickCounts()
+}
+
+// syncClickCounts 将缓冲的点击计数写入数据库，调用方需持有 syncMutex
+// 写入失败的计数默认放回缓冲区等待下次同步，CLICK_SYNC_RETRY=false 时丢弃
+func (s *URLService) syncClickCounts() {
+	clickCounts := s.cacheManager.Flush()
+	failed := make(map[string]int64)
 	for shortCode, count := range clickCounts {
-		err := s.db.Model(&models.URL{}).Where("short_code = ?", shortCode).Update("click_count", gorm.Expr("click_count + ?", count)).Error
-		if err != nil {
-			fmt.Printf("同步点击计数失败 [%s]: %v\n", shortCode, err)
+		if err := s.repo.AddClicks(shortCode, count); err != nil {
+			log.Printf("同步点击计数失败 [%s]: %v", shortCode, err)
+			failed[shortCode] = count
+			continue
+		}
+		s.addCachedClicks(shortCode, count)
+	}
+
+	if len(failed) == 0 {
+		return
+	}
+	if s.config.ClickSyncRetry {
+		s.cacheManager.RestoreClicks(failed)
+		log.Printf("%d 个短链接的点击计数同步失败，已放回缓冲区等待重试", len(failed))
+	} else {
+		log.Printf("%d 个短链接的点击计数同步失败，已丢弃", len(failed))
+	}
+}
+
+// FlushURLClicks 立即将单个URL缓冲的点击计数写入数据库，返回写入后的总点击数
+// 只取出该短代码的计数，不影响其他链接；写入失败时计数放回缓冲区
+func (s *URLService) FlushURLClicks(id uint) (int64, error) {
+	url, err := s.repo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return 0, ErrNotFound
+		}
+		return 0, fmt.Errorf("查询URL失败: %v", err)
+	}
+
+	if count := s.cacheManager.GetAndResetClicks(url.ShortCode); count > 0 {
+		if err := s.repo.AddClicks(url.ShortCode, count); err != nil {
+			s.cacheManager.RestoreClicks(map[string]int64{url.ShortCode: count})
+			return 0, fmt.Errorf("写入点击计数失败: %v", err)
 		}
+		s.addCachedClicks(url.ShortCode, count)
 	}
-	s.cacheManager.ClearClickCounts()
+
+	clickCount, err := s.repo.ClickCount(url.ID)
+	if err != nil {
+		return 0, fmt.Errorf("查询点击数失败: %v", err)
+	}
+	return clickCount, nil
 }
 
 // StartClickCountSync 启动点击计数同步
+// 除定时同步外，内存点击计数超过上限时也会提前同步；SyncClickCounts 自带互斥，两条路径不会并发执行
 func (s *URLService) StartClickCountSync() {
 	ticker := time.NewTicker(10 * time.Second)
+	s.syncDone = make(chan struct{})
 	go func() {
+		defer close(s.syncDone)
 		defer ticker.Stop()
 		for {
 			select {
 			case <-ticker.C:
 				s.SyncClickCounts()
+			case <-s.cacheManager.FlushSignal():
+				s.SyncClickCounts()
+			case <-s.stop:
+				return
 			}
 		}
 	}()
 }
 
+// Shutdown 停止后台任务并将剩余的点击计数写入数据库
+// 等待同步任务退出的时间受 ctx 限制；超时后仍会执行最后一次同步，并返回 ctx 的错误
+func (s *URLService) Shutdown(ctx context.Context) error {
+	s.stopOnce.Do(func() { close(s.stop) })
+
+	var err error
+	if s.syncDone != nil {
+		select {
+		case <-s.syncDone:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+
+	s.FlushClickCounts()
+	return err
+}
+
 // GetURLByID 根据ID获取URL
 func (s *URLService) GetURLByID(id uint) (*models.URL, error) {
-	var url models.URL
-	err := s.db.First(&url, id).Error
+	url, err := s.repo.FindByID(id)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+		if errors.Is(err, ErrRecordNotFound) {
 			return nil, errors.New("URL不存在")
 		}
 		return nil, fmt.Errorf("查询URL失败: %v", err)
 	}
-	return &url, nil
+	return url, nil
+}
+
+// FindByShortCode 根据短代码从数据库获取URL
+func (s *URLService) FindByShortCode(shortCode string) (*models.URL, error) {
+	url, err := s.repo.FindByShortCode(shortCode)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return nil, errors.New("URL不存在")
+		}
+		return nil, fmt.Errorf("查询URL失败: %v", err)
+	}
+	return url, nil
+}
+
+// FindByShortCodes 根据多个短代码从数据库获取URL，非admin用户只返回自己创建的URL，不存在的短代码被忽略
+func (s *URLService) FindByShortCodes(shortCodes []string, username string) ([]models.URL, error) {
+	owner := username
+	if username == "admin" {
+		owner = ""
+	}
+	urls, err := s.repo.FindByShortCodes(shortCodes, owner)
+	if err != nil {
+		return nil, fmt.Errorf("查询URL失败: %v", err)
+	}
+	return urls, nil
+}
+
+// FindByOriginalURL 按规范化后的目标URL查找已有短链接，createdBy 非空时只查该用户的链接
+func (s *URLService) FindByOriginalURL(originalURL, createdBy string) (*models.URL, error) {
+	validatedURL, err := s.validateURL(originalURL)
+	if err != nil {
+		return nil, err
+	}
+
+	url, err := s.repo.FindByOriginalURL(validatedURL, createdBy)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("查询URL失败: %v", err)
+	}
+	return url, nil
 }
 
 // GetExpiredURLs 获取过期的URL
 func (s *URLService) GetExpiredURLs() ([]models.URL, error) {
-	var urls []models.URL
-	err := s.db.Where("expires_at IS NOT NULL AND expires_at < ? AND is_active = ?", time.Now(), true).Find(&urls).Error
-	return urls, err
+	return s.repo.FindExpiredActive(models.Now())
 }
 
 // // GetClickStats 获取点击统计
@@ -482,19 +1777,41 @@ func (s *URLService) GetExpiredURLs() ([]models.URL, error) {
 func (s *URLService) WarmupCache() {
 	go func() {
 		// 查询所有有效且未过期的短链接
-		var urls []models.URL
-		err := s.db.Where("is_active = ? AND (expires_at IS NULL OR expires_at > ?)",
-			true, time.Now()).Find(&urls).Error
+		urls, err := s.repo.FindActive(models.Now())
 		if err != nil {
 			return
 		}
 		// 将查询到的URL加载到缓存中
 		for _, url := range urls {
-			s.cacheManager.SetURL(url.ShortCode, &url)
+			s.cacheManager.SetURL(url.ShortCode, cache.NewCachedURL(&url))
 		}
 	}()
 }
 
+// uniqueShortCode 基于原始URL生成未被占用的短代码
+// 同一URL（允许重复时）或哈希冲突时追加序号重新哈希，保证短代码不同
+func (s *URLService) uniqueShortCode(originalURL string) (string, error) {
+	const maxAttempts = 10
+
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		input := originalURL
+		if attempt > 0 {
+			input = fmt.Sprintf("%s#%d", originalURL, attempt)
+		}
+		shortCode := s.generateShortCodeFromURL(input)
+
+		exists, err := s.repo.ShortCodeExists(shortCode, 0)
+		if err != nil {
+			return "", fmt.Errorf("检查短代码失败: %v", err)
+		}
+		if !exists {
+			return shortCode, nil
+		}
+	}
+
+	return "", errors.New("生成短代码失败，请重试")
+}
+
 // generateShortCodeFromURL 基于原始URL生成base62短代码
 func (s *URLService) generateShortCodeFromURL(originalURL string) string {
 	const base62Charset = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"